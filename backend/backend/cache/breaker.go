@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // Calls go to the backend
+	BreakerOpen     = "open"      // Calls fail fast with ErrUnavailable
+	BreakerHalfOpen = "half-open" // One trial call is let through
+)
+
+// Breaker wraps a Cache with a circuit breaker: after threshold consecutive
+// failures every call fails fast with ErrUnavailable for the cooldown, after
+// which a single trial call decides whether to close the circuit again.
+//
+// Invalidations (Delete, Incr) that fail are remembered and replayed once the
+// backend recovers, so entries written before an outage are not served after it.
+type Breaker struct {
+	backend   Cache
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+
+	pendingDeletes map[string]struct{}
+	pendingIncrs   map[string]struct{}
+}
+
+// NewBreaker wraps backend with a circuit breaker
+func NewBreaker(backend Cache, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		backend:        backend,
+		threshold:      threshold,
+		cooldown:       cooldown,
+		state:          BreakerClosed,
+		pendingDeletes: make(map[string]struct{}),
+		pendingIncrs:   make(map[string]struct{}),
+	}
+}
+
+// State returns the current circuit state
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow reports whether a call may go to the backend
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		return true
+	case BreakerHalfOpen:
+		return false // A trial call is already in flight
+	}
+	return true
+}
+
+// record updates the circuit with the outcome of a call
+func (b *Breaker) record(err error) {
+	failed := err != nil && !errors.Is(err, ErrMiss)
+
+	b.mu.Lock()
+	recovered, hasPending := false, false
+	if failed {
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+			if b.state != BreakerOpen {
+				log.Printf("Warning: cache circuit opened after %d failures: %v", b.failures, err)
+			}
+			b.state = BreakerOpen
+			b.openedAt = time.Now()
+		}
+	} else {
+		recovered = b.state == BreakerHalfOpen
+		hasPending = len(b.pendingDeletes) > 0 || len(b.pendingIncrs) > 0
+		b.state = BreakerClosed
+		b.failures = 0
+	}
+	b.mu.Unlock()
+
+	if recovered {
+		log.Println("Cache circuit closed, backend recovered")
+	}
+	if hasPending {
+		b.replayInvalidations()
+	}
+}
+
+// call runs fn through the circuit
+func (b *Breaker) call(fn func() error) error {
+	if !b.allow() {
+		return ErrUnavailable
+	}
+	err := fn()
+	b.record(err)
+	return err
+}
+
+// remember queues invalidations that could not be applied
+func (b *Breaker) remember(incr bool, keys []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pending := b.pendingDeletes
+	if incr {
+		pending = b.pendingIncrs
+	}
+	for _, key := range keys {
+		pending[key] = struct{}{}
+	}
+}
+
+// replayInvalidations applies the invalidations that failed while the backend was unavailable
+func (b *Breaker) replayInvalidations() {
+	b.mu.Lock()
+	deletes := make([]string, 0, len(b.pendingDeletes))
+	for key := range b.pendingDeletes {
+		deletes = append(deletes, key)
+	}
+	incrs := make([]string, 0, len(b.pendingIncrs))
+	for key := range b.pendingIncrs {
+		incrs = append(incrs, key)
+	}
+	b.pendingDeletes = make(map[string]struct{})
+	b.pendingIncrs = make(map[string]struct{})
+	b.mu.Unlock()
+
+	if len(deletes) > 0 {
+		if err := b.Delete(deletes...); err != nil {
+			log.Printf("Warning: failed to replay %d cache deletes: %v", len(deletes), err)
+		}
+	}
+	if len(incrs) > 0 {
+		if err := b.Incr(incrs...); err != nil {
+			log.Printf("Warning: failed to replay %d generation bumps: %v", len(incrs), err)
+		}
+	}
+}
+
+func (b *Breaker) Get(key string) (string, error) {
+	var value string
+	err := b.call(func() (err error) {
+		value, err = b.backend.Get(key)
+		return err
+	})
+	return value, err
+}
+
+func (b *Breaker) GetDel(key string) (string, error) {
+	var value string
+	err := b.call(func() (err error) {
+		value, err = b.backend.GetDel(key)
+		return err
+	})
+	return value, err
+}
+
+func (b *Breaker) MGet(keys ...string) ([]string, error) {
+	var values []string
+	err := b.call(func() (err error) {
+		values, err = b.backend.MGet(keys...)
+		return err
+	})
+	return values, err
+}
+
+func (b *Breaker) Set(key, value string, expiration time.Duration) error {
+	return b.call(func() error { return b.backend.Set(key, value, expiration) })
+}
+
+func (b *Breaker) SetNX(key, value string, expiration time.Duration) (bool, error) {
+	var set bool
+	err := b.call(func() (err error) {
+		set, err = b.backend.SetNX(key, value, expiration)
+		return err
+	})
+	return set, err
+}
+
+func (b *Breaker) Delete(keys ...string) error {
+	err := b.call(func() error { return b.backend.Delete(keys...) })
+	if err != nil {
+		b.remember(false, keys)
+	}
+	return err
+}
+
+func (b *Breaker) DeleteIfEqual(key, value string) error {
+	return b.call(func() error { return b.backend.DeleteIfEqual(key, value) })
+}
+
+func (b *Breaker) Incr(keys ...string) error {
+	err := b.call(func() error { return b.backend.Incr(keys...) })
+	if err != nil {
+		b.remember(true, keys)
+	}
+	return err
+}
+
+func (b *Breaker) IncrExpire(key string, expiration time.Duration) (int64, error) {
+	var value int64
+	err := b.call(func() (err error) {
+		value, err = b.backend.IncrExpire(key, expiration)
+		return err
+	})
+	return value, err
+}
+
+func (b *Breaker) IncrWindow(currentKey, previousKey string, previousWeight float64, limit int64, expiration time.Duration) (int64, bool, error) {
+	var estimated int64
+	var counted bool
+	err := b.call(func() (err error) {
+		estimated, counted, err = b.backend.IncrWindow(currentKey, previousKey, previousWeight, limit, expiration)
+		return err
+	})
+	return estimated, counted, err
+}
+
+func (b *Breaker) Publish(channel, message string) error {
+	return b.call(func() error { return b.backend.Publish(channel, message) })
+}
+
+// Subscribe bypasses the circuit: the backend reconnects the subscription itself
+func (b *Breaker) Subscribe(subscribeCtx context.Context, channel string) (<-chan string, error) {
+	return b.backend.Subscribe(subscribeCtx, channel)
+}
+
+func (b *Breaker) Close() error {
+	return b.backend.Close()
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrMiss is returned by Get when the key does not exist
+	ErrMiss = errors.New("cache miss")
+	// ErrUnavailable is returned while the cache backend is unreachable (circuit open)
+	ErrUnavailable = errors.New("cache unavailable")
+)
+
+// Cache is the shared (L2) cache backend used by the tiered cache, generation
+// counters, load locks and event dedup. Callers treat every error as a miss, so
+// an unavailable backend degrades to database reads.
+type Cache interface {
+	// Get returns the value of key, or ErrMiss
+	Get(key string) (string, error)
+	// GetDel atomically returns and deletes the value of key, or returns ErrMiss; of concurrent
+	// callers only one gets the value
+	GetDel(key string) (string, error)
+	// MGet returns the values of keys in order, "" for missing keys
+	MGet(keys ...string) ([]string, error)
+	Set(key, value string, expiration time.Duration) error
+	// SetNX sets key only if it does not exist and reports whether it was set
+	SetNX(key, value string, expiration time.Duration) (bool, error)
+	Delete(keys ...string) error
+	// DeleteIfEqual deletes key only if it still holds value
+	DeleteIfEqual(key, value string) error
+	// Incr atomically increments every counter in keys
+	Incr(keys ...string) error
+	// IncrExpire increments the counter at key, setting its expiry when it is created, and returns the new value
+	IncrExpire(key string, expiration time.Duration) (int64, error)
+	// IncrWindow atomically counts a request against a sliding-window limit: unless the counter
+	// at previousKey weighted by previousWeight plus the counter at currentKey reaches limit, it
+	// increments the counter at currentKey (setting its expiry when it is created). It returns
+	// the weighted count before the request and whether the request was counted.
+	IncrWindow(currentKey, previousKey string, previousWeight float64, limit int64, expiration time.Duration) (int64, bool, error)
+	Publish(channel, message string) error
+	// Subscribe delivers messages published on channel until ctx is cancelled
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+	Close() error
+}
+
+// Default is the cache backend used by the package-level helpers.
+// It is replaced by InitRedis or Use at startup; until then entries live in process memory.
+var Default Cache = NewMemoryCache()
+
+// Use installs c as the cache backend
+func Use(c Cache) {
+	Default = c
+}
+
+// Get retrieves a value from cache
+func Get(key string) (string, error) {
+	return Default.Get(key)
+}
+
+// GetDel retrieves and removes a value from cache in one step
+func GetDel(key string) (string, error) {
+	return Default.GetDel(key)
+}
+
+// Set sets a value in cache
+func Set(key string, value interface{}, expiration time.Duration) error {
+	return Default.Set(key, fmt.Sprint(value), expiration)
+}
+
+// Delete deletes a value from cache
+func Delete(key string) error {
+	return Default.Delete(key)
+}
+
+// SetNX sets a value in cache only if the key does not exist
+func SetNX(key, value string, expiration time.Duration) (bool, error) {
+	return Default.SetNX(key, value, expiration)
+}
+
+// Close releases the cache backend
+func Close() error {
+	return Default.Close()
+}
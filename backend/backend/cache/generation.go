@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Generation counters let callers invalidate a whole family of keys in O(1):
+// readers embed the current generation in their cache keys and writers bump it,
+// so stale entries are simply never read again and expire through their TTL.
+
+// GetGenerations returns the current value of each generation counter (0 if never bumped)
+// Counters are served from L1 when possible; BumpGenerations evicts them on every instance.
+func GetGenerations(keys ...string) ([]int64, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	generations := make([]int64, len(keys))
+	missing := make([]int, 0, len(keys))
+	for i, key := range keys {
+		if value, ok := L1.Get(key); ok {
+			if generation, err := strconv.ParseInt(value, 10, 64); err == nil {
+				generations[i] = generation
+				continue
+			}
+		}
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return generations, nil
+	}
+
+	missingKeys := make([]string, len(missing))
+	for j, i := range missing {
+		missingKeys[j] = keys[i]
+	}
+	values, err := Default.MGet(missingKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generations: %w", err)
+	}
+	for j, value := range values {
+		i := missing[j]
+		if value != "" {
+			if generations[i], err = strconv.ParseInt(value, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid generation %q for %s", value, keys[i])
+			}
+		}
+		L1.Set(keys[i], strconv.FormatInt(generations[i], 10), l1TTL)
+	}
+	return generations, nil
+}
+
+// BumpGenerations atomically increments the given generation counters
+func BumpGenerations(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := Default.Incr(keys...); err != nil {
+		return fmt.Errorf("failed to bump generations: %w", err)
+	}
+	EvictL1(keys...)
+	return nil
+}
+
+// DeleteByPattern deletes every key matching pattern using SCAN (O(keyspace); prefer generations)
+// It returns the number of keys deleted. It requires InitRedis.
+func DeleteByPattern(pattern string) (int, error) {
+	if Client == nil {
+		return 0, ErrUnavailable
+	}
+	var cursor uint64
+	deleted := 0
+	for {
+		keys, next, err := Client.Scan(ctx, cursor, pattern, 100).Result()
+		if err != nil {
+			return deleted, err
+		}
+		if len(keys) > 0 {
+			if err := Client.Del(ctx, keys...).Err(); err != nil {
+				return deleted, err
+			}
+			deleted += len(keys)
+		}
+		if next == 0 {
+			return deleted, nil
+		}
+		cursor = next
+	}
+}
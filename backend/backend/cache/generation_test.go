@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	benchKeys   = 10000 // Cached inventory queries
+	benchStores = 50    // Stores the cached queries are spread over
+)
+
+// useBenchRedis connects to the Redis at REDIS_HOST (and REDIS_PORT) and returns the prefix to
+// write keys under; the keys are removed afterwards. The benchmark is skipped without REDIS_HOST.
+func useBenchRedis(b *testing.B) string {
+	b.Helper()
+	host, port := os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PORT")
+	if host == "" {
+		b.Skip("REDIS_HOST not set")
+	}
+	if port == "" {
+		port = "6379"
+	}
+	previous := Default
+	if err := InitRedis(host, port); err != nil {
+		b.Fatal(err)
+	}
+	prefix := fmt.Sprintf("bench:%d:", time.Now().UnixNano())
+	b.Cleanup(func() {
+		DeleteByPattern(prefix + "*")
+		Client.Close()
+		Client = nil
+		Use(previous)
+	})
+	return prefix
+}
+
+// populateBench writes the cached query entries, spread evenly over the stores
+func populateBench(b *testing.B, prefix string) {
+	b.Helper()
+	_, err := Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := 0; i < benchKeys; i++ {
+			pipe.Set(ctx, fmt.Sprintf("%sinventory:store:%d:page:%d", prefix, i%benchStores, i), "{}", 5*time.Minute)
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatalf("failed to populate keys: %v", err)
+	}
+}
+
+// BenchmarkInvalidation compares invalidating the cached inventory queries of one store by
+// SCAN-and-delete (the previous InventoryService.invalidateCache) with generation counters.
+// It needs a live Redis:
+//
+//	REDIS_HOST=localhost go test ./cache -run '^$' -bench Invalidation
+func BenchmarkInvalidation(b *testing.B) {
+	prefix := useBenchRedis(b)
+
+	b.Run("scan", func(b *testing.B) {
+		deleted := 0
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			populateBench(b, prefix)
+			b.StartTimer()
+
+			store := i % benchStores
+			for _, pattern := range []string{
+				fmt.Sprintf("%sinventory:store:%d:*", prefix, store),
+				prefix + "inventory:page:*",
+				prefix + "inventory:*",
+			} {
+				n, err := DeleteByPattern(pattern)
+				if err != nil {
+					b.Fatalf("scan invalidation failed: %v", err)
+				}
+				deleted += n
+			}
+		}
+		b.ReportMetric(float64(deleted)/float64(b.N), "keys-deleted/op")
+	})
+
+	b.Run("generations", func(b *testing.B) {
+		populateBench(b, prefix)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			store := i % benchStores
+			if err := BumpGenerations(
+				fmt.Sprintf("%sinventory:gen:store:%d", prefix, store),
+				prefix+"inventory:gen:all",
+			); err != nil {
+				b.Fatalf("generation invalidation failed: %v", err)
+			}
+		}
+		b.ReportMetric(0, "keys-deleted/op")
+	})
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stampede protection: concurrent misses for one key are coalesced within the
+// pod (single-flight), and across pods a short lock in the shared cache lets one loader run
+// while the others wait for it to fill the cache.
+
+const (
+	// loadLockTTL bounds how long other pods wait for the lock holder before loading themselves
+	loadLockTTL = 5 * time.Second
+	// loadPollInterval is how often waiting pods check whether the lock holder has filled the cache
+	loadPollInterval = 50 * time.Millisecond
+)
+
+// flightGroup runs at most one load per key at a time within this process
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+var flights = &flightGroup{calls: make(map[string]*flightCall)}
+
+// do calls fn once for concurrent callers with the same key and hands every caller its result
+func (g *flightGroup) do(key string, fn func() (string, error)) (string, error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.value, call.err = fn()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+	return call.value, call.err
+}
+
+// GetOrLoad returns the cached value for key, or calls load and caches its result.
+// Concurrent misses for the same key cause a single load across all pods; errors are not cached.
+func GetOrLoad(key string, expiration time.Duration, load func() (string, error)) (string, error) {
+	if value, ok := GetTiered(key); ok {
+		return value, nil
+	}
+	return flights.do(key, func() (string, error) {
+		// Another flight may have filled the cache while this one was queued
+		if value, ok := GetTiered(key); ok {
+			return value, nil
+		}
+
+		lockKey := "lock:" + key
+		token := uuid.New().String()
+		acquired, err := Default.SetNX(lockKey, token, loadLockTTL)
+		if err == nil && !acquired {
+			// Another pod is loading; wait for it, then fall back to loading here
+			if value, ok := waitForFill(key); ok {
+				return value, nil
+			}
+		}
+
+		value, err := load()
+		if err != nil {
+			if acquired {
+				Default.DeleteIfEqual(lockKey, token)
+			}
+			return "", err
+		}
+		SetTiered(key, value, expiration)
+		if acquired {
+			Default.DeleteIfEqual(lockKey, token)
+		}
+		return value, nil
+	})
+}
+
+// waitForFill polls the shared cache until key is filled or the load lock would have expired
+func waitForFill(key string) (string, bool) {
+	deadline := time.Now().Add(loadLockTTL)
+	for time.Now().Before(deadline) {
+		time.Sleep(loadPollInterval)
+		if value, err := Get(key); err == nil && value != "" {
+			L1.Set(key, value, l1TTL)
+			return value, true
+		}
+	}
+	return "", false
+}
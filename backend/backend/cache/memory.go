@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-process implementation of Cache for single-instance
+// deployments and local development. Nothing is shared between instances.
+type MemoryCache struct {
+	mu          sync.Mutex
+	entries     map[string]memoryEntry
+	subscribers map[string][]chan string
+}
+
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time // Zero means no expiry
+}
+
+// NewMemoryCache creates an empty in-process cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries:     make(map[string]memoryEntry),
+		subscribers: make(map[string][]chan string),
+	}
+}
+
+// get returns the live entry for key; the caller holds mu
+func (m *MemoryCache) get(key string) (string, bool) {
+	entry, ok := m.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		delete(m.entries, key)
+		return "", false
+	}
+	return entry.value, true
+}
+
+// set stores key; the caller holds mu
+func (m *MemoryCache) set(key, value string, expiration time.Duration) {
+	entry := memoryEntry{value: value}
+	if expiration > 0 {
+		entry.expiresAt = time.Now().Add(expiration)
+	}
+	m.entries[key] = entry
+}
+
+func (m *MemoryCache) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if value, ok := m.get(key); ok {
+		return value, nil
+	}
+	return "", ErrMiss
+}
+
+func (m *MemoryCache) GetDel(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if value, ok := m.get(key); ok {
+		delete(m.entries, key)
+		return value, nil
+	}
+	return "", ErrMiss
+}
+
+func (m *MemoryCache) MGet(keys ...string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i], _ = m.get(key)
+	}
+	return values, nil
+}
+
+func (m *MemoryCache) Set(key, value string, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(key, value, expiration)
+	return nil
+}
+
+func (m *MemoryCache) SetNX(key, value string, expiration time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.get(key); ok {
+		return false, nil
+	}
+	m.set(key, value, expiration)
+	return true, nil
+}
+
+func (m *MemoryCache) Delete(keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+func (m *MemoryCache) DeleteIfEqual(key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, ok := m.get(key); ok && current == value {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+func (m *MemoryCache) Incr(keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		current, _ := m.get(key)
+		n, _ := strconv.ParseInt(current, 10, 64)
+		m.entries[key] = memoryEntry{value: strconv.FormatInt(n+1, 10)}
+	}
+	return nil
+}
+
+func (m *MemoryCache) IncrExpire(key string, expiration time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.get(key)
+	if !ok {
+		m.set(key, "1", expiration)
+		return 1, nil
+	}
+	n, _ := strconv.ParseInt(current, 10, 64)
+	entry := m.entries[key]
+	entry.value = strconv.FormatInt(n+1, 10)
+	m.entries[key] = entry
+	return n + 1, nil
+}
+
+func (m *MemoryCache) IncrWindow(currentKey, previousKey string, previousWeight float64, limit int64, expiration time.Duration) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previousValue, _ := m.get(previousKey)
+	currentValue, exists := m.get(currentKey)
+	previous, _ := strconv.ParseInt(previousValue, 10, 64)
+	current, _ := strconv.ParseInt(currentValue, 10, 64)
+	estimated := int64(float64(previous)*previousWeight) + current
+	if estimated >= limit {
+		return estimated, false, nil
+	}
+	if !exists {
+		m.set(currentKey, "1", expiration)
+		return estimated, true, nil
+	}
+	entry := m.entries[currentKey]
+	entry.value = strconv.FormatInt(current+1, 10)
+	m.entries[currentKey] = entry
+	return estimated, true, nil
+}
+
+// Publish delivers message to the subscribers of this process
+func (m *MemoryCache) Publish(channel, message string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, subscriber := range m.subscribers[channel] {
+		select {
+		case subscriber <- message:
+		default: // Drop rather than block publishers on a slow subscriber
+		}
+	}
+	return nil
+}
+
+func (m *MemoryCache) Subscribe(subscribeCtx context.Context, channel string) (<-chan string, error) {
+	messages := make(chan string, 64)
+	m.mu.Lock()
+	m.subscribers[channel] = append(m.subscribers[channel], messages)
+	m.mu.Unlock()
+
+	go func() {
+		<-subscribeCtx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		subscribers := m.subscribers[channel]
+		for i, subscriber := range subscribers {
+			if subscriber == messages {
+				m.subscribers[channel] = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		close(messages)
+	}()
+	return messages, nil
+}
+
+func (m *MemoryCache) Close() error {
+	return nil
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitResult is the outcome of a rate limit check
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration // Until the current window ends
+}
+
+// AllowRate checks and counts one request against a sliding-window limit of limit requests per window.
+//
+// The window is approximated from two fixed-window counters shared by all instances: the previous
+// window's count is weighted by how much of it still overlaps the sliding window. Rejected requests
+// are not counted, so a client that backs off regains capacity as the window slides.
+func AllowRate(key string, limit int, window time.Duration) (RateLimitResult, error) {
+	now := time.Now()
+	index := now.UnixNano() / int64(window)
+	elapsed := time.Duration(now.UnixNano() - index*int64(window))
+	result := RateLimitResult{Limit: limit, Reset: window - elapsed}
+
+	// The hash tag keeps both counters in one Redis Cluster slot, as the check is one script
+	currentKey := fmt.Sprintf("ratelimit:{%s}:%d", key, index)
+	previousKey := fmt.Sprintf("ratelimit:{%s}:%d", key, index-1)
+	weight := 1 - float64(elapsed)/float64(window)
+
+	// Checking and counting is atomic, so concurrent requests cannot all pass on the same count.
+	// Counters outlive their window so they can be weighted as the previous window.
+	estimated, counted, err := Default.IncrWindow(currentKey, previousKey, weight, int64(limit), 2*window)
+	if err != nil || !counted {
+		return result, err
+	}
+	result.Allowed = true
+	result.Remaining = limit - int(estimated) - 1
+	return result, nil
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -9,14 +10,23 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// Client is the raw Redis client, for Redis-specific tooling (e.g. DeleteByPattern).
+// Application code goes through Default so that it survives Redis outages.
 var Client *redis.Client
 var ctx = context.Background()
 
-// InitRedis initializes Redis connection
+// InitRedis connects to Redis and installs it, behind a circuit breaker, as the cache backend.
+// The backend is installed even if the initial ping fails: reads degrade to the database
+// until Redis becomes reachable.
 func InitRedis(host, port string) error {
 	Client = redis.NewClient(&redis.Options{
 		Addr: fmt.Sprintf("%s:%s", host, port),
+		// Fail fast so an unhealthy Redis does not stall requests
+		DialTimeout:  time.Second,
+		ReadTimeout:  500 * time.Millisecond,
+		WriteTimeout: 500 * time.Millisecond,
 	})
+	Use(NewBreaker(NewRedisCache(Client), 5, 10*time.Second))
 
 	// Test connection
 	_, err := Client.Ping(ctx).Result()
@@ -28,22 +38,150 @@ func InitRedis(host, port string) error {
 	return nil
 }
 
-// Get retrieves a value from cache
-func Get(key string) (string, error) {
-	return Client.Get(ctx, key).Result()
+// GetClient returns the Redis client
+func GetClient() *redis.Client {
+	return Client
 }
 
-// Set sets a value in cache
-func Set(key string, value interface{}, expiration time.Duration) error {
-	return Client.Set(ctx, key, value, expiration).Err()
+// RedisCache is the Redis implementation of Cache
+type RedisCache struct {
+	client *redis.Client
 }
 
-// Delete deletes a value from cache
-func Delete(key string) error {
-	return Client.Del(ctx, key).Err()
+// NewRedisCache creates a Cache backed by client
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{client: client}
 }
 
-// GetClient returns the Redis client
-func GetClient() *redis.Client {
-	return Client
+// releaseScript deletes a key only if it still holds the caller's value
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+func (r *RedisCache) Get(key string) (string, error) {
+	value, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrMiss
+	}
+	return value, err
+}
+
+func (r *RedisCache) GetDel(key string) (string, error) {
+	value, err := r.client.GetDel(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrMiss
+	}
+	return value, err
+}
+
+func (r *RedisCache) MGet(keys ...string) ([]string, error) {
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(values))
+	for i, value := range values {
+		if str, ok := value.(string); ok {
+			result[i] = str
+		}
+	}
+	return result, nil
+}
+
+func (r *RedisCache) Set(key, value string, expiration time.Duration) error {
+	return r.client.Set(ctx, key, value, expiration).Err()
+}
+
+func (r *RedisCache) SetNX(key, value string, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, expiration).Result()
+}
+
+func (r *RedisCache) Delete(keys ...string) error {
+	return r.client.Del(ctx, keys...).Err()
+}
+
+func (r *RedisCache) DeleteIfEqual(key, value string) error {
+	return releaseScript.Run(ctx, r.client, []string{key}, value).Err()
+}
+
+func (r *RedisCache) Incr(keys ...string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Incr(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+// incrExpireScript increments a counter and sets its expiry only when it is created
+var incrExpireScript = redis.NewScript(`
+local value = redis.call("INCR", KEYS[1])
+if value == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return value`)
+
+func (r *RedisCache) IncrExpire(key string, expiration time.Duration) (int64, error) {
+	return incrExpireScript.Run(ctx, r.client, []string{key}, expiration.Milliseconds()).Int64()
+}
+
+// incrWindowScript counts a request against a sliding-window limit (see Cache.IncrWindow), so
+// concurrent requests cannot all pass on the same count
+var incrWindowScript = redis.NewScript(`
+local previous = tonumber(redis.call("GET", KEYS[2]) or "0")
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local estimated = math.floor(previous * tonumber(ARGV[1])) + current
+if estimated >= tonumber(ARGV[2]) then
+	return {estimated, 0}
+end
+if redis.call("INCR", KEYS[1]) == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[3])
+end
+return {estimated, 1}`)
+
+func (r *RedisCache) IncrWindow(currentKey, previousKey string, previousWeight float64, limit int64, expiration time.Duration) (int64, bool, error) {
+	result, err := incrWindowScript.Run(ctx, r.client, []string{currentKey, previousKey},
+		previousWeight, limit, expiration.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	return result[0], result[1] == 1, nil
+}
+
+func (r *RedisCache) Publish(channel, message string) error {
+	return r.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe keeps the subscription alive across reconnects (handled by go-redis)
+func (r *RedisCache) Subscribe(subscribeCtx context.Context, channel string) (<-chan string, error) {
+	pubsub := r.client.Subscribe(subscribeCtx, channel)
+	messages := make(chan string)
+	go func() {
+		defer close(messages)
+		defer pubsub.Close()
+		incoming := pubsub.Channel()
+		for {
+			select {
+			case msg, ok := <-incoming:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- msg.Payload:
+				case <-subscribeCtx.Done():
+					return
+				}
+			case <-subscribeCtx.Done():
+				return
+			}
+		}
+	}()
+	return messages, nil
+}
+
+func (r *RedisCache) Close() error {
+	return r.client.Close()
 }
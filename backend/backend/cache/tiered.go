@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Two-tier cache: a bounded in-process LRU (L1) in front of Redis (L2).
+// Invalidations are applied locally and published on a Redis pub/sub channel so
+// every instance drops the key from its L1. The L1 TTL bounds staleness if a
+// message is missed (e.g. while an instance reconnects to Redis).
+
+// invalidationChannel is the Redis pub/sub channel carrying L1 invalidations
+const invalidationChannel = "cache:invalidate"
+
+var (
+	// L1 is the in-process cache tier
+	L1 = NewLRU(10000)
+
+	l1TTL      = 30 * time.Second
+	instanceID string
+
+	l1Hits, l1Misses, l2Hits, l2Misses atomic.Uint64
+)
+
+type invalidationMessage struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// InitL1 configures the in-process cache tier
+func InitL1(capacity int, ttl time.Duration, instance string) {
+	L1 = NewLRU(capacity)
+	l1TTL = ttl
+	instanceID = instance
+}
+
+// GetTiered looks a key up in L1, then Redis (filling L1 on a Redis hit)
+func GetTiered(key string) (string, bool) {
+	if value, ok := L1.Get(key); ok {
+		l1Hits.Add(1)
+		return value, true
+	}
+	l1Misses.Add(1)
+
+	value, err := Get(key)
+	if err != nil || value == "" {
+		l2Misses.Add(1)
+		return "", false
+	}
+	l2Hits.Add(1)
+	L1.Set(key, value, l1TTL)
+	return value, true
+}
+
+// SetTiered stores a value in both tiers; L1 keeps it for at most the L1 TTL
+func SetTiered(key, value string, expiration time.Duration) {
+	if err := Set(key, value, expiration); err != nil {
+		// Without the shared tier there is no cross-instance invalidation, so don't cache locally either
+		return
+	}
+	ttl := l1TTL
+	if expiration > 0 && expiration < ttl {
+		ttl = expiration
+	}
+	L1.Set(key, value, ttl)
+}
+
+// GetJSON looks a key up in both tiers and decodes it into dest
+func GetJSON(key string, dest interface{}) bool {
+	value, ok := GetTiered(key)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal([]byte(value), dest) == nil
+}
+
+// SetJSON encodes value and stores it in both tiers
+func SetJSON(key string, value interface{}, expiration time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	SetTiered(key, string(data), expiration)
+}
+
+// Invalidate removes keys from Redis and from the L1 of every instance
+func Invalidate(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	if err := Default.Delete(keys...); err != nil {
+		log.Printf("Warning: failed to invalidate cache keys %v: %v", keys, err)
+	}
+	EvictL1(keys...)
+}
+
+// EvictL1 removes keys from the L1 of every instance, leaving Redis untouched
+func EvictL1(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	L1.Delete(keys...)
+	data, err := json.Marshal(invalidationMessage{Origin: instanceID, Keys: keys})
+	if err != nil {
+		return
+	}
+	if err := Default.Publish(invalidationChannel, string(data)); err != nil {
+		log.Printf("Warning: failed to publish cache invalidation: %v", err)
+	}
+}
+
+// StartInvalidationListener applies L1 invalidations published by other instances until ctx is cancelled
+func StartInvalidationListener(listenCtx context.Context) {
+	messages, err := Default.Subscribe(listenCtx, invalidationChannel)
+	if err != nil {
+		log.Printf("Warning: failed to subscribe to cache invalidations: %v", err)
+		return
+	}
+
+	log.Println("Cache invalidation listener started")
+	for payload := range messages {
+		var invalidation invalidationMessage
+		if err := json.Unmarshal([]byte(payload), &invalidation); err != nil {
+			log.Printf("Warning: invalid cache invalidation message: %v", err)
+			continue
+		}
+		if invalidation.Origin == instanceID {
+			continue // Already applied locally
+		}
+		L1.Delete(invalidation.Keys...)
+	}
+	log.Println("Cache invalidation listener stopped")
+}
+
+// TierStats holds hit/miss counters of one cache tier
+type TierStats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// Stats holds hit/miss counters of both tiers since startup
+type Stats struct {
+	L1      TierStats `json:"l1"`
+	L2      TierStats `json:"l2"`
+	L1Size  int       `json:"l1_size"`
+	L2State string    `json:"l2_state"` // Circuit breaker state of the shared tier
+}
+
+// GetStats returns the cache hit ratios per tier
+func GetStats() Stats {
+	stats := Stats{
+		L1:      newTierStats(l1Hits.Load(), l1Misses.Load()),
+		L2:      newTierStats(l2Hits.Load(), l2Misses.Load()),
+		L1Size:  L1.Len(),
+		L2State: BreakerClosed,
+	}
+	if breaker, ok := Default.(*Breaker); ok {
+		stats.L2State = breaker.State()
+	}
+	return stats
+}
+
+func newTierStats(hits, misses uint64) TierStats {
+	stats := TierStats{Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		stats.HitRatio = float64(hits) / float64(total)
+	}
+	return stats
+}
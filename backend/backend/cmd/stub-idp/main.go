@@ -0,0 +1,30 @@
+// Command stub-idp is a development-only OpenID Connect identity provider that signs in
+// whoever submits its login form:
+//
+//	stub-idp [--addr=:9000] [--issuer=http://localhost:9000] [--public-url=...]
+//	         [--client-id=inventory-manager] [--client-secret=...]
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"inventory-manager-server/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL, as the API servers reach the provider")
+	publicURL := flag.String("public-url", "", "base URL browsers reach the provider at (default: the issuer)")
+	clientID := flag.String("client-id", "inventory-manager", "client ID the API servers use")
+	clientSecret := flag.String("client-secret", "stub-secret", "client secret the API servers use")
+	flag.Parse()
+
+	provider, err := oidctest.NewProvider(*issuer, *publicURL, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatalf("Failed to create stub provider: %v", err)
+	}
+	log.Printf("Stub identity provider for client %q listening on %s (issuer %s); development only", *clientID, *addr, *issuer)
+	log.Fatal(http.ListenAndServe(*addr, provider.Handler()))
+}
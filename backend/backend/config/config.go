@@ -1,7 +1,11 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -19,12 +23,104 @@ type Config struct {
 	RedisHost string
 	RedisPort string
 
+	// Cache configuration
+	CacheBackend string        // "redis" (shared, default) or "memory" (single instance only)
+	CacheL1Size  int           // Maximum entries held per instance
+	CacheL1TTL   time.Duration // Upper bound on L1 staleness if an invalidation is missed
+
 	// Kafka configuration
 	KafkaBrokers string
 	KafkaTopic   string
 
+	// Event consumer configuration
+	EventDedupTTL time.Duration // How long processed event IDs are remembered
+
+	// Authentication tokens
+	AccessTokenTTL          time.Duration // Lifetime of JWT access tokens
+	RefreshTokenTTL         time.Duration // Lifetime of refresh tokens
+	RefreshTokenRememberTTL time.Duration // Lifetime of refresh tokens with "remember me"
+
+	// JWT signing keys (generated and rotated in the database unless key files are configured)
+	JWTAlgorithm        string        // Algorithm of generated keys: "RS256" (default) or "EdDSA"
+	JWTKeyFiles         []string      // PEM private keys; each file name is the key ID
+	JWTKeyDir           string        // Directory of *.pem private keys, re-read on every reload
+	JWTSigningKeyID     string        // Key that signs tokens when loading files; defaults to the last key ID in sort order
+	JWTKeyRotation      time.Duration // Age at which a generated key is replaced; 0 disables rotation
+	JWTKeyReload        time.Duration // How often each instance reloads the key set; new keys sign after two intervals
+	JWTKeyEncryptionKey string        // Base64 AES-256 key that encrypts generated keys in the database; required unless loading files
+
+	// Rate limiting and login protection
+	RateLimitPublic  RateLimit     // Unauthenticated routes, per client IP
+	RateLimitLogin   RateLimit     // Login endpoint, per client IP
+	RateLimitAPI     RateLimit     // Authenticated routes, per user or API key
+	RateLimitAPIIP   RateLimit     // Authenticated routes, per client IP, counted before authentication
+	LoginMaxAttempts int           // Failed logins per username before lockout
+	LoginLockout     time.Duration // How long a username stays locked out
+
+	// Two-factor authentication
+	MFAIssuer        string        // Shown as the account issuer in authenticator apps
+	MFAChallengeTTL  time.Duration // How long a login challenge waits for the second factor
+	MFARequiredRoles []string      // Roles that must use two-factor authentication
+
+	// Single sign-on through an OpenID Connect identity provider (disabled without an issuer)
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string         // Frontend page that receives the authorization code
+	OIDCScopes       []string       // Requested scopes; must include "openid"
+	OIDCDisplayName  string         // Shown on the login button
+	OIDCGroupsClaim  string         // ID token claim listing the user's groups
+	OIDCRoleMapping  []GroupMapping // Group to role; the first matching entry wins
+	OIDCDefaultRole  string         // Role of users without a mapped group; empty refuses them
+	OIDCStoreMapping []GroupMapping // Group to store name; empty leaves store assignments alone
+
+	// Initial admin account, created on first start
+	AdminEmail           string // Receives the password reset link when no initial password is set
+	AdminInitialPassword string // Must satisfy the password policy; empty requires a password reset
+
+	// Password reset
+	PasswordResetTTL time.Duration // Lifetime of password reset tokens
+	PasswordResetURL string        // Frontend page that receives the reset token as ?token=
+
+	// Password policy
+	PasswordMinLength    int    // Minimum length in characters
+	PasswordMaxLength    int    // Maximum length in characters (argon2id hashes any length, this bounds the work)
+	PasswordBreachedList string // Local list of breached passwords, in addition to the built-in list of common ones
+	PasswordHistory      int    // Number of previous passwords that cannot be reused; 0 disables the check
+
+	// Audit log
+	AuditRetention time.Duration // Age at which audit log entries are purged; 0 keeps them forever
+
+	// Impersonation ("view as user")
+	ImpersonationTTL time.Duration // Lifetime of impersonation tokens, which cannot be refreshed
+
+	// Deactivated users
+	UserAnonymizeAfter time.Duration // Time after deactivation at which personal data is erased; 0 keeps it
+
+	// Mail delivery
+	MailBackend  string // "log" (default, development only) or "smtp"
+	MailFrom     string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string // Empty for unauthenticated relays and local stand-ins
+	SMTPPassword string
+
 	// Server configuration
-	ServerPort string
+	ServerPort      string
+	ShutdownTimeout time.Duration // Deadline for draining on SIGTERM
+	TrustedProxies  []string      // Proxy IPs/CIDRs whose X-Forwarded-For is believed; empty uses the connection's address
+}
+
+// GroupMapping maps an identity provider group to a value, configured as "<group>=<value>"
+type GroupMapping struct {
+	Group string
+	Value string
+}
+
+// RateLimit is a request budget per sliding window, configured as "<limit>/<window>" (e.g. "20/1m")
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
 }
 
 var CONFIG *Config
@@ -46,10 +142,76 @@ func LoadConfig() *Config {
 		RedisHost: getEnv("REDIS_HOST", "localhost"),
 		RedisPort: getEnv("REDIS_PORT", "6379"),
 
+		CacheBackend: getEnv("CACHE_BACKEND", "redis"),
+		CacheL1Size:  getEnvInt("CACHE_L1_SIZE", 10000),
+		CacheL1TTL:   getEnvDuration("CACHE_L1_TTL", 30*time.Second),
+
 		KafkaBrokers: getEnv("KAFKA_BROKERS", "localhost:9092"),
 		KafkaTopic:   getEnv("KAFKA_TOPIC", "inventory-updates"),
 
-		ServerPort: getEnv("SERVER_PORT", "3000"),
+		EventDedupTTL: getEnvDuration("EVENT_DEDUP_TTL", 24*time.Hour),
+
+		AccessTokenTTL:          getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:         getEnvDuration("REFRESH_TOKEN_TTL", 24*time.Hour),
+		RefreshTokenRememberTTL: getEnvDuration("REFRESH_TOKEN_REMEMBER_TTL", 7*24*time.Hour),
+
+		JWTAlgorithm:        getEnv("JWT_ALGORITHM", "RS256"),
+		JWTKeyFiles:         getEnvList("JWT_KEY_FILES", nil),
+		JWTKeyDir:           getEnv("JWT_KEY_DIR", ""),
+		JWTSigningKeyID:     getEnv("JWT_SIGNING_KEY_ID", ""),
+		JWTKeyRotation:      getEnvDuration("JWT_KEY_ROTATION", 30*24*time.Hour),
+		JWTKeyReload:        getEnvDuration("JWT_KEY_RELOAD_INTERVAL", time.Minute),
+		JWTKeyEncryptionKey: getEnv("JWT_KEY_ENCRYPTION_KEY", ""),
+
+		RateLimitPublic:  getEnvRateLimit("RATE_LIMIT_PUBLIC", RateLimit{Limit: 60, Window: time.Minute}),
+		RateLimitLogin:   getEnvRateLimit("RATE_LIMIT_LOGIN", RateLimit{Limit: 10, Window: time.Minute}),
+		RateLimitAPI:     getEnvRateLimit("RATE_LIMIT_API", RateLimit{Limit: 600, Window: time.Minute}),
+		RateLimitAPIIP:   getEnvRateLimit("RATE_LIMIT_API_IP", RateLimit{Limit: 1200, Window: time.Minute}),
+		LoginMaxAttempts: getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginLockout:     getEnvDuration("LOGIN_LOCKOUT", 15*time.Minute),
+
+		MFAIssuer:        getEnv("MFA_ISSUER", "Inventory Manager"),
+		MFAChallengeTTL:  getEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+		MFARequiredRoles: getEnvList("MFA_REQUIRED_ROLES", []string{"manager"}),
+
+		OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/oidc/callback"),
+		OIDCScopes:       getEnvList("OIDC_SCOPES", []string{"openid", "profile", "email"}),
+		OIDCDisplayName:  getEnv("OIDC_DISPLAY_NAME", "SSO"),
+		OIDCGroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCRoleMapping:  getEnvGroupMapping("OIDC_ROLE_MAPPING"),
+		OIDCDefaultRole:  getEnv("OIDC_DEFAULT_ROLE", ""),
+		OIDCStoreMapping: getEnvGroupMapping("OIDC_STORE_MAPPING"),
+
+		AdminEmail:           getEnv("ADMIN_EMAIL", "admin@admin.com"),
+		AdminInitialPassword: getEnv("ADMIN_INITIAL_PASSWORD", ""),
+
+		PasswordResetTTL: getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+
+		PasswordMinLength:    getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:    getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordBreachedList: getEnv("PASSWORD_BREACHED_LIST", ""),
+		PasswordHistory:      getEnvInt("PASSWORD_HISTORY", 5),
+
+		AuditRetention: getEnvDuration("AUDIT_RETENTION", 365*24*time.Hour),
+
+		ImpersonationTTL: getEnvDuration("IMPERSONATION_TTL", 15*time.Minute),
+
+		UserAnonymizeAfter: getEnvDuration("USER_ANONYMIZE_AFTER", 90*24*time.Hour),
+
+		MailBackend:  getEnv("MAIL_BACKEND", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "Inventory Manager <no-reply@inventory.local>"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "1025"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
+		ServerPort:      getEnv("SERVER_PORT", "3000"),
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 25*time.Second),
+		TrustedProxies:  getEnvList("TRUSTED_PROXIES", nil),
 	}
 	return CONFIG
 }
@@ -60,3 +222,81 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid duration %q for %s, using default %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid integer %q for %s, using default %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvList reads a comma-separated list; set the variable to "none" for an empty list
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	if value == "none" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvGroupMapping reads comma-separated "<group>=<value>" pairs
+func getEnvGroupMapping(key string) []GroupMapping {
+	var mappings []GroupMapping
+	for _, item := range getEnvList(key, nil) {
+		group, value, ok := strings.Cut(item, "=")
+		group, value = strings.TrimSpace(group), strings.TrimSpace(value)
+		if !ok || group == "" || value == "" {
+			log.Printf("Warning: ignoring invalid mapping %q in %s, expected <group>=<value>", item, key)
+			continue
+		}
+		mappings = append(mappings, GroupMapping{Group: group, Value: value})
+	}
+	return mappings
+}
+
+func getEnvRateLimit(key string, defaultValue RateLimit) RateLimit {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	limitStr, windowStr, ok := strings.Cut(value, "/")
+	limit, err := strconv.Atoi(limitStr)
+	if !ok || err != nil || limit <= 0 {
+		log.Printf("Warning: invalid rate limit %q for %s, using default %d/%s", value, key, defaultValue.Limit, defaultValue.Window)
+		return defaultValue
+	}
+	window, err := time.ParseDuration(windowStr)
+	if err != nil || window <= 0 {
+		log.Printf("Warning: invalid rate limit %q for %s, using default %d/%s", value, key, defaultValue.Limit, defaultValue.Window)
+		return defaultValue
+	}
+	return RateLimit{Limit: limit, Window: window}
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// AuditPurgeSetting is the transaction-local setting that allows deleting audit log rows;
+// only the retention job sets it
+const AuditPurgeSetting = "audit_log.purge"
+
+// AuditAnonymizeSetting is the transaction-local setting that allows rewriting the names, client
+// IPs and diffs of audit log rows; only user anonymization sets it
+const AuditAnonymizeSetting = "audit_log.anonymize"
+
+// auditLogTriggerSQL makes audit_log append-only (also in init.sql, for databases that are not auto-migrated)
+const auditLogTriggerSQL = `
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('` + AuditPurgeSetting + `', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    IF TG_OP = 'UPDATE' AND current_setting('` + AuditAnonymizeSetting + `', true) = 'on'
+        AND (NEW.id, NEW.actor_id, NEW.action, NEW.target_type, NEW.target_id, NEW.request_id, NEW.created_at)
+            IS NOT DISTINCT FROM (OLD.id, OLD.actor_id, OLD.action, OLD.target_type, OLD.target_id, OLD.request_id, OLD.created_at) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE OR REPLACE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+`
+
+// protectAuditLog installs the triggers that reject changes to audit log rows
+func protectAuditLog(db *gorm.DB) error {
+	return db.Exec(auditLogTriggerSQL).Error
+}
@@ -26,18 +26,42 @@ func InitDB(dsn string) error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := registerStoreScopeCheck(DB); err != nil {
+		return fmt.Errorf("failed to register store scope check: %w", err)
+	}
+
 	// Auto migrate models
 	err = DB.AutoMigrate(
+		&models.Role{},
+		&models.RolePermission{},
 		&models.User{},
 		&models.Store{},
 		&models.StoreUser{},
 		&models.SKU{},
 		&models.Inventory{},
 		&models.Outbox{},
+		&models.StockSummary{},
+		&models.EventCheckpoint{},
+		&models.ScheduledJob{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
+		&models.PasswordHistory{},
+		&models.UserTOTP{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.ServiceAccount{},
+		&models.APIKey{},
+		&models.APIKeyPermission{},
+		&models.APIKeyStore{},
+		&models.AuditLog{},
+		&models.SigningKey{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to auto migrate: %w", err)
 	}
+	if err := protectAuditLog(DB); err != nil {
+		return fmt.Errorf("failed to protect audit log: %w", err)
+	}
 
 	log.Println("Database connected and migrated successfully")
 	return nil
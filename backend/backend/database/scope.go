@@ -0,0 +1,77 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// StoreScopeKey is set on statements limited to the stores the caller may access
+// (see services.StoreScope). Statements on store-scoped tables without it fail with
+// ErrUnscopedQuery, so a new query cannot silently skip the store access check.
+const StoreScopeKey = "store_scope"
+
+// ErrUnscopedQuery is returned for queries on a store-scoped table without a store scope
+var ErrUnscopedQuery = errors.New("query on store-scoped table without a store scope")
+
+// storeScopedTables lists the tables whose rows belong to a store
+var storeScopedTables = map[string]bool{
+	"inventory": true,
+}
+
+// rawTableRef matches the tables named by raw SQL
+var rawTableRef = regexp.MustCompile(`(?i)\b(?:from|join|update|into)\s+"?(\w+)"?`)
+
+// registerStoreScopeCheck makes every statement on a store-scoped table require a store scope:
+// queries, Row/Rows and Raw(...).Scan (row), updates including Save, deletes, and Exec (raw,
+// matched by the table names in the SQL). Creates are not checked, because a new row names its
+// store explicitly and callers check it with StoreScope.CheckStore first.
+func registerStoreScopeCheck(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("store_scope:check", checkStoreScope); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("store_scope:check", checkStoreScope); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("store_scope:check", checkStoreScope); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("store_scope:check", checkStoreScope); err != nil {
+		return err
+	}
+	return callbacks.Raw().Before("gorm:raw").Register("store_scope:check", checkStoreScope)
+}
+
+func checkStoreScope(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	table := scopedTable(db.Statement)
+	if table == "" {
+		return
+	}
+	if _, ok := db.Get(StoreScopeKey); !ok {
+		db.AddError(fmt.Errorf("%w: %s", ErrUnscopedQuery, table))
+	}
+}
+
+// scopedTable returns the store-scoped table a statement works on, if any: its model's table,
+// or for raw SQL (already built when the callbacks run) the first scoped table it names
+func scopedTable(stmt *gorm.Statement) string {
+	if storeScopedTables[stmt.Table] {
+		return stmt.Table
+	}
+	if stmt.SQL.Len() == 0 {
+		return ""
+	}
+	for _, match := range rawTableRef.FindAllStringSubmatch(stmt.SQL.String(), -1) {
+		if table := strings.ToLower(match[1]); storeScopedTables[table] {
+			return table
+		}
+	}
+	return ""
+}
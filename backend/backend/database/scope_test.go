@@ -0,0 +1,92 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newDryRunDB returns a database that builds statements without a server, with the store
+// scope check registered. Writes skip the default transaction, which would need a connection.
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 user=test dbname=test sslmode=disable"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("failed to open dry-run database: %v", err)
+	}
+	if err := registerStoreScopeCheck(db); err != nil {
+		t.Fatalf("failed to register store scope check: %v", err)
+	}
+	return db
+}
+
+func TestStoreScopeCheck(t *testing.T) {
+	id := uuid.New()
+	statements := []struct {
+		name   string
+		scoped bool // Works on a store-scoped table
+		run    func(db *gorm.DB) error
+	}{
+		{"query", true, func(db *gorm.DB) error {
+			return db.Find(&[]models.Inventory{}).Error
+		}},
+		{"count", true, func(db *gorm.DB) error {
+			var count int64
+			return db.Model(&models.Inventory{}).Count(&count).Error
+		}},
+		{"rows", true, func(db *gorm.DB) error {
+			_, err := db.Model(&models.Inventory{}).Select("id").Rows()
+			return err
+		}},
+		{"raw scan", true, func(db *gorm.DB) error {
+			var quantity int
+			return db.Raw("SELECT SUM(quantity) FROM inventory WHERE sku_id = ?", id).Scan(&quantity).Error
+		}},
+		{"update", true, func(db *gorm.DB) error {
+			return db.Model(&models.Inventory{ID: id}).Update("quantity", 0).Error
+		}},
+		{"save", true, func(db *gorm.DB) error {
+			return db.Save(&models.Inventory{ID: id, Quantity: 1}).Error
+		}},
+		{"delete", true, func(db *gorm.DB) error {
+			return db.Delete(&models.Inventory{ID: id}).Error
+		}},
+		{"exec", true, func(db *gorm.DB) error {
+			return db.Exec("UPDATE inventory SET quantity = 0").Error
+		}},
+		{"exec reading inventory", true, func(db *gorm.DB) error {
+			return db.Exec(`INSERT INTO stock_summary (store_id) SELECT i.store_id FROM "inventory" i`).Error
+		}},
+		{"other table", false, func(db *gorm.DB) error {
+			return db.Find(&[]models.SKU{}).Error
+		}},
+		{"exec on other table", false, func(db *gorm.DB) error {
+			return db.Exec("DELETE FROM stock_summary").Error
+		}},
+	}
+
+	db := newDryRunDB(t)
+	for _, statement := range statements {
+		t.Run(statement.name, func(t *testing.T) {
+			err := statement.run(db)
+			if statement.scoped && !errors.Is(err, ErrUnscopedQuery) {
+				t.Errorf("unscoped statement error = %v, want %v", err, ErrUnscopedQuery)
+			}
+			if !statement.scoped && err != nil {
+				t.Errorf("statement on a table without stores failed: %v", err)
+			}
+
+			// Dry runs cannot return rows, which is reported after the scope check passed
+			err = statement.run(db.Set(StoreScopeKey, "test"))
+			if err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+				t.Errorf("scoped statement failed: %v", err)
+			}
+		})
+	}
+}
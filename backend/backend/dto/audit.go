@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+)
+
+// AuditQueryParams filters and pages the audit log
+type AuditQueryParams struct {
+	ActorID    string     `form:"actor_id"`
+	Actor      string     `form:"actor"`  // Actor name, case-insensitive substring
+	Action     string     `form:"action"` // Exact action ("user.delete"), or every action on a target type ("user")
+	TargetType string     `form:"target_type"`
+	TargetID   string     `form:"target_id"`
+	RequestID  string     `form:"request_id"`
+	From       *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Format     string     `form:"format,default=json" binding:"oneof=json csv"`
+	Page       int        `form:"page,default=1" binding:"min=1"`
+	PageSize   int        `form:"page_size,default=50" binding:"min=1,max=200"`
+}
+
+// AuditLogResponse is one audit log entry
+type AuditLogResponse struct {
+	ID         uuid.UUID   `json:"id"`
+	ActorID    uuid.UUID   `json:"actor_id"`
+	ActorName  string      `json:"actor_name"`
+	Action     string      `json:"action"`
+	TargetType string      `json:"target_type"`
+	TargetID   uuid.UUID   `json:"target_id"`
+	TargetName string      `json:"target_name"`
+	Diff       models.JSON `json:"diff"` // {"field": {"old": ..., "new": ...}}
+	ClientIP   string      `json:"client_ip"`
+	RequestID  string      `json:"request_id"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// AuditListResponse is one page of audit log entries, newest first
+type AuditListResponse struct {
+	Items      []AuditLogResponse `json:"items"`
+	Total      int64              `json:"total"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"page_size"`
+	TotalPages int                `json:"total_pages"`
+}
@@ -7,8 +7,36 @@ type LoginRequest struct {
 	RememberMe bool   `json:"rememberMe"`
 }
 
-// LoginResponse represents a login response
+// LoginResponse represents a login (or token refresh) response
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int          `json:"expires_in"` // Access token lifetime in seconds
+	User         UserResponse `json:"user"`
+	// The user's active store assignments, so clients can show a store picker without another call
+	Stores []AssignedStore `json:"stores"`
+	// Set only when a login completed two-factor enrolment; shown once
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// RefreshRequest represents a token refresh request
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest represents a logout request
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+	All          bool   `json:"all"` // Revoke every session of the user
+}
+
+// ForgotPasswordRequest represents a request for a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents a password reset with a token from a reset email
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
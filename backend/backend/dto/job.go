@@ -0,0 +1,19 @@
+package dto
+
+import (
+	"time"
+)
+
+// JobResponse represents the state of a scheduled background job
+type JobResponse struct {
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule"`
+	Owner          string     `json:"owner"` // Instance that claimed the current or last run
+	Running        bool       `json:"running"`
+	FencingToken   int64      `json:"fencing_token"`
+	LastStartedAt  *time.Time `json:"last_started_at"`
+	LastFinishedAt *time.Time `json:"last_finished_at"`
+	LastStatus     string     `json:"last_status"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextRunAt      time.Time  `json:"next_run_at"`
+}
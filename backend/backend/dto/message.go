@@ -1,6 +1,7 @@
 package dto
 
 type Message struct {
+	EventID          string      `json:"event_id,omitempty"` // Stable id (Outbox.ID) used by consumers for deduplication
 	SenderInstanceID string      `json:"sender_instance_id"`
 	Payload          interface{} `json:"payload"`
 }
@@ -0,0 +1,43 @@
+package dto
+
+// MFAChallengeResponse is returned by login instead of tokens when a second factor is required
+type MFAChallengeResponse struct {
+	MFARequired        bool   `json:"mfa_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"` // The user's role requires 2FA but no authenticator is set up yet
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int    `json:"expires_in"` // Challenge token lifetime in seconds
+}
+
+// MFAVerifyRequest completes a login challenge with a TOTP code or a recovery code
+type MFAVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// MFAChallengeRequest identifies a login challenge
+type MFAChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// MFACodeRequest carries a TOTP code (or, where accepted, a recovery code)
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAStatusResponse describes the two-factor authentication state of the current user
+type MFAStatusResponse struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"` // Required by the user's role; cannot be disabled
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// MFAEnrollmentResponse carries a new TOTP secret; the user confirms it with a first code
+type MFAEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to render as a QR code
+}
+
+// RecoveryCodesResponse carries new recovery codes; they are shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
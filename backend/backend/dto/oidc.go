@@ -0,0 +1,27 @@
+package dto
+
+// OIDCConfigResponse tells the login page whether to offer single sign-on
+type OIDCConfigResponse struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name,omitempty"` // Label for the sign-in button
+}
+
+// OIDCAuthorizeRequest starts a single sign-on login
+type OIDCAuthorizeRequest struct {
+	RememberMe bool `json:"rememberMe"`
+}
+
+// OIDCAuthorizeResponse carries the identity provider URL to redirect the browser to
+type OIDCAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	// Binding is kept by the browser (not sent to the identity provider) and returned with the callback
+	Binding string `json:"binding"`
+}
+
+// OIDCCallbackRequest carries the parameters the identity provider redirected back with, and
+// the binding returned when the login started
+type OIDCCallbackRequest struct {
+	Code    string `json:"code" binding:"required"`
+	State   string `json:"state" binding:"required"`
+	Binding string `json:"binding" binding:"required"`
+}
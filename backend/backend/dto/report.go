@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StockSummaryRow represents the stock of one category in one store
+type StockSummaryRow struct {
+	StoreID         uuid.UUID `json:"store_id"`
+	StoreName       string    `json:"store_name"`
+	Category        string    `json:"category"`
+	SKUCount        int64     `json:"sku_count"`
+	TotalQuantity   int64     `json:"total_quantity"`
+	OutOfStockCount int64     `json:"out_of_stock_count"`
+	TotalValue      float64   `json:"total_value"`
+	RefreshedAt     time.Time `json:"refreshed_at"`
+}
+
+// StoreStockSummary represents the stock totals of one store across all categories
+type StoreStockSummary struct {
+	StoreID         uuid.UUID `json:"store_id"`
+	StoreName       string    `json:"store_name"`
+	SKUCount        int64     `json:"sku_count"`
+	TotalQuantity   int64     `json:"total_quantity"`
+	OutOfStockCount int64     `json:"out_of_stock_count"`
+	TotalValue      float64   `json:"total_value"`
+}
+
+// StockSummaryResponse represents the stock summary report
+// AsOf is the creation time of the newest event the projection applied; StaleSeconds is how much
+// older it is than the newest event written to the outbox (0 when the projection is up to date)
+type StockSummaryResponse struct {
+	Stores       []StoreStockSummary `json:"stores"`
+	Categories   []StockSummaryRow   `json:"categories"`
+	AsOf         *time.Time          `json:"as_of"`
+	StaleSeconds float64             `json:"stale_seconds"`
+}
@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServiceAccountResponse represents a service account and its API keys in API responses
+type ServiceAccountResponse struct {
+	ID          uuid.UUID        `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Keys        []APIKeyResponse `json:"keys"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// APIKeyResponse represents an API key in API responses (never the key itself)
+type APIKeyResponse struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Prefix      string      `json:"prefix"`
+	Permissions []string    `json:"permissions"`
+	StoreIDs    []uuid.UUID `json:"store_ids"`
+	ExpiresAt   *time.Time  `json:"expires_at"`
+	LastUsedAt  *time.Time  `json:"last_used_at"`
+	RevokedAt   *time.Time  `json:"revoked_at"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse is returned once when an API key is created; the key cannot be retrieved later
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// CreateServiceAccountRequest represents a request to create a service account
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Description string `json:"description" binding:"max=255"`
+}
+
+// UpdateServiceAccountRequest represents a request to update a service account
+type UpdateServiceAccountRequest struct {
+	Description *string `json:"description" binding:"omitempty,max=255"`
+}
+
+// CreateAPIKeyRequest represents a request to create an API key for a service account
+// Without the inventory.all_stores permission, the key only covers the listed stores.
+type CreateAPIKeyRequest struct {
+	Name        string      `json:"name" binding:"required,max=100"`
+	Permissions []string    `json:"permissions" binding:"required"`
+	StoreIDs    []uuid.UUID `json:"store_ids"`
+	ExpiresAt   *time.Time  `json:"expires_at"`
+}
@@ -21,10 +21,23 @@ type CreateStoreRequest struct {
 	Address string `json:"address" binding:"required,max=255"`
 }
 
+// StoreAssignmentFields are the optional terms of a store assignment
+type StoreAssignmentFields struct {
+	Role      *string    `json:"role" binding:"omitempty,max=50"` // Store role; its permissions apply in this store only
+	ValidFrom *time.Time `json:"valid_from"`                      // Omit for immediate access
+	ValidTo   *time.Time `json:"valid_to"`                        // Exclusive; omit for access until removed
+}
+
 // AddStaffToStoreRequest represents a request to add staff to a store
 type AddStaffToStoreRequest struct {
 	StoreID uuid.UUID `json:"store_id" binding:"required"`
 	UserID  uuid.UUID `json:"user_id" binding:"required"`
+	StoreAssignmentFields
+}
+
+// UpdateStoreStaffRequest replaces the terms of a store assignment
+type UpdateStoreStaffRequest struct {
+	StoreAssignmentFields
 }
 
 // StoreStaffResponse represents a store staff member in API responses
@@ -32,14 +45,56 @@ type StoreStaffResponse struct {
 	ID        uuid.UUID     `json:"id"`
 	StoreID   uuid.UUID     `json:"store_id"`
 	UserID    uuid.UUID     `json:"user_id"`
+	Role      *string       `json:"role"`
+	ValidFrom *time.Time    `json:"valid_from"`
+	ValidTo   *time.Time    `json:"valid_to"`
+	Active    bool          `json:"active"` // Whether the assignment grants access now
 	User      UserResponse  `json:"user"`
 	Store     StoreResponse `json:"store"`
 	CreatedAt time.Time     `json:"created_at"`
 	UpdatedAt time.Time     `json:"updated_at"`
 }
 
+// StoreStaffMember is a user assigned to a store, with the terms of the assignment
+type StoreStaffMember struct {
+	UserResponse
+	AssignmentID uuid.UUID  `json:"assignment_id"`
+	StoreRole    *string    `json:"store_role"`
+	ValidFrom    *time.Time `json:"valid_from"`
+	ValidTo      *time.Time `json:"valid_to"`
+	Active       bool       `json:"active"`
+}
+
 // StoreStaffListResponse represents the response for listing store staff
 type StoreStaffListResponse struct {
-	StoreID uuid.UUID      `json:"store_id"`
-	Staff   []UserResponse `json:"staff"`
+	StoreID uuid.UUID          `json:"store_id"`
+	Staff   []StoreStaffMember `json:"staff"`
+}
+
+// StoreStockCounts holds the stock counts of a store from the stock summary projection
+type StoreStockCounts struct {
+	SKUCount        int64      `json:"sku_count"`
+	TotalQuantity   int64      `json:"total_quantity"`
+	OutOfStockCount int64      `json:"out_of_stock_count"`
+	AsOf            *time.Time `json:"as_of"` // Oldest refresh time of the store's rows; nil if it has none
+}
+
+// AssignedStore is a store the user is assigned to, with the terms of the assignment
+type AssignedStore struct {
+	StoreResponse
+	StoreRole *string           `json:"store_role"`
+	ValidFrom *time.Time        `json:"valid_from"`
+	ValidTo   *time.Time        `json:"valid_to"`
+	Stock     *StoreStockCounts `json:"stock,omitempty"` // Omitted from login responses
+}
+
+// StoreDetailResponse represents a store with its stock counts; the assignment fields are set
+// when the caller is assigned to the store
+type StoreDetailResponse struct {
+	StoreResponse
+	Assigned  bool             `json:"assigned"`
+	StoreRole *string          `json:"store_role"`
+	ValidFrom *time.Time       `json:"valid_from"`
+	ValidTo   *time.Time       `json:"valid_to"`
+	Stock     StoreStockCounts `json:"stock"`
 }
@@ -14,6 +14,23 @@ type UserResponse struct {
 	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// Permissions of the user's role (only in login and profile responses)
+	Permissions []string `json:"permissions,omitempty"`
+	// Status and deactivation details (only in user management responses)
+	Status             string     `json:"status,omitempty"` // "active" or "deactivated"
+	DeactivatedAt      *time.Time `json:"deactivated_at,omitempty"`
+	DeactivationReason string     `json:"deactivation_reason,omitempty"`
+	Anonymized         bool       `json:"anonymized,omitempty"`
+	// Set only in profile responses of impersonation sessions
+	Impersonation *ImpersonationInfo `json:"impersonation,omitempty"`
+}
+
+// ImpersonationInfo marks a session in which a manager views the application as another user
+type ImpersonationInfo struct {
+	ImpersonatorID       uuid.UUID `json:"impersonator_id"`
+	ImpersonatorUsername string    `json:"impersonator_username"`
+	ReadOnly             bool      `json:"read_only"`
+	ExpiresAt            time.Time `json:"expires_at"`
 }
 
 // UserPreviewResponse represents a user in API responses
@@ -27,7 +44,7 @@ type CreateUserRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
-	Role     string `json:"role" binding:"required,oneof=manager staff"`
+	Role     string `json:"role" binding:"required,max=50"`
 }
 
 // UpdateUserRequest represents a request to update user information
@@ -35,11 +52,30 @@ type UpdateUserRequest struct {
 	TargetID uuid.UUID `json:"target_id" binding:"required"`
 	Username string    `json:"username" binding:"omitempty"`
 	Email    string    `json:"email" binding:"omitempty,email"`
-	Role     string    `json:"role" binding:"omitempty,oneof=manager staff"`
+	Role     string    `json:"role" binding:"omitempty,max=50"`
+}
+
+// DeactivateUserRequest represents a request to deactivate a user
+type DeactivateUserRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// ImpersonateUserRequest represents a request to view the application as another user
+type ImpersonateUserRequest struct {
+	AllowWrites bool   `json:"allow_writes"` // Read-only unless set
+	Reason      string `json:"reason" binding:"max=255"`
+}
+
+// ImpersonationResponse carries a short-lived access token of an impersonation session; it
+// cannot be refreshed
+type ImpersonationResponse struct {
+	Token     string       `json:"token"`
+	ExpiresIn int          `json:"expires_in"` // Token lifetime in seconds
+	User      UserResponse `json:"user"`
 }
 
 // ChangePasswordRequest represents a request to change password
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=8"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
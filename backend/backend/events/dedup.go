@@ -0,0 +1,64 @@
+package events
+
+import (
+	"fmt"
+	"log"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/config"
+
+	"github.com/google/uuid"
+)
+
+// Dedup scopes
+const (
+	ScopeInstance = "instance" // Applied once per instance (e.g. broadcasting to local WebSocket clients)
+	ScopeCluster  = "cluster"  // Applied once across all instances (e.g. projections)
+)
+
+// onceSink applies each event at most once per scope, using a TTL'd processed-id set in the shared cache
+type onceSink struct {
+	sink  Sink
+	scope string
+}
+
+// Once wraps a sink so redelivered or republished events (same Outbox.ID) are only applied once
+func Once(sink Sink, scope string) Sink {
+	return onceSink{sink: sink, scope: scope}
+}
+
+func (s onceSink) Name() string { return s.sink.Name() }
+
+func (s onceSink) Apply(event Event) error {
+	if event.Record.ID == uuid.Nil {
+		// Without an event id, fall back to at-least-once delivery
+		return s.sink.Apply(event)
+	}
+
+	key := s.key(event.Record.ID)
+	claimed, err := cache.SetNX(key, config.CONFIG.InstanceID, config.CONFIG.EventDedupTTL)
+	if err != nil {
+		log.Printf("Warning: failed to check processed event %s: %v (applying anyway)", event.Record.ID, err)
+		return s.sink.Apply(event)
+	}
+	if !claimed {
+		log.Printf("Skipping duplicate event %s for sink %s", event.Record.ID, s.sink.Name())
+		return nil
+	}
+
+	if err := s.sink.Apply(event); err != nil {
+		// Release the claim so a redelivery can retry the effect
+		cache.Delete(key)
+		return err
+	}
+	return nil
+}
+
+// key builds the processed-id key for an event in this sink's scope
+func (s onceSink) key(eventID uuid.UUID) string {
+	scope := s.scope
+	if scope == ScopeInstance {
+		scope = config.CONFIG.InstanceID
+	}
+	return fmt.Sprintf("events:processed:%s:%s:%s", scope, s.sink.Name(), eventID)
+}
@@ -1,30 +1,26 @@
 package events
 
 import (
-	"inventory-manager-server/dto"
-	"inventory-manager-server/websocket"
 	"log"
 
 	"github.com/IBM/sarama"
-	"github.com/bytedance/sonic"
 )
 
+// LiveSinks are applied to every event received by the Kafka consumer
+// Each sink applies an event once, deduplicated by its Outbox.ID
+var LiveSinks = []Sink{
+	Once(NewBroadcastSink(), ScopeInstance),
+	Once(NewProjectionSink(), ScopeCluster),
+}
+
 func InventoryUpdateHandler(msg *sarama.ConsumerMessage) {
-	message := dto.Message{}
-	if err := sonic.Unmarshal(msg.Value, &message); err != nil {
-		log.Printf("Warning: Failed to unmarshal message: %v (continuing without Kafka consumer)", err)
+	event, err := DecodeEvent(msg.Value)
+	if err != nil {
+		log.Printf("Warning: %v (continuing without Kafka consumer)", err)
 		return
 	}
 
 	// Broadcast to WebSocket for all messages (both from this instance and other instances)
 	// This ensures all connected clients receive updates regardless of which node they're connected to
-	if websocket.Hub != nil {
-		messageBytes, err := sonic.Marshal(message.Payload)
-		if err == nil {
-			websocket.Hub.Broadcast(messageBytes)
-			log.Printf("Broadcasted inventory update from instance %s", message.SenderInstanceID)
-		} else {
-			log.Printf("Failed to marshal message payload: %v", err)
-		}
-	}
+	ApplyAll(event, LiveSinks)
 }
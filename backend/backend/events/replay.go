@@ -0,0 +1,141 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"inventory-manager-server/database"
+	"inventory-manager-server/kafka"
+	"inventory-manager-server/models"
+
+	"github.com/IBM/sarama"
+	"gorm.io/gorm"
+)
+
+// Replay sources
+const (
+	SourceKafka  = "kafka"  // Events retained in the Kafka topic
+	SourceOutbox = "outbox" // Records still waiting in the outbox table
+)
+
+// replayBatchSize is the number of outbox records loaded per query
+const replayBatchSize = 500
+
+// ReplayOptions configures an event replay
+type ReplayOptions struct {
+	Source     string
+	Brokers    []string
+	Topic      string
+	From       time.Time
+	To         time.Time // Zero means up to now
+	EntityType string    // Only replay events for this entity type (empty means all)
+	Sinks      []Sink
+	DryRun     bool // Log events without applying them to sinks
+	Rate       int  // Maximum events per second (0 means unlimited)
+}
+
+// ReplayStats summarises a replay run
+type ReplayStats struct {
+	Read    int
+	Applied int
+	Skipped int
+	Failed  int
+}
+
+// Replay re-reads persisted events and re-applies them to the configured sinks
+func Replay(ctx context.Context, opts ReplayOptions) (ReplayStats, error) {
+	var stats ReplayStats
+	var throttle <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.Rate))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	apply := func(event Event) error {
+		stats.Read++
+		if opts.EntityType != "" && event.Record.EntityType != opts.EntityType {
+			stats.Skipped++
+			return nil
+		}
+		if throttle != nil {
+			select {
+			case <-throttle:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if opts.DryRun {
+			stats.Skipped++
+			return NewLogSink().Apply(event)
+		}
+
+		failed := false
+		for _, sink := range opts.Sinks {
+			if err := sink.Apply(event); err != nil {
+				log.Printf("Sink %s failed for event %s: %v", sink.Name(), event.Record.ID, err)
+				failed = true
+			}
+		}
+		if failed {
+			stats.Failed++
+		} else {
+			stats.Applied++
+		}
+		return nil
+	}
+
+	var err error
+	switch opts.Source {
+	case SourceKafka:
+		err = kafka.ReadRange(ctx, opts.Brokers, opts.Topic, opts.From, opts.To, func(msg *sarama.ConsumerMessage) error {
+			event, err := DecodeEvent(msg.Value)
+			if err != nil {
+				log.Printf("Skipping offset %d on partition %d: %v", msg.Offset, msg.Partition, err)
+				stats.Read++
+				stats.Skipped++
+				return nil
+			}
+			return apply(event)
+		})
+	case SourceOutbox:
+		err = replayOutbox(opts, apply)
+	default:
+		err = fmt.Errorf("unknown replay source %q", opts.Source)
+	}
+	return stats, err
+}
+
+// replayOutbox reads outbox records created in the replay window, oldest first
+func replayOutbox(opts ReplayOptions, apply func(Event) error) error {
+	if database.DB == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	query := database.DB.Model(&models.Outbox{}).Where("created_at >= ?", opts.From)
+	if !opts.To.IsZero() {
+		query = query.Where("created_at <= ?", opts.To)
+	}
+
+	for offset := 0; ; offset += replayBatchSize {
+		var records []models.Outbox
+		if err := query.Session(&gorm.Session{}).Order("created_at ASC, id ASC").
+			Offset(offset).Limit(replayBatchSize).Find(&records).Error; err != nil {
+			return fmt.Errorf("failed to query outbox: %w", err)
+		}
+		for _, record := range records {
+			event, err := EventFromOutbox(record)
+			if err != nil {
+				return err
+			}
+			if err := apply(event); err != nil {
+				return err
+			}
+		}
+		if len(records) < replayBatchSize {
+			return nil
+		}
+	}
+}
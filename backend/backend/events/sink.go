@@ -0,0 +1,262 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+	"inventory-manager-server/services"
+	"inventory-manager-server/websocket"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+)
+
+// Event is a decoded outbox event read from Kafka or the outbox table
+type Event struct {
+	SenderInstanceID string
+	Record           models.Outbox
+	Payload          []byte // Raw JSON of the outbox record as published
+}
+
+// Sink applies an event to a downstream target (WebSocket clients, caches, projections, webhooks)
+type Sink interface {
+	Name() string
+	Apply(event Event) error
+}
+
+// DecodeEvent decodes a Kafka message value produced by kafka.PublishMessage
+func DecodeEvent(value []byte) (Event, error) {
+	var envelope struct {
+		SenderInstanceID string          `json:"sender_instance_id"`
+		Payload          json.RawMessage `json:"payload"`
+	}
+	if err := sonic.Unmarshal(value, &envelope); err != nil {
+		return Event{}, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+
+	event := Event{
+		SenderInstanceID: envelope.SenderInstanceID,
+		Payload:          []byte(envelope.Payload),
+	}
+	if err := sonic.Unmarshal(event.Payload, &event.Record); err != nil {
+		return Event{}, fmt.Errorf("failed to unmarshal outbox record: %w", err)
+	}
+	// Records published before entity types existed are inventory records
+	if event.Record.EntityType == "" {
+		event.Record.EntityType = models.EntityInventory
+	}
+	return event, nil
+}
+
+// EventFromOutbox builds an event from an outbox record that has not been published yet
+func EventFromOutbox(record models.Outbox) (Event, error) {
+	payload, err := sonic.Marshal(record)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to marshal outbox record: %w", err)
+	}
+	return Event{
+		SenderInstanceID: record.SenderInstanceID,
+		Record:           record,
+		Payload:          payload,
+	}, nil
+}
+
+// ApplyAll applies an event to every sink, logging (not stopping on) individual failures
+func ApplyAll(event Event, sinks []Sink) {
+	for _, sink := range sinks {
+		if err := sink.Apply(event); err != nil {
+			log.Printf("Sink %s failed for event %s: %v", sink.Name(), event.Record.ID, err)
+		}
+	}
+}
+
+// redactedFields are removed from the diffs of events sent outside the backend (WebSocket
+// clients and webhooks), by entity type; the audit log keeps them
+var redactedFields = map[string][]string{
+	models.EntityUser: {"deactivation_reason"},
+}
+
+// broadcastSink forwards events to the WebSocket clients connected to this instance
+type broadcastSink struct{}
+
+// NewBroadcastSink creates a sink that broadcasts events via the WebSocket Hub
+func NewBroadcastSink() Sink {
+	return broadcastSink{}
+}
+
+func (broadcastSink) Name() string { return "broadcast" }
+
+func (broadcastSink) Apply(event Event) error {
+	if websocket.Hub == nil {
+		return nil
+	}
+	payload, err := redact(event)
+	if err != nil {
+		return err
+	}
+	websocket.Hub.Broadcast(event.Record, payload)
+	log.Printf("Broadcasted %s update from instance %s", event.Record.EntityType, event.SenderInstanceID)
+	return nil
+}
+
+// redact returns the payload of an event without the diff fields in redactedFields
+func redact(event Event) ([]byte, error) {
+	fields := redactedFields[event.Record.EntityType]
+	if len(fields) == 0 || len(event.Record.Diff) == 0 {
+		return event.Payload, nil
+	}
+	var diff map[string]json.RawMessage
+	if err := sonic.Unmarshal(event.Record.Diff, &diff); err != nil {
+		return nil, fmt.Errorf("failed to decode diff of event %s: %w", event.Record.ID, err)
+	}
+	redacted := false
+	for _, field := range fields {
+		if _, ok := diff[field]; ok {
+			delete(diff, field)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return event.Payload, nil
+	}
+
+	record := event.Record
+	data, err := sonic.Marshal(diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode diff of event %s: %w", event.Record.ID, err)
+	}
+	record.Diff = data
+	return sonic.Marshal(record)
+}
+
+// cacheSink drops and re-warms the inventory cache of the store touched by an event, and drops
+// the inventory cache of the stores stocking an updated SKU
+type cacheSink struct {
+	inventoryService *services.InventoryService
+}
+
+// NewCacheSink creates a sink that invalidates and warms the inventory cache
+func NewCacheSink() Sink {
+	return cacheSink{inventoryService: services.NewInventoryService()}
+}
+
+func (cacheSink) Name() string { return "cache" }
+
+func (s cacheSink) Apply(event Event) error {
+	if event.Record.EntityType == models.EntitySKU && event.Record.OperationType == "update" {
+		s.inventoryService.InvalidateSKU(event.Record.EntityID)
+		return nil
+	}
+	storeID := event.Record.StoreID
+	if storeID == uuid.Nil {
+		return nil
+	}
+	s.inventoryService.InvalidateCache(storeID)
+
+	// Warm the default first page for the store
+	params := dto.InventoryQueryParams{Page: 1, PageSize: 20, SortBy: "created_at", Order: "desc"}
+	if _, err := s.inventoryService.GetInventory(services.SystemScope, params, &storeID, nil); err != nil {
+		return fmt.Errorf("failed to warm cache for store %s: %w", storeID, err)
+	}
+	return nil
+}
+
+// projectionSink keeps the stock summary projection up to date
+type projectionSink struct {
+	summaryService *services.SummaryService
+}
+
+// NewProjectionSink creates a sink that refreshes the stock summary rows affected by an event
+func NewProjectionSink() Sink {
+	return projectionSink{summaryService: services.NewSummaryService()}
+}
+
+func (projectionSink) Name() string { return "projection" }
+
+func (s projectionSink) Apply(event Event) error {
+	if database.DB == nil {
+		return nil
+	}
+	if err := s.apply(event.Record); err != nil {
+		return err
+	}
+	// Events that change no summary rows are applied too, or the projection would look stale
+	return s.summaryService.MarkApplied(event.Record.CreatedAt)
+}
+
+func (s projectionSink) apply(r models.Outbox) error {
+	switch r.EntityType {
+	case models.EntityInventory:
+		return s.summaryService.RefreshStores(r.StoreID)
+	case models.EntitySKU:
+		// Category or price changes move stock between rows; new SKUs have no inventory yet
+		if r.OperationType == "update" {
+			return s.summaryService.RefreshSKU(r.EntityID)
+		}
+	case models.EntityStore:
+		if r.OperationType == "delete" {
+			return s.summaryService.RemoveStores(r.EntityID)
+		}
+	}
+	return nil
+}
+
+// webhookSink posts each event payload, redacted like broadcasts, to an HTTP endpoint
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink creates a sink that POSTs event payloads to url
+func NewWebhookSink(url string) Sink {
+	return webhookSink{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (webhookSink) Name() string { return "webhook" }
+
+func (s webhookSink) Apply(event Event) error {
+	payload, err := redact(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", event.Record.ID.String())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// logSink prints a one-line summary of each event
+type logSink struct{}
+
+// NewLogSink creates a sink that logs events
+func NewLogSink() Sink {
+	return logSink{}
+}
+
+func (logSink) Name() string { return "log" }
+
+func (logSink) Apply(event Event) error {
+	r := event.Record
+	log.Printf("Event %s: %s %s %s (sender: %s, version: %d, at: %s)",
+		r.ID, r.EntityType, r.OperationType, r.EntityID, event.SenderInstanceID, r.Version, r.CreatedAt.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"inventory-manager-server/dto"
+	"inventory-manager-server/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var auditService = services.NewAuditService()
+
+// ListAuditLog searches the audit log, newest first; format=csv downloads every matching
+// entry instead of one page (audit.read)
+func ListAuditLog(c *gin.Context) {
+	var params dto.AuditQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid query parameters", "errors": err.Error()})
+		return
+	}
+
+	if params.Format == "csv" {
+		exportAuditLog(c, params)
+		return
+	}
+
+	result, err := auditService.Search(params)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAuditFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid query parameters", "errors": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to query audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// exportAuditLog streams the matching audit log entries as a CSV download
+func exportAuditLog(c *gin.Context, params dto.AuditQueryParams) {
+	filename := fmt.Sprintf("audit-log-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if err := auditService.ExportCSV(c.Request.Context(), params, c.Writer); err != nil {
+		if errors.Is(err, services.ErrInvalidAuditFilter) && !c.Writer.Written() {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid query parameters", "errors": err.Error()})
+			return
+		}
+		// Headers may already be sent; the truncated download is all the client gets
+		log.Printf("Audit log export failed: %v", err)
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to export audit log"})
+		}
+	}
+}
@@ -1,18 +1,28 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"inventory-manager-server/database"
 	"inventory-manager-server/dto"
+	"inventory-manager-server/middleware"
 	"inventory-manager-server/models"
-	"inventory-manager-server/utils"
+	"inventory-manager-server/password"
+	"inventory-manager-server/services"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var authService = services.NewAuthService()
+var passwordResetService = services.NewPasswordResetService()
+var passwordService = services.NewPasswordService()
+
 // Login handles user login
 func Login(c *gin.Context) {
 	var req dto.LoginRequest
@@ -21,44 +31,180 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Reject locked out usernames before touching the database
+	attempts := services.NewLoginAttemptService()
+	if lockedFor := attempts.LockedFor(req.Username); lockedFor > 0 {
+		respondLockedOut(c, lockedFor)
+		return
+	}
+
 	var user models.User
 	err := database.DB.Where("username = ?", req.Username).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid credentials"})
+			// Unknown usernames count too and take as long as a wrong password, so neither
+			// lockout nor response time reveals which usernames exist
+			password.Verify(req.Password, password.DummyHash())
+			respondInvalidCredentials(c, attempts, req.Username)
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
 		return
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid credentials"})
+	// Verify password (argon2id, or bcrypt for passwords set before the switch)
+	ok, needsRehash, err := password.Verify(req.Password, user.PasswordHash)
+	if err != nil {
+		log.Printf("Warning: failed to verify password of user %s: %v", user.ID, err)
+	}
+	if !ok {
+		respondInvalidCredentials(c, attempts, req.Username)
+		return
+	}
+	attempts.Reset(req.Username)
+
+	// Deactivated users stay in history but cannot log in
+	if !user.Active() {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Account is deactivated"})
+		return
+	}
+
+	// Move the hash to argon2id with the current parameters while the password is at hand
+	if needsRehash {
+		passwordService.Upgrade(user, req.Password)
+	}
+
+	// Users whose password was reset by a manager must choose a new one first
+	if user.PasswordResetRequired {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Password reset required"})
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Email, user.Role, req.RememberMe)
+	// Users with two-factor authentication (or whose role requires it) continue at /api/auth/2fa/verify
+	challenge, err := mfaService.Challenge(user, req.RememberMe)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	// Generate access and refresh tokens
+	response, err := authService.IssueTokens(user, req.RememberMe)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate token"})
 		return
 	}
 
-	// Return token and user information
-	userResponse := dto.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+	// Return tokens and user information
+	c.JSON(http.StatusOK, response)
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new refresh token
+func RefreshToken(c *gin.Context) {
+	var req dto.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, dto.LoginResponse{
-		Token: token,
-		User:  userResponse,
-	})
+	response, err := authService.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ForgotPassword emails a password reset link. It always succeeds, so callers cannot tell
+// which email addresses belong to users.
+func ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	// Send in the background, so the response time does not reveal whether the address exists
+	go func() {
+		if err := passwordResetService.RequestReset(req.Email); err != nil {
+			log.Printf("Warning: password reset request failed: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the email address belongs to a user, a password reset link has been sent"})
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out everywhere
+func ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	if err := passwordResetService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired reset token"})
+			return
+		}
+		respondPasswordError(c, err, "Failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// Logout revokes the session of a refresh token (or every session of its user)
+func Logout(c *gin.Context) {
+	var req dto.LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	if err := authService.Logout(req.RefreshToken, req.All); err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			// Already logged out
+			c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// respondInvalidCredentials records a failed login and responds 401, or 429 if it triggered a lockout
+func respondInvalidCredentials(c *gin.Context, attempts *services.LoginAttemptService, username string) {
+	if lockedFor := attempts.RecordFailure(username); lockedFor > 0 {
+		respondLockedOut(c, lockedFor)
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid credentials"})
+}
+
+// respondLockedOut responds 429 with the remaining lockout in Retry-After
+func respondLockedOut(c *gin.Context, lockedFor time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedFor.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"message": "Too many failed login attempts, please try again later"})
+}
+
+// respondPasswordError responds 400 for password policy violations (naming the rule) and 500 otherwise
+func respondPasswordError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, services.ErrPasswordPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"message": fallback})
 }
 
 // GetProfile gets current user information
@@ -80,13 +226,22 @@ func GetProfile(c *gin.Context) {
 		return
 	}
 
+	permissions, err := roleService.Permissions(user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
 	userResponse := dto.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Role:        user.Role,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Permissions: permissions.Names(),
+		// Marks impersonation sessions, so clients can show whose view this is
+		Impersonation: middleware.Impersonation(c),
 	}
 
 	c.JSON(http.StatusOK, userResponse)
@@ -118,22 +273,17 @@ func ChangePassword(c *gin.Context) {
 	}
 
 	// Verify old password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.OldPassword)); err != nil {
+	if ok, _, _ := password.Verify(req.OldPassword, user.PasswordHash); !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid old password"})
 		return
 	}
 
-	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	// Check the new password against the policy and update it
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return passwordService.Set(tx, &user, req.NewPassword)
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to hash password"})
-		return
-	}
-
-	// Update password
-	user.PasswordHash = string(hashedPassword)
-	if err := database.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update password"})
+		respondPasswordError(c, err, "Failed to update password")
 		return
 	}
 
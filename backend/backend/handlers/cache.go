@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCacheStats returns the hit ratio of each cache tier on this instance (system.monitor)
+func GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": config.CONFIG.InstanceID,
+		"stats":       cache.GetStats(),
+	})
+}
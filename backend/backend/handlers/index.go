@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"inventory-manager-server/config"
@@ -19,3 +20,20 @@ func Index(c *gin.Context) {
 		"version":     "1.0.5",
 	})
 }
+
+// draining is set once the server starts shutting down
+var draining atomic.Bool
+
+// SetDraining marks the instance as shutting down so readiness checks fail
+func SetDraining() {
+	draining.Store(true)
+}
+
+// Ready handles the readiness check; it fails while draining so no new traffic is routed here
+func Ready(c *gin.Context) {
+	if draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
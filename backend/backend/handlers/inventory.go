@@ -1,17 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
-	"inventory-manager-server/database"
 	"inventory-manager-server/dto"
-	"inventory-manager-server/models"
+	"inventory-manager-server/middleware"
 	"inventory-manager-server/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 var inventoryService = services.NewInventoryService()
@@ -47,29 +46,13 @@ func GetInventory(c *gin.Context) {
 		skuID = &parsedSKUID
 	}
 
-	// Get user role and ID from context
-	userRole, _ := c.Get("userRole")
-	userIDRaw, _ := c.Get("userID")
-	userIDUUID := userIDRaw.(uuid.UUID)
-
-	// For staff, verify access if store_id is specified
-	if userRole == "staff" && storeID != nil {
-		// Verify staff belongs to the specified store
-		var storeUser models.StoreUser
-		if err := database.DB.Where("user_id = ? AND store_id = ?", userIDUUID, *storeID).
-			First(&storeUser).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusForbidden, gin.H{"message": "You can only access inventory for your assigned stores"})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+	// Query inventory - the service limits the query to the user's store scope
+	result, err := inventoryService.GetInventory(middleware.StoreScope(c), params, storeID, skuID)
+	if err != nil {
+		if errors.Is(err, services.ErrStoreForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"message": "You can only access inventory for your assigned stores"})
 			return
 		}
-	}
-
-	// Query inventory - service will handle store filtering based on user role
-	result, err := inventoryService.GetInventory(params, storeID, skuID, &userIDUUID, userRole.(string))
-	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to query inventory", "error": err.Error()})
 		return
 	}
@@ -78,6 +61,7 @@ func GetInventory(c *gin.Context) {
 }
 
 // GetInventoryByID gets a single inventory by ID
+// Inventory of stores outside the user's store scope is reported as not found
 func GetInventoryByID(c *gin.Context) {
 	// Get inventory ID from path parameter
 	inventoryIDStr := c.Param("id")
@@ -87,38 +71,7 @@ func GetInventoryByID(c *gin.Context) {
 		return
 	}
 
-	// Check permissions: staff can only view their store's inventory. Do this before querying inventory.
-	userRole, _ := c.Get("userRole")
-	if userRole == "staff" {
-		userID, _ := c.Get("userID")
-		userIDUUID := userID.(uuid.UUID)
-
-		// Need to know the storeID for this inventory: do a pre-query for permission before proceeding.
-		var inventory models.Inventory
-		// Select only store_id to minimize query
-		if err := database.DB.Select("store_id").Where("id = ?", inventoryID).First(&inventory).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusNotFound, gin.H{"message": "Inventory not found"})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "error": err.Error()})
-			return
-		}
-
-		var storeUser models.StoreUser
-		if err := database.DB.Where("user_id = ? AND store_id = ?", userIDUUID, inventory.StoreID).
-			First(&storeUser).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusForbidden, gin.H{"message": "You can only access inventory for your assigned stores"})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-			return
-		}
-	}
-
-	// Now query the inventory fully
-	inventoryRes, err := inventoryService.GetInventoryByID(inventoryID)
+	inventoryRes, err := inventoryService.GetInventoryByID(middleware.StoreScope(c), inventoryID)
 	if err != nil {
 		if err.Error() == "inventory not found" {
 			c.JSON(http.StatusNotFound, gin.H{"message": "Inventory not found"})
@@ -131,7 +84,7 @@ func GetInventoryByID(c *gin.Context) {
 	c.JSON(http.StatusOK, inventoryRes)
 }
 
-// CreateInventory creates a new inventory record (manager only)
+// CreateInventory creates a new inventory record (inventory.manage)
 func CreateInventory(c *gin.Context) {
 	// Parse request body
 	var req dto.CreateInventoryRequest
@@ -146,8 +99,12 @@ func CreateInventory(c *gin.Context) {
 	userIDUUID := userID.(uuid.UUID)
 
 	// Create inventory
-	inventory, err := inventoryService.CreateInventory(req, userIDUUID, userName.(string))
+	inventory, err := inventoryService.CreateInventory(middleware.StoreScope(c), req, userIDUUID, userName.(string))
 	if err != nil {
+		if errors.Is(err, services.ErrStoreForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"message": "You can only create inventory for your assigned stores"})
+			return
+		}
 		if err.Error() == "inventory already exists for this SKU and store" {
 			c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
 			return
@@ -163,7 +120,7 @@ func CreateInventory(c *gin.Context) {
 	c.JSON(http.StatusCreated, inventory)
 }
 
-// UpdateInventory updates inventory quantity (manager only)
+// UpdateInventory updates inventory quantity (inventory.update)
 func UpdateInventory(c *gin.Context) {
 	// Get inventory ID from path parameter
 	inventoryIDStr := c.Param("id")
@@ -186,7 +143,7 @@ func UpdateInventory(c *gin.Context) {
 	userIDUUID := userID.(uuid.UUID)
 
 	// Update inventory
-	inventory, err := inventoryService.UpdateInventory(inventoryID, req.Quantity, userIDUUID, userName.(string))
+	inventory, err := inventoryService.UpdateInventory(middleware.StoreScope(c), inventoryID, req.Quantity, userIDUUID, userName.(string))
 	if err != nil {
 		if err.Error() == "inventory not found" {
 			c.JSON(http.StatusNotFound, gin.H{"message": "Inventory not found"})
@@ -199,7 +156,7 @@ func UpdateInventory(c *gin.Context) {
 	c.JSON(http.StatusOK, inventory)
 }
 
-// DeleteInventory deletes an inventory record (manager only)
+// DeleteInventory deletes an inventory record (inventory.manage)
 func DeleteInventory(c *gin.Context) {
 	// Get inventory ID from path parameter
 	inventoryIDStr := c.Param("id")
@@ -215,7 +172,7 @@ func DeleteInventory(c *gin.Context) {
 	userIDUUID := userID.(uuid.UUID)
 
 	// Delete inventory
-	if err := inventoryService.DeleteInventory(inventoryID, userIDUUID, userName.(string)); err != nil {
+	if err := inventoryService.DeleteInventory(middleware.StoreScope(c), inventoryID, userIDUUID, userName.(string)); err != nil {
 		if err.Error() == "inventory not found" {
 			c.JSON(http.StatusNotFound, gin.H{"message": "Inventory not found"})
 			return
@@ -228,7 +185,7 @@ func DeleteInventory(c *gin.Context) {
 }
 
 // AdjustInventory adjusts inventory quantity by delta
-// Staff can only adjust their store's inventory, manager can adjust any
+// Inventory of stores outside the user's store scope is reported as not found
 func AdjustInventory(c *gin.Context) {
 	// Get inventory ID from path parameter
 	inventoryIDStr := c.Param("id")
@@ -248,37 +205,10 @@ func AdjustInventory(c *gin.Context) {
 	// Get user info from context
 	userID, _ := c.Get("userID")
 	userName, _ := c.Get("userName")
-	userRole, _ := c.Get("userRole")
 	userIDUUID := userID.(uuid.UUID)
 
-	// Check permissions: staff can only adjust their store's inventory
-	if userRole == "staff" {
-		// Get the inventory to check store
-		var inventory models.Inventory
-		if err := database.DB.First(&inventory, "id = ?", inventoryID).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusNotFound, gin.H{"message": "Inventory not found"})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-			return
-		}
-
-		// Verify staff belongs to this store
-		var storeUser models.StoreUser
-		if err := database.DB.Where("user_id = ? AND store_id = ?", userIDUUID, inventory.StoreID).
-			First(&storeUser).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusForbidden, gin.H{"message": "You can only adjust inventory for your assigned stores"})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-			return
-		}
-	}
-
 	// Adjust inventory
-	inventory, err := inventoryService.AdjustInventory(inventoryID, req.DeltaQuantity, userIDUUID, userName.(string))
+	inventory, err := inventoryService.AdjustInventory(middleware.StoreScope(c), inventoryID, req.DeltaQuantity, userIDUUID, userName.(string))
 	if err != nil {
 		if err.Error() == "inventory not found" {
 			c.JSON(http.StatusNotFound, gin.H{"message": "Inventory not found"})
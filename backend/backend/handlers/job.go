@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"inventory-manager-server/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListJobs lists scheduled background jobs with their last and next run (system.monitor)
+func ListJobs(c *gin.Context) {
+	jobs, err := scheduler.ListJobs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to query jobs", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": jobs})
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"inventory-manager-server/config"
+	"inventory-manager-server/signing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS publishes the public keys that verify our access tokens, so other services can
+// verify them without a shared secret
+func GetJWKS(c *gin.Context) {
+	// New keys are published one reload interval before they sign (see SigningKeyService.activeKey)
+	if maxAge := int(config.CONFIG.JWTKeyReload.Seconds()); maxAge > 0 {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	}
+	c.JSON(http.StatusOK, signing.PublicKeys())
+}
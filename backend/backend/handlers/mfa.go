@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/middleware"
+	"inventory-manager-server/models"
+	"inventory-manager-server/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var mfaService = services.NewMFAService()
+
+// VerifyMFA completes a two-step login with a TOTP code or a recovery code. Users enrolling
+// during login confirm their new authenticator and receive their recovery codes.
+func VerifyMFA(c *gin.Context) {
+	var req dto.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	user, rememberMe, err := mfaService.ResolveChallenge(req.ChallengeToken)
+	if err != nil {
+		respondMFAError(c, err, "Failed to verify code")
+		return
+	}
+
+	attempts := services.NewLoginAttemptService()
+	if lockedFor := attempts.LockedFor(user.Username); lockedFor > 0 {
+		respondLockedOut(c, lockedFor)
+		return
+	}
+
+	recoveryCodes, err := mfaService.CompleteChallenge(user, req.Code)
+	if err != nil {
+		respondMFACodeError(c, attempts, user.Username, err, "Failed to verify code")
+		return
+	}
+	attempts.Reset(user.Username)
+
+	response, err := authService.IssueTokens(user, rememberMe)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate token"})
+		return
+	}
+	response.RecoveryCodes = recoveryCodes
+
+	c.JSON(http.StatusOK, response)
+}
+
+// EnrollMFAChallenge starts authenticator enrolment during a login that requires 2FA
+// before the user has set it up
+func EnrollMFAChallenge(c *gin.Context) {
+	var req dto.MFAChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	user, _, err := mfaService.ResolveChallenge(req.ChallengeToken)
+	if err != nil {
+		respondMFAError(c, err, "Failed to start enrolment")
+		return
+	}
+
+	enrollment, err := mfaService.Enroll(user)
+	if err != nil {
+		respondMFAError(c, err, "Failed to start enrolment")
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// GetMFAStatus gets the two-factor authentication state of the current user
+func GetMFAStatus(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := mfaService.Status(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollMFA creates a pending authenticator for the current user; it is enabled by ConfirmMFA
+func EnrollMFA(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := mfaService.Enroll(user)
+	if err != nil {
+		respondMFAError(c, err, "Failed to start enrolment")
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmMFA enables the current user's pending authenticator and returns recovery codes
+func ConfirmMFA(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	attempts := services.NewLoginAttemptService()
+	if lockedFor := attempts.LockedFor(user.Username); lockedFor > 0 {
+		respondLockedOut(c, lockedFor)
+		return
+	}
+
+	codes, err := mfaService.Confirm(user, req.Code)
+	if err != nil {
+		respondMFACodeError(c, attempts, user.Username, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	attempts := services.NewLoginAttemptService()
+	if lockedFor := attempts.LockedFor(user.Username); lockedFor > 0 {
+		respondLockedOut(c, lockedFor)
+		return
+	}
+
+	codes, err := mfaService.RegenerateRecoveryCodes(user.ID, req.Code)
+	if err != nil {
+		respondMFACodeError(c, attempts, user.Username, err, "Failed to generate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFA turns off two-factor authentication for the current user, unless their role requires it
+func DisableMFA(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	attempts := services.NewLoginAttemptService()
+	if lockedFor := attempts.LockedFor(user.Username); lockedFor > 0 {
+		respondLockedOut(c, lockedFor)
+		return
+	}
+
+	if err := mfaService.Disable(user, req.Code); err != nil {
+		respondMFACodeError(c, attempts, user.Username, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// ResetUserMFA removes a user's authenticator and recovery codes, e.g. after a lost device (users.manage)
+func ResetUserMFA(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
+		return
+	}
+
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	if err := mfaService.Reset(userID, actor); err != nil {
+		respondMFAError(c, err, "Failed to reset two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// loadCurrentUser loads the authenticated user, responding 404 or 500 if that fails
+func loadCurrentUser(c *gin.Context) (models.User, bool) {
+	userID, _ := c.Get("userID")
+	var user models.User
+	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+			return user, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return user, false
+	}
+	return user, true
+}
+
+// respondMFACodeError responds to a failed code check; wrong codes count as failed logins of
+// the username, so codes cannot be guessed faster than passwords
+func respondMFACodeError(c *gin.Context, attempts *services.LoginAttemptService, username string, err error, fallback string) {
+	if errors.Is(err, services.ErrInvalidMFACode) {
+		if lockedFor := attempts.RecordFailure(username); lockedFor > 0 {
+			respondLockedOut(c, lockedFor)
+			return
+		}
+	}
+	respondMFAError(c, err, fallback)
+}
+
+// respondMFAError maps MFA service errors to responses
+func respondMFAError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidChallenge):
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired login challenge"})
+	case errors.Is(err, services.ErrInvalidMFACode):
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid code"})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"message": "Two-factor authentication is already enabled"})
+	case errors.Is(err, services.ErrMFANotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Two-factor authentication is not enabled"})
+	case errors.Is(err, services.ErrMFARequired):
+		c.JSON(http.StatusForbidden, gin.H{"message": "Two-factor authentication is required for your role"})
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": fallback})
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"inventory-manager-server/config"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/oidc"
+	"inventory-manager-server/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var oidcService = services.NewOIDCService()
+
+// GetOIDCConfig tells the login page whether single sign-on is available
+func GetOIDCConfig(c *gin.Context) {
+	if !oidcService.Enabled() {
+		c.JSON(http.StatusOK, dto.OIDCConfigResponse{Enabled: false})
+		return
+	}
+	c.JSON(http.StatusOK, dto.OIDCConfigResponse{Enabled: true, Name: config.CONFIG.OIDCDisplayName})
+}
+
+// StartOIDCLogin returns the identity provider URL that starts a single sign-on login
+func StartOIDCLogin(c *gin.Context) {
+	var req dto.OIDCAuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	authURL, binding, err := oidcService.Begin(c.Request.Context(), req.RememberMe)
+	if err != nil {
+		respondOIDCError(c, err, "Failed to start single sign-on")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.OIDCAuthorizeResponse{AuthorizationURL: authURL, Binding: binding})
+}
+
+// CompleteOIDCLogin redeems the authorization code the identity provider redirected back with
+// and signs the user in, creating their account on first login
+func CompleteOIDCLogin(c *gin.Context) {
+	var req dto.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	user, rememberMe, err := oidcService.Complete(c.Request.Context(), req.Code, req.State, req.Binding)
+	if err != nil {
+		respondOIDCError(c, err, "Single sign-on failed")
+		return
+	}
+
+	// The identity provider does not replace this application's second factor: users with an
+	// authenticator (or whose role requires one) continue at /api/auth/2fa/verify
+	challenge, err := mfaService.Challenge(user, rememberMe)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	response, err := authService.IssueTokens(user, rememberMe)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// StartOIDCLink returns the identity provider URL that links an identity provider account to
+// the current user, who can then sign in with either
+func StartOIDCLink(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	authURL, binding, err := oidcService.BeginLink(c.Request.Context(), user.ID)
+	if err != nil {
+		respondOIDCError(c, err, "Failed to start single sign-on")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.OIDCAuthorizeResponse{AuthorizationURL: authURL, Binding: binding})
+}
+
+// CompleteOIDCLink redeems the authorization code of a link started by the current user
+func CompleteOIDCLink(c *gin.Context) {
+	var req dto.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := oidcService.CompleteLink(c.Request.Context(), req.Code, req.State, req.Binding, user); err != nil {
+		respondOIDCError(c, err, "Failed to link single sign-on")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Single sign-on linked"})
+}
+
+// respondOIDCError maps single sign-on errors to responses
+func respondOIDCError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrOIDCDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"message": "Single sign-on is not configured"})
+	case errors.Is(err, services.ErrInvalidOIDCState):
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired login, please try again"})
+	case errors.Is(err, oidc.ErrInvalidIDToken):
+		log.Printf("OIDC login rejected: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "The identity provider's response could not be verified"})
+	case errors.Is(err, services.ErrOIDCProvider):
+		log.Printf("OIDC login failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"message": "The identity provider could not complete the login"})
+	case errors.Is(err, services.ErrOIDCNotAuthorized):
+		c.JSON(http.StatusForbidden, gin.H{"message": "Your account is not authorized to use this application"})
+	case errors.Is(err, services.ErrOIDCMissingEmail):
+		c.JSON(http.StatusForbidden, gin.H{"message": "The identity provider did not share your email address"})
+	case errors.Is(err, services.ErrUserDeactivated):
+		c.JSON(http.StatusForbidden, gin.H{"message": "Account is deactivated"})
+	case errors.Is(err, services.ErrOIDCAccountConflict):
+		c.JSON(http.StatusConflict, gin.H{"message": "An account with your email address already exists; ask an administrator to link it"})
+	case errors.Is(err, services.ErrOIDCLinkRequired):
+		c.JSON(http.StatusConflict, gin.H{"message": "An account with your email address already exists; sign in with your password and link single sign-on from your profile"})
+	case errors.Is(err, services.ErrOIDCIdentityLinked):
+		c.JSON(http.StatusConflict, gin.H{"message": "This identity provider account is linked to another user"})
+	default:
+		log.Printf("OIDC login error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": fallback})
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"inventory-manager-server/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var summaryService = services.NewSummaryService()
+
+// GetStockSummary returns per-store and per-category stock totals from the summary projection (reports.read)
+func GetStockSummary(c *gin.Context) {
+	// Optional store filter
+	var storeID *uuid.UUID
+	if storeIDStr := c.Query("store_id"); storeIDStr != "" {
+		parsedStoreID, err := uuid.Parse(storeIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid store ID"})
+			return
+		}
+		storeID = &parsedStoreID
+	}
+
+	summary, err := summaryService.GetSummary(storeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to query stock summary", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// RebuildStockSummary recomputes the summary projection from inventory (reports.rebuild)
+func RebuildStockSummary(c *gin.Context) {
+	if err := summaryService.RebuildAll(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to rebuild stock summary", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stock summary rebuilt successfully"})
+}
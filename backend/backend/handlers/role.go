@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"inventory-manager-server/dto"
+	"inventory-manager-server/middleware"
+	"inventory-manager-server/models"
+	"inventory-manager-server/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var roleService = services.NewRoleService()
+
+// ListRoles lists all roles with their permissions
+func ListRoles(c *gin.Context) {
+	roles, err := roleService.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to query roles"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": roles})
+}
+
+// ListPermissions lists every permission that can be granted to a role
+func ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"items": models.Permissions})
+}
+
+// CreateRole creates a custom role
+func CreateRole(c *gin.Context) {
+	var req dto.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	// Prevent privilege escalation through roles
+	if !middleware.HasPermission(c, req.Permissions...) {
+		c.JSON(http.StatusForbidden, gin.H{"message": "You can only grant permissions you have"})
+		return
+	}
+
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	role, err := roleService.CreateRole(req, actor)
+	if err != nil {
+		respondRoleError(c, err, "Failed to create role")
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole updates the description and permissions of a custom role
+func UpdateRole(c *gin.Context) {
+	var req dto.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	// Prevent privilege escalation through roles
+	if !middleware.HasPermission(c, req.Permissions...) {
+		c.JSON(http.StatusForbidden, gin.H{"message": "You can only grant permissions you have"})
+		return
+	}
+
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	role, err := roleService.UpdateRole(c.Param("name"), req, actor)
+	if err != nil {
+		respondRoleError(c, err, "Failed to update role")
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole deletes a custom role that is not assigned to any user
+func DeleteRole(c *gin.Context) {
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	if err := roleService.DeleteRole(c.Param("name"), actor); err != nil {
+		respondRoleError(c, err, "Failed to delete role")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// respondRoleError maps role service errors to responses
+func respondRoleError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "Role not found"})
+	case errors.Is(err, services.ErrRoleExists):
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Role already exists"})
+	case errors.Is(err, services.ErrUnknownPermission):
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	case errors.Is(err, services.ErrBuiltInRole):
+		c.JSON(http.StatusForbidden, gin.H{"message": "Built-in roles cannot be modified"})
+	case errors.Is(err, services.ErrRoleInUse):
+		c.JSON(http.StatusConflict, gin.H{"message": "Role is assigned to users"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": fallback})
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"inventory-manager-server/dto"
+	"inventory-manager-server/middleware"
+	"inventory-manager-server/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var serviceAccountService = services.NewServiceAccountService()
+
+// ListServiceAccounts lists all service accounts with their API keys
+func ListServiceAccounts(c *gin.Context) {
+	accounts, err := serviceAccountService.ListServiceAccounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to query service accounts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": accounts})
+}
+
+// CreateServiceAccount creates a service account
+func CreateServiceAccount(c *gin.Context) {
+	var req dto.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	account, err := serviceAccountService.CreateServiceAccount(req, actor)
+	if err != nil {
+		respondServiceAccountError(c, err, "Failed to create service account")
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+// UpdateServiceAccount updates the description of a service account
+func UpdateServiceAccount(c *gin.Context) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid service account ID"})
+		return
+	}
+
+	var req dto.UpdateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	account, err := serviceAccountService.UpdateServiceAccount(accountID, req, actor)
+	if err != nil {
+		respondServiceAccountError(c, err, "Failed to update service account")
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// DeleteServiceAccount deletes a service account and all of its API keys
+func DeleteServiceAccount(c *gin.Context) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid service account ID"})
+		return
+	}
+
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	if err := serviceAccountService.DeleteServiceAccount(accountID, actor); err != nil {
+		respondServiceAccountError(c, err, "Failed to delete service account")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Service account deleted successfully"})
+}
+
+// CreateAPIKey creates an API key for a service account
+// Callers can only grant permissions they hold themselves, and only stores in their own store scope.
+func CreateAPIKey(c *gin.Context) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid service account ID"})
+		return
+	}
+
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	// Prevent privilege escalation through keys
+	if !middleware.HasPermission(c, req.Permissions...) {
+		c.JSON(http.StatusForbidden, gin.H{"message": "You can only grant permissions you have"})
+		return
+	}
+	scope := middleware.StoreScope(c)
+	for _, storeID := range req.StoreIDs {
+		if err := scope.CheckStore(storeID); err != nil {
+			if errors.Is(err, services.ErrStoreForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"message": "You can only grant access to your assigned stores"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	key, err := serviceAccountService.CreateAPIKey(accountID, req, actor)
+	if err != nil {
+		respondServiceAccountError(c, err, "Failed to create API key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// RevokeAPIKey revokes an API key of a service account
+func RevokeAPIKey(c *gin.Context) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid service account ID"})
+		return
+	}
+	keyID, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid API key ID"})
+		return
+	}
+
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	if err := serviceAccountService.RevokeAPIKey(accountID, keyID, actor); err != nil {
+		respondServiceAccountError(c, err, "Failed to revoke API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
+
+// respondServiceAccountError maps service account service errors to responses
+func respondServiceAccountError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrServiceAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "Service account not found"})
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "API key not found"})
+	case errors.Is(err, services.ErrServiceAccountExists):
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Service account already exists"})
+	case errors.Is(err, services.ErrUnknownPermission):
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	case errors.Is(err, services.ErrUnknownStore):
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Unknown store"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": fallback})
+	}
+}
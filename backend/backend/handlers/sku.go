@@ -5,10 +5,14 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"inventory-manager-server/cache"
 	"inventory-manager-server/database"
 	"inventory-manager-server/dto"
+	"inventory-manager-server/middleware"
 	"inventory-manager-server/models"
+	"inventory-manager-server/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -16,13 +20,28 @@ import (
 	"gorm.io/gorm"
 )
 
+// Catalog reads are served from the two-tier cache and invalidated on every mutation
+const (
+	skuCategoriesCacheKey = "sku:categories"
+	catalogCacheTTL       = 10 * time.Minute
+)
+
+func skuCacheKey(skuID uuid.UUID) string {
+	return "sku:" + skuID.String()
+}
+
 // ListSKUCategories lists all SKU categories
 func ListSKUCategories(c *gin.Context) {
 	var categories []string
+	if cache.GetJSON(skuCategoriesCacheKey, &categories) {
+		c.JSON(http.StatusOK, gin.H{"categories": categories})
+		return
+	}
 	if err := database.DB.Model(&models.SKU{}).Distinct("category").Pluck("category", &categories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
 		return
 	}
+	cache.SetJSON(skuCategoriesCacheKey, categories, catalogCacheTTL)
 	c.JSON(http.StatusOK, gin.H{"categories": categories})
 }
 
@@ -128,6 +147,13 @@ func GetSKU(c *gin.Context) {
 		return
 	}
 
+	// Serve from cache when possible
+	var skuResponse dto.SKUResponse
+	if cache.GetJSON(skuCacheKey(skuID), &skuResponse) {
+		c.JSON(http.StatusOK, skuResponse)
+		return
+	}
+
 	// Get SKU
 	var sku models.SKU
 	if err := database.DB.First(&sku, "id = ?", skuID).Error; err != nil {
@@ -140,7 +166,7 @@ func GetSKU(c *gin.Context) {
 	}
 
 	// Convert to response format
-	skuResponse := dto.SKUResponse{
+	skuResponse = dto.SKUResponse{
 		ID:          sku.ID,
 		Name:        sku.Name,
 		Category:    sku.Category,
@@ -150,11 +176,12 @@ func GetSKU(c *gin.Context) {
 		CreatedAt:   sku.CreatedAt,
 		UpdatedAt:   sku.UpdatedAt,
 	}
+	cache.SetJSON(skuCacheKey(skuID), skuResponse, catalogCacheTTL)
 
 	c.JSON(http.StatusOK, skuResponse)
 }
 
-// CreateSKU creates a SKU (manager only)
+// CreateSKU creates a SKU (sku.manage)
 func CreateSKU(c *gin.Context) {
 	// Parse request body
 	var req dto.CreateSKURequest
@@ -163,7 +190,10 @@ func CreateSKU(c *gin.Context) {
 		return
 	}
 
-	// Create SKU
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	// Create SKU and outbox record in transaction
 	sku := models.SKU{
 		Name:        req.Name,
 		Category:    req.Category,
@@ -171,7 +201,22 @@ func CreateSKU(c *gin.Context) {
 		Price:       req.Price,
 	}
 
-	if err := database.DB.Create(&sku).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sku).Error; err != nil {
+			return err
+		}
+		return services.RecordEntityChange(tx, services.EntityChange{
+			EntityType:    models.EntitySKU,
+			EntityID:      sku.ID,
+			OperationType: "create",
+			Version:       sku.Version,
+			After:         skuSnapshot(sku),
+			Actor:         actor,
+			SKUID:         sku.ID,
+			SKUName:       sku.Name,
+		})
+	})
+	if err != nil {
 		// Check for unique constraint violation using PostgreSQL error code
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -184,6 +229,7 @@ func CreateSKU(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create SKU"})
 		return
 	}
+	cache.Invalidate(skuCategoriesCacheKey)
 
 	// Return SKU information
 	skuResponse := dto.SKUResponse{
@@ -200,7 +246,7 @@ func CreateSKU(c *gin.Context) {
 	c.JSON(http.StatusCreated, skuResponse)
 }
 
-// UpdateSKU updates SKU information (manager only)
+// UpdateSKU updates SKU information (sku.manage)
 func UpdateSKU(c *gin.Context) {
 	// Get SKU ID from path parameter
 	skuIDStr := c.Param("id")
@@ -229,6 +275,7 @@ func UpdateSKU(c *gin.Context) {
 	}
 
 	// Update fields if provided
+	before := skuSnapshot(sku)
 	if req.Name != "" {
 		sku.Name = req.Name
 	}
@@ -245,7 +292,26 @@ func UpdateSKU(c *gin.Context) {
 	// Increment version on update
 	sku.Version++
 
-	if err := database.DB.Save(&sku).Error; err != nil {
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&sku).Error; err != nil {
+			return err
+		}
+		return services.RecordEntityChange(tx, services.EntityChange{
+			EntityType:    models.EntitySKU,
+			EntityID:      sku.ID,
+			OperationType: "update",
+			Version:       sku.Version,
+			Before:        before,
+			After:         skuSnapshot(sku),
+			Actor:         actor,
+			SKUID:         sku.ID,
+			SKUName:       sku.Name,
+		})
+	})
+	if err != nil {
 		// Check for unique constraint violation using PostgreSQL error code
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -258,6 +324,9 @@ func UpdateSKU(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update SKU"})
 		return
 	}
+	cache.Invalidate(skuCacheKey(sku.ID), skuCategoriesCacheKey)
+	// Cached inventory pages show the SKU's name and price
+	inventoryService.InvalidateSKU(sku.ID)
 
 	// Return updated SKU
 	skuResponse := dto.SKUResponse{
@@ -274,7 +343,7 @@ func UpdateSKU(c *gin.Context) {
 	c.JSON(http.StatusOK, skuResponse)
 }
 
-// DeleteSKU deletes a SKU (manager only)
+// DeleteSKU deletes a SKU (sku.manage)
 func DeleteSKU(c *gin.Context) {
 	// Get SKU ID from path parameter
 	skuIDStr := c.Param("id")
@@ -297,7 +366,7 @@ func DeleteSKU(c *gin.Context) {
 
 	// Check if SKU has inventory
 	var inventoryCount int64
-	if err := database.DB.Model(&models.Inventory{}).Where("sku_id = ?", skuID).Count(&inventoryCount).Error; err != nil {
+	if err := services.SystemScope.Inventory(database.DB).Where("sku_id = ?", skuID).Count(&inventoryCount).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
 		return
 	}
@@ -307,11 +376,40 @@ func DeleteSKU(c *gin.Context) {
 		return
 	}
 
-	// Delete SKU
-	if err := database.DB.Delete(&sku).Error; err != nil {
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	// Delete SKU and create outbox record in transaction
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&sku).Error; err != nil {
+			return err
+		}
+		return services.RecordEntityChange(tx, services.EntityChange{
+			EntityType:    models.EntitySKU,
+			EntityID:      sku.ID,
+			OperationType: "delete",
+			Version:       sku.Version + 1,
+			Before:        skuSnapshot(sku),
+			Actor:         actor,
+			SKUID:         sku.ID,
+			SKUName:       sku.Name,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to delete SKU"})
 		return
 	}
+	cache.Invalidate(skuCacheKey(sku.ID), skuCategoriesCacheKey)
 
 	c.JSON(http.StatusOK, gin.H{"message": "SKU deleted successfully"})
 }
+
+// skuSnapshot returns the SKU fields tracked in outbox diffs
+func skuSnapshot(sku models.SKU) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        sku.Name,
+		"category":    sku.Category,
+		"description": sku.Description,
+		"price":       sku.Price,
+	}
+}
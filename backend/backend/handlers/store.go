@@ -4,10 +4,14 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"inventory-manager-server/cache"
 	"inventory-manager-server/database"
 	"inventory-manager-server/dto"
+	"inventory-manager-server/middleware"
 	"inventory-manager-server/models"
+	"inventory-manager-server/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -15,7 +19,13 @@ import (
 	"gorm.io/gorm"
 )
 
-// CreateStore creates a store (manager only)
+var storeAssignmentService = services.NewStoreAssignmentService()
+var storeService = services.NewStoreService()
+
+// storesCacheKey caches the full store list served by ListStores
+const storesCacheKey = "stores:all"
+
+// CreateStore creates a store (stores.manage)
 func CreateStore(c *gin.Context) {
 
 	// Parse request body
@@ -25,13 +35,30 @@ func CreateStore(c *gin.Context) {
 		return
 	}
 
-	// Create store
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	// Create store and outbox record in transaction
 	store := models.Store{
 		Name:    req.Name,
 		Address: req.Address,
 	}
 
-	if err := database.DB.Create(&store).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&store).Error; err != nil {
+			return err
+		}
+		return services.RecordEntityChange(tx, services.EntityChange{
+			EntityType:    models.EntityStore,
+			EntityID:      store.ID,
+			OperationType: "create",
+			After:         storeSnapshot(store),
+			Actor:         actor,
+			StoreID:       store.ID,
+			StoreName:     store.Name,
+		})
+	})
+	if err != nil {
 		// Check for unique constraint violation using PostgreSQL error code
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -48,6 +75,7 @@ func CreateStore(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create store"})
 		return
 	}
+	cache.Invalidate(storesCacheKey)
 
 	// Return store information
 	storeResponse := dto.StoreResponse{
@@ -61,7 +89,7 @@ func CreateStore(c *gin.Context) {
 	c.JSON(http.StatusCreated, storeResponse)
 }
 
-// DeleteStore deletes a store (manager only)
+// DeleteStore deletes a store (stores.manage)
 func DeleteStore(c *gin.Context) {
 	// Get store ID from path parameter
 	storeIDStr := c.Param("id")
@@ -84,7 +112,7 @@ func DeleteStore(c *gin.Context) {
 
 	// Check if store has inventory
 	var inventoryCount int64
-	if err := database.DB.Model(&models.Inventory{}).Where("store_id = ?", storeID).Count(&inventoryCount).Error; err != nil {
+	if err := services.SystemScope.Inventory(database.DB).Where("store_id = ?", storeID).Count(&inventoryCount).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
 		return
 	}
@@ -106,17 +134,44 @@ func DeleteStore(c *gin.Context) {
 		return
 	}
 
-	// Delete store
-	if err := database.DB.Delete(&store).Error; err != nil {
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	// Delete store and create outbox record in transaction
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&store).Error; err != nil {
+			return err
+		}
+		return services.RecordEntityChange(tx, services.EntityChange{
+			EntityType:    models.EntityStore,
+			EntityID:      store.ID,
+			OperationType: "delete",
+			Before:        storeSnapshot(store),
+			Actor:         actor,
+			StoreID:       store.ID,
+			StoreName:     store.Name,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to delete store"})
 		return
 	}
+	cache.Invalidate(storesCacheKey)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Store deleted successfully"})
 }
 
 // ListStores lists all stores
 func ListStores(c *gin.Context) {
+	// Serve from cache when possible
+	var storeResponses []dto.StoreResponse
+	if cache.GetJSON(storesCacheKey, &storeResponses) {
+		c.JSON(http.StatusOK, gin.H{
+			"items": storeResponses,
+		})
+		return
+	}
+
 	// Query all stores
 	var stores []models.Store
 	if err := database.DB.Order("created_at DESC").Find(&stores).Error; err != nil {
@@ -125,7 +180,7 @@ func ListStores(c *gin.Context) {
 	}
 
 	// Convert to response format
-	storeResponses := make([]dto.StoreResponse, len(stores))
+	storeResponses = make([]dto.StoreResponse, len(stores))
 	for i, store := range stores {
 		storeResponses[i] = dto.StoreResponse{
 			ID:        store.ID,
@@ -135,12 +190,73 @@ func ListStores(c *gin.Context) {
 			UpdatedAt: store.UpdatedAt,
 		}
 	}
+	cache.SetJSON(storesCacheKey, storeResponses, catalogCacheTTL)
 
 	c.JSON(http.StatusOK, gin.H{
 		"items": storeResponses,
 	})
 }
 
+// ListMyStores lists the stores the current user is assigned to, with their stock counts
+func ListMyStores(c *gin.Context) {
+	// Service accounts are scoped by their API keys, not by store assignments
+	if _, isAPIKey := c.Get("apiKeyID"); isAPIKey {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Service accounts have no store assignments"})
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	stores, err := storeService.AssignedStores(userID.(uuid.UUID), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": stores,
+	})
+}
+
+// GetStore gets a store with its stock counts (assigned staff, stores.manage or inventory.all_stores)
+func GetStore(c *gin.Context) {
+	storeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid store ID"})
+		return
+	}
+
+	// Check store access before existence, so unassigned staff cannot probe store IDs
+	if !middleware.HasPermission(c, models.PermStoresManage) {
+		if err := middleware.StoreScope(c).CheckStore(storeID); err != nil {
+			if errors.Is(err, services.ErrStoreForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"message": "You can only access your assigned stores"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	// Service accounts have no store assignments
+	var userID uuid.UUID
+	if _, isAPIKey := c.Get("apiKeyID"); !isAPIKey {
+		value, _ := c.Get("userID")
+		userID = value.(uuid.UUID)
+	}
+
+	store, err := storeService.GetStore(storeID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrStoreNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Store not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, store)
+}
+
 // ListStoreStaff lists all staff members for a store
 func ListStoreStaff(c *gin.Context) {
 	// Get store ID from query parameter
@@ -177,16 +293,24 @@ func ListStoreStaff(c *gin.Context) {
 		return
 	}
 
-	// Convert to response format - just user info, no repeated store info
-	staffResponses := make([]dto.UserResponse, len(storeStaff))
+	// Convert to response format - user info and assignment terms, no repeated store info
+	now := time.Now()
+	staffResponses := make([]dto.StoreStaffMember, len(storeStaff))
 	for i, staff := range storeStaff {
-		staffResponses[i] = dto.UserResponse{
-			ID:        staff.User.ID,
-			Username:  staff.User.Username,
-			Email:     staff.User.Email,
-			Role:      staff.User.Role,
-			CreatedAt: staff.User.CreatedAt,
-			UpdatedAt: staff.User.UpdatedAt,
+		staffResponses[i] = dto.StoreStaffMember{
+			UserResponse: dto.UserResponse{
+				ID:        staff.User.ID,
+				Username:  staff.User.Username,
+				Email:     staff.User.Email,
+				Role:      staff.User.Role,
+				CreatedAt: staff.User.CreatedAt,
+				UpdatedAt: staff.User.UpdatedAt,
+			},
+			AssignmentID: staff.ID,
+			StoreRole:    staff.Role,
+			ValidFrom:    staff.ValidFrom,
+			ValidTo:      staff.ValidTo,
+			Active:       staff.ActiveAt(now),
 		}
 	}
 
@@ -198,7 +322,7 @@ func ListStoreStaff(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// AddStaffToStore adds a staff member to a store (manager only)
+// AddStaffToStore adds a staff member to a store (stores.manage)
 func AddStaffToStore(c *gin.Context) {
 	// Parse request body
 	var req dto.AddStaffToStoreRequest
@@ -228,6 +352,14 @@ func AddStaffToStore(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
 		return
 	}
+	if !user.Active() {
+		c.JSON(http.StatusConflict, gin.H{"message": "User is deactivated"})
+		return
+	}
+
+	if !validStoreAssignment(c, req.StoreAssignmentFields) {
+		return
+	}
 
 	// Check if association already exists
 	var existingStoreUser models.StoreUser
@@ -237,16 +369,38 @@ func AddStaffToStore(c *gin.Context) {
 		return
 	}
 
-	// Create store-user association
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	// Create store-user association and outbox record in transaction
 	storeUser := models.StoreUser{
-		StoreID: req.StoreID,
-		UserID:  req.UserID,
+		StoreID:   req.StoreID,
+		UserID:    req.UserID,
+		Role:      req.Role,
+		ValidFrom: req.ValidFrom,
+		ValidTo:   req.ValidTo,
 	}
 
-	if err := database.DB.Create(&storeUser).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&storeUser).Error; err != nil {
+			return err
+		}
+		return services.RecordEntityChange(tx, services.EntityChange{
+			EntityType:    models.EntityStoreUser,
+			EntityID:      storeUser.ID,
+			OperationType: "create",
+			Version:       storeUser.Version,
+			After:         services.StoreUserSnapshot(storeUser, user.Username),
+			Actor:         actor,
+			StoreID:       store.ID,
+			StoreName:     store.Name,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to add staff to store"})
 		return
 	}
+	storeAssignmentService.Invalidate(storeUser.UserID)
 
 	// Load user and store for response
 	if err := database.DB.Preload("User").Preload("Store").First(&storeUser, storeUser.ID).Error; err != nil {
@@ -254,34 +408,73 @@ func AddStaffToStore(c *gin.Context) {
 		return
 	}
 
-	// Return staff information
-	staffResponse := dto.StoreStaffResponse{
-		ID:      storeUser.ID,
-		StoreID: storeUser.StoreID,
-		UserID:  storeUser.UserID,
-		User: dto.UserResponse{
-			ID:        storeUser.User.ID,
-			Username:  storeUser.User.Username,
-			Email:     storeUser.User.Email,
-			Role:      storeUser.User.Role,
-			CreatedAt: storeUser.User.CreatedAt,
-			UpdatedAt: storeUser.User.UpdatedAt,
-		},
-		Store: dto.StoreResponse{
-			ID:        storeUser.Store.ID,
-			Name:      storeUser.Store.Name,
-			Address:   storeUser.Store.Address,
-			CreatedAt: storeUser.Store.CreatedAt,
-			UpdatedAt: storeUser.Store.UpdatedAt,
-		},
-		CreatedAt: storeUser.CreatedAt,
-		UpdatedAt: storeUser.UpdatedAt,
+	c.JSON(http.StatusCreated, storeStaffResponse(storeUser))
+}
+
+// UpdateStoreStaff replaces the store role and period of a staff assignment (stores.manage)
+func UpdateStoreStaff(c *gin.Context) {
+	staffID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid staff ID"})
+		return
+	}
+
+	var req dto.UpdateStoreStaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+
+	var storeUser models.StoreUser
+	if err := database.DB.Preload("User").Preload("Store").First(&storeUser, "id = ?", staffID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Store staff association not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	if !validStoreAssignment(c, req.StoreAssignmentFields) {
+		return
+	}
+
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	before := services.StoreUserSnapshot(storeUser, storeUser.User.Username)
+	storeUser.Role = req.Role
+	storeUser.ValidFrom = req.ValidFrom
+	storeUser.ValidTo = req.ValidTo
+	storeUser.Version++
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&storeUser).Select("role", "valid_from", "valid_to", "version", "updated_at").
+			Updates(&storeUser).Error; err != nil {
+			return err
+		}
+		return services.RecordEntityChange(tx, services.EntityChange{
+			EntityType:    models.EntityStoreUser,
+			EntityID:      storeUser.ID,
+			OperationType: "update",
+			Version:       storeUser.Version,
+			Before:        before,
+			After:         services.StoreUserSnapshot(storeUser, storeUser.User.Username),
+			Actor:         actor,
+			StoreID:       storeUser.StoreID,
+			StoreName:     storeUser.Store.Name,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update store staff"})
+		return
 	}
+	storeAssignmentService.Invalidate(storeUser.UserID)
 
-	c.JSON(http.StatusCreated, staffResponse)
+	c.JSON(http.StatusOK, storeStaffResponse(storeUser))
 }
 
-// DeleteStaffFromStore removes a staff member from a store (manager only)
+// DeleteStaffFromStore removes a staff member from a store (stores.manage)
 func DeleteStaffFromStore(c *gin.Context) {
 	// Get store staff ID from path parameter
 	staffIDStr := c.Param("id")
@@ -293,7 +486,7 @@ func DeleteStaffFromStore(c *gin.Context) {
 
 	// Get store-user association to delete
 	var storeUser models.StoreUser
-	if err := database.DB.First(&storeUser, "id = ?", staffID).Error; err != nil {
+	if err := database.DB.Preload("User").Preload("Store").First(&storeUser, "id = ?", staffID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"message": "Store staff association not found"})
 			return
@@ -302,11 +495,100 @@ func DeleteStaffFromStore(c *gin.Context) {
 		return
 	}
 
-	// Delete store-user association
-	if err := database.DB.Delete(&storeUser).Error; err != nil {
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	// Delete store-user association and create outbox record in transaction
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&storeUser).Error; err != nil {
+			return err
+		}
+		return services.RecordEntityChange(tx, services.EntityChange{
+			EntityType:    models.EntityStoreUser,
+			EntityID:      storeUser.ID,
+			OperationType: "delete",
+			Version:       storeUser.Version + 1,
+			Before:        services.StoreUserSnapshot(storeUser, storeUser.User.Username),
+			Actor:         actor,
+			StoreID:       storeUser.StoreID,
+			StoreName:     storeUser.Store.Name,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to remove staff from store"})
 		return
 	}
+	storeAssignmentService.Invalidate(storeUser.UserID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Staff removed from store successfully"})
 }
+
+// storeSnapshot returns the store fields tracked in outbox diffs
+func storeSnapshot(store models.Store) map[string]interface{} {
+	return map[string]interface{}{
+		"name":    store.Name,
+		"address": store.Address,
+	}
+}
+
+// validStoreAssignment checks the terms of a store assignment, responding 400, 403 or 500 if
+// they are not acceptable. A store role may only be granted by a user holding all of its
+// permissions.
+func validStoreAssignment(c *gin.Context, fields dto.StoreAssignmentFields) bool {
+	if fields.ValidTo != nil {
+		if fields.ValidFrom != nil && !fields.ValidTo.After(*fields.ValidFrom) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "valid_to must be after valid_from"})
+			return false
+		}
+		if !fields.ValidTo.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "valid_to must be in the future"})
+			return false
+		}
+	}
+	if fields.Role == nil {
+		return true
+	}
+	if !roleExists(c, *fields.Role) {
+		return false
+	}
+	permissions, err := roleService.Permissions(*fields.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return false
+	}
+	if !middleware.HasPermission(c, permissions.Names()...) {
+		c.JSON(http.StatusForbidden, gin.H{"message": "You can only grant store roles whose permissions you have"})
+		return false
+	}
+	return true
+}
+
+// storeStaffResponse converts a store assignment with its user and store loaded
+func storeStaffResponse(storeUser models.StoreUser) dto.StoreStaffResponse {
+	return dto.StoreStaffResponse{
+		ID:        storeUser.ID,
+		StoreID:   storeUser.StoreID,
+		UserID:    storeUser.UserID,
+		Role:      storeUser.Role,
+		ValidFrom: storeUser.ValidFrom,
+		ValidTo:   storeUser.ValidTo,
+		Active:    storeUser.ActiveAt(time.Now()),
+		User: dto.UserResponse{
+			ID:        storeUser.User.ID,
+			Username:  storeUser.User.Username,
+			Email:     storeUser.User.Email,
+			Role:      storeUser.User.Role,
+			CreatedAt: storeUser.User.CreatedAt,
+			UpdatedAt: storeUser.User.UpdatedAt,
+		},
+		Store: dto.StoreResponse{
+			ID:        storeUser.Store.ID,
+			Name:      storeUser.Store.Name,
+			Address:   storeUser.Store.Address,
+			CreatedAt: storeUser.Store.CreatedAt,
+			UpdatedAt: storeUser.Store.UpdatedAt,
+		},
+		CreatedAt: storeUser.CreatedAt,
+		UpdatedAt: storeUser.UpdatedAt,
+	}
+}
@@ -23,7 +23,7 @@ func TestInfra(c *gin.Context) {
 		Username:     "test" + strconv.Itoa(int(userCount)),
 		PasswordHash: "test1234",
 		Email:        "test" + strconv.Itoa(int(userCount)) + "@test.com",
-		Role:         "staff",
+		Role:         models.RoleStaff,
 	}
 	if err := db.Create(&newUser).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user", "details": err.Error()})
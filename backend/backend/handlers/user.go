@@ -1,20 +1,26 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"inventory-manager-server/database"
 	"inventory-manager-server/dto"
+	"inventory-manager-server/middleware"
 	"inventory-manager-server/models"
+	"inventory-manager-server/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// CreateUser creates a user (manager only)
+var userService = services.NewUserService()
+var impersonationService = services.NewImpersonationService()
+
+// CreateUser creates a user (users.manage)
 func CreateUser(c *gin.Context) {
 	var req dto.CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -35,76 +41,188 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to hash password"})
+	// Check if role exists and may be granted by the caller
+	if !grantableRole(c, req.Role) {
 		return
 	}
 
-	// Create user
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	// Create user, password history and outbox record in transaction
 	user := models.User{
-		Username:     req.Username,
-		PasswordHash: string(hashedPassword),
-		Email:        req.Email,
-		Role:         req.Role,
+		Username: req.Username,
+		Email:    req.Email,
+		Role:     req.Role,
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create user"})
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		hash, err := passwordService.Hash(tx, user, req.Password)
+		if err != nil {
+			return err
+		}
+		user.PasswordHash = hash
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if err := passwordService.Remember(tx, user.ID, hash); err != nil {
+			return err
+		}
+		return services.RecordEntityChange(tx, services.EntityChange{
+			EntityType:    models.EntityUser,
+			EntityID:      user.ID,
+			OperationType: "create",
+			After:         services.UserSnapshot(user),
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		respondPasswordError(c, err, "Failed to create user")
 		return
 	}
 
 	// Return user information (without password)
-	userResponse := dto.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+	c.JSON(http.StatusCreated, managedUserResponse(user))
+}
+
+// ForcePasswordReset signs a user out everywhere and emails them a reset link; they cannot
+// log in until they have chosen a new password (users.manage)
+func ForcePasswordReset(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
+		return
 	}
 
-	c.JSON(http.StatusCreated, userResponse)
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	if err := passwordResetService.ForceReset(userID, actor); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		case errors.Is(err, services.ErrUserDeactivated):
+			c.JSON(http.StatusConflict, gin.H{"message": "User is deactivated"})
+		case errors.Is(err, services.ErrResetMailFailed):
+			c.JSON(http.StatusBadGateway, gin.H{"message": "Password reset forced, but the reset email could not be sent"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to reset password"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset forced; a reset link has been sent to the user"})
 }
 
-// DeleteUser deletes a user (manager only)
+// DeleteUser deactivates a user; users are never removed, so history keeps pointing at them (users.manage)
 func DeleteUser(c *gin.Context) {
-	// Get user ID from path parameter
-	userIDStr := c.Param("id")
-	userID, err := uuid.Parse(userIDStr)
+	deactivateUser(c, "")
+}
+
+// DeactivateUser blocks a user's logins and revokes their sessions (users.manage)
+func DeactivateUser(c *gin.Context) {
+	var req dto.DeactivateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+	deactivateUser(c, strings.TrimSpace(req.Reason))
+}
+
+func deactivateUser(c *gin.Context, reason string) {
+	userID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
 		return
 	}
 
-	// Get user to delete
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+	if actor.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot deactivate your own account"})
+		return
+	}
+	if isInitialAdmin(c, userID) {
 		return
 	}
 
-	// Cannot delete the initial admin user
-	if user.Username == "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"message": "Cannot delete the initial admin user"})
+	user, err := userService.Deactivate(userID, reason, actor)
+	if err != nil {
+		respondUserStatusError(c, err, "Failed to deactivate user")
 		return
 	}
 
-	// Delete user
-	if err := database.DB.Delete(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to delete user"})
+	c.JSON(http.StatusOK, managedUserResponse(user))
+}
+
+// ReactivateUser lets a deactivated user log in again (users.manage)
+func ReactivateUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	user, err := userService.Reactivate(userID, actor)
+	if err != nil {
+		respondUserStatusError(c, err, "Failed to reactivate user")
+		return
+	}
+
+	c.JSON(http.StatusOK, managedUserResponse(user))
 }
 
-// ListUsers lists all users (manager only)
+// ImpersonateUser issues a short-lived token with which the manager views the application as
+// another user; read-only unless writes are requested (users.impersonate)
+func ImpersonateUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
+		return
+	}
+
+	var req dto.ImpersonateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "errors": err.Error()})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+
+	// Service accounts have no session to return to
+	if _, isAPIKey := c.Get("apiKeyID"); isAPIKey {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Service accounts cannot impersonate users"})
+		return
+	}
+
+	// Get the acting user and their permissions from context
+	actor := middleware.Actor(c)
+	value, _ := c.Get("permissions")
+	permissions, _ := value.(services.PermissionSet)
+
+	response, err := impersonationService.Start(actor, permissions, userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		case errors.Is(err, services.ErrImpersonateSelf):
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot impersonate yourself"})
+		case errors.Is(err, services.ErrUserDeactivated):
+			c.JSON(http.StatusConflict, gin.H{"message": "User is deactivated"})
+		case errors.Is(err, services.ErrImpersonationEscalation):
+			c.JSON(http.StatusForbidden, gin.H{"message": "Cannot impersonate a user with permissions you do not have"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to impersonate user"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListUsers lists all users (users.manage)
 func ListUsers(c *gin.Context) {
 	// Parse pagination parameters
 	page := 1
@@ -123,18 +241,31 @@ func ListUsers(c *gin.Context) {
 	// Calculate offset
 	offset := (page - 1) * limit
 
+	// Filter by status (deactivated users stay listed by default)
+	query := database.DB.Model(&models.User{})
+	switch c.Query("status") {
+	case "":
+	case models.UserStatusActive:
+		query = query.Where("deactivated_at IS NULL")
+	case models.UserStatusDeactivated:
+		query = query.Where("deactivated_at IS NOT NULL")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "status must be active or deactivated"})
+		return
+	}
+
 	// Query users with pagination
 	var users []models.User
 	var total int64
 
 	// Get total count
-	if err := database.DB.Model(&models.User{}).Count(&total).Error; err != nil {
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
 		return
 	}
 
 	// Get users
-	if err := database.DB.Offset(offset).Limit(limit).Order("created_at DESC").Find(&users).Error; err != nil {
+	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
 		return
 	}
@@ -142,14 +273,7 @@ func ListUsers(c *gin.Context) {
 	// Convert to response format
 	userResponses := make([]dto.UserResponse, len(users))
 	for i, user := range users {
-		userResponses[i] = dto.UserResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			Role:      user.Role,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		}
+		userResponses[i] = managedUserResponse(user)
 	}
 
 	// Calculate total pages
@@ -164,7 +288,7 @@ func ListUsers(c *gin.Context) {
 	})
 }
 
-// UpdateUser updates user information (manager only)
+// UpdateUser updates user information (users.manage)
 func UpdateUser(c *gin.Context) {
 
 	var req dto.UpdateUserRequest
@@ -188,8 +312,24 @@ func UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"message": "Cannot modify the initial admin user"})
 		return
 	}
+	if user.AnonymizedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"message": "User has been anonymized"})
+		return
+	}
+	// Users with more permissions than the caller could otherwise be taken over, e.g. through a
+	// new email address and a password reset
+	permissions, err := roleService.Permissions(user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	if !middleware.HasPermission(c, permissions.Names()...) {
+		c.JSON(http.StatusForbidden, gin.H{"message": "You cannot modify users whose role has permissions you lack"})
+		return
+	}
 
 	// Update fields if provided
+	before := services.UserSnapshot(user)
 	if req.Username != "" {
 		// Check if new username is already taken
 		var existingUser models.User
@@ -210,23 +350,127 @@ func UpdateUser(c *gin.Context) {
 		user.Email = req.Email
 	}
 
+	roleChanged := req.Role != "" && req.Role != user.Role
+	if roleChanged && !grantableRole(c, req.Role) {
+		return
+	}
 	if req.Role != "" {
 		user.Role = req.Role
 	}
 
-	if err := database.DB.Save(&user).Error; err != nil {
+	// Get the acting user from context
+	actor := middleware.Actor(c)
+
+	// Save user and create outbox record in transaction
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		// Access tokens carry the role, so a role change invalidates them
+		if roleChanged {
+			if err := authService.BumpTokenVersion(tx, user.ID); err != nil {
+				return err
+			}
+		}
+		return services.RecordEntityChange(tx, services.EntityChange{
+			EntityType:    models.EntityUser,
+			EntityID:      user.ID,
+			OperationType: "update",
+			Before:        before,
+			After:         services.UserSnapshot(user),
+			Actor:         actor,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update user"})
 		return
 	}
+	if roleChanged {
+		authService.PublishRevocation(user.ID)
+	}
+
+	c.JSON(http.StatusOK, managedUserResponse(user))
+}
+
+// isInitialAdmin reports whether userID is the initial admin user, responding 403 if so (or 404/500
+// if the user cannot be loaded)
+func isInitialAdmin(c *gin.Context, userID uuid.UUID) bool {
+	var user models.User
+	if err := database.DB.Select("username").First(&user, "id = ?", userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+			return true
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return true
+	}
+	if user.Username == "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Cannot deactivate the initial admin user"})
+		return true
+	}
+	return false
+}
+
+// respondUserStatusError maps deactivation and reactivation errors to responses
+func respondUserStatusError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+	case errors.Is(err, services.ErrUserDeactivated):
+		c.JSON(http.StatusConflict, gin.H{"message": "User is already deactivated"})
+	case errors.Is(err, services.ErrUserActive):
+		c.JSON(http.StatusConflict, gin.H{"message": "User is not deactivated"})
+	case errors.Is(err, services.ErrUserAnonymized):
+		c.JSON(http.StatusConflict, gin.H{"message": "User has been anonymized and cannot be reactivated"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": fallback})
+	}
+}
 
-	userResponse := dto.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+// managedUserResponse converts a user for user management responses, including their status
+func managedUserResponse(user models.User) dto.UserResponse {
+	return dto.UserResponse{
+		ID:                 user.ID,
+		Username:           user.Username,
+		Email:              user.Email,
+		Role:               user.Role,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
+		Status:             user.Status(),
+		DeactivatedAt:      user.DeactivatedAt,
+		DeactivationReason: user.DeactivationReason,
+		Anonymized:         user.AnonymizedAt != nil,
+	}
+}
+
+// grantableRole reports whether role exists and the caller holds all of its permissions,
+// responding 400, 403 or 500 if not
+func grantableRole(c *gin.Context, role string) bool {
+	if !roleExists(c, role) {
+		return false
+	}
+	permissions, err := roleService.Permissions(role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return false
+	}
+	if !middleware.HasPermission(c, permissions.Names()...) {
+		c.JSON(http.StatusForbidden, gin.H{"message": "You can only assign roles whose permissions you have"})
+		return false
 	}
+	return true
+}
 
-	c.JSON(http.StatusOK, userResponse)
+// roleExists reports whether role exists, responding 400 or 500 if it does not
+func roleExists(c *gin.Context, role string) bool {
+	exists, err := roleService.Exists(role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Role not found"})
+		return false
+	}
+	return true
 }
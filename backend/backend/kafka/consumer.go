@@ -3,12 +3,16 @@ package kafka
 import (
 	"context"
 	"log"
+	"sync"
 
 	"github.com/IBM/sarama"
 )
 
 var Consumer sarama.Consumer
 
+// partitionWG tracks the partition consumer goroutines started by StartConsumer
+var partitionWG sync.WaitGroup
+
 func InitConsumer(brokers []string) error {
 	var err error
 	Consumer, err = sarama.NewConsumer(brokers, nil)
@@ -31,7 +35,9 @@ func StartConsumer(ctx context.Context, topic string, handler func(*sarama.Consu
 			return err
 		}
 
+		partitionWG.Add(1)
 		go func(pc sarama.PartitionConsumer) {
+			defer partitionWG.Done()
 			defer pc.Close()
 			for {
 				select {
@@ -52,7 +58,11 @@ func StartConsumer(ctx context.Context, topic string, handler func(*sarama.Consu
 	return nil
 }
 
+// CloseConsumer waits for the partition consumers to stop and closes the consumer
+// The context passed to StartConsumer must be cancelled first. The broadcast consumer
+// has no consumer group and always starts at the newest offset, so there are no offsets to commit.
 func CloseConsumer() error {
+	partitionWG.Wait()
 	if Consumer != nil {
 		return Consumer.Close()
 	}
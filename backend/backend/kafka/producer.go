@@ -30,10 +30,17 @@ func InitProducer(brokers []string) error {
 
 // PublishMessage publishes a message to Kafka
 func PublishMessage(topic string, message interface{}) error {
+	return PublishEvent(topic, "", "", message)
+}
+
+// PublishEvent publishes a message carrying a stable event id, keyed so that events
+// for the same entity land on the same partition (empty key means round-robin)
+func PublishEvent(topic, eventID, key string, message interface{}) error {
 	if Producer == nil {
 		return fmt.Errorf("kafka producer not initialized")
 	}
 	messageDTO := dto.Message{
+		EventID:          eventID,
 		SenderInstanceID: config.CONFIG.InstanceID,
 		Payload:          message,
 	}
@@ -48,6 +55,12 @@ func PublishMessage(topic string, message interface{}) error {
 		Topic: topic,
 		Value: sarama.ByteEncoder(payload),
 	}
+	if key != "" {
+		kafkaMsg.Key = sarama.StringEncoder(key)
+	}
+	if eventID != "" {
+		kafkaMsg.Headers = []sarama.RecordHeader{{Key: []byte("event_id"), Value: []byte(eventID)}}
+	}
 
 	// Send the message
 	_, _, err = Producer.SendMessage(kafkaMsg)
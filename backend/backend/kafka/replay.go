@@ -0,0 +1,86 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// ReadRange reads the messages of a topic published between from and to (zero to means "up to now")
+// Partitions are read one after another, so ordering is only guaranteed within a partition.
+// It stops at the first error returned by handler.
+func ReadRange(ctx context.Context, brokers []string, topic string, from, to time.Time, handler func(*sarama.ConsumerMessage) error) error {
+	client, err := sarama.NewClient(brokers, sarama.NewConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return fmt.Errorf("failed to create kafka consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	for _, p := range partitions {
+		start, err := client.GetOffset(topic, p, from.UnixMilli())
+		if err != nil {
+			return fmt.Errorf("failed to look up offset for partition %d: %w", p, err)
+		}
+		end, err := client.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return fmt.Errorf("failed to look up newest offset for partition %d: %w", p, err)
+		}
+		if start < 0 || start >= end {
+			// No messages after from in this partition
+			continue
+		}
+
+		if err := readPartition(ctx, consumer, topic, p, start, end, to, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readPartition reads offsets [start, end) of one partition, stopping early once messages are newer than to
+func readPartition(ctx context.Context, consumer sarama.Consumer, topic string, partition int32, start, end int64, to time.Time, handler func(*sarama.ConsumerMessage) error) error {
+	pc, err := consumer.ConsumePartition(topic, partition, start)
+	if err != nil {
+		return fmt.Errorf("failed to consume partition %d: %w", partition, err)
+	}
+	defer pc.Close()
+
+	log.Printf("Replaying partition %d offsets %d-%d", partition, start, end-1)
+	for {
+		select {
+		case msg := <-pc.Messages():
+			if msg == nil {
+				return nil
+			}
+			if !to.IsZero() && msg.Timestamp.After(to) {
+				return nil
+			}
+			if err := handler(msg); err != nil {
+				return err
+			}
+			if msg.Offset >= end-1 {
+				return nil
+			}
+		case err := <-pc.Errors():
+			if err != nil {
+				return fmt.Errorf("kafka partition %d error: %w", partition, err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
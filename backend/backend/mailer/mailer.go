@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"log"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Default is the mailer used by Send.
+// It is replaced by Use at startup; until then messages are only logged.
+var Default Mailer = LogMailer{}
+
+// Use installs m as the mailer
+func Use(m Mailer) {
+	Default = m
+}
+
+// Send delivers msg with the default mailer
+func Send(ctx context.Context, msg Message) error {
+	return Default.Send(ctx, msg)
+}
+
+// LogMailer writes messages to the log instead of sending them (local development only:
+// the log then contains secrets such as password reset links)
+type LogMailer struct{}
+
+// Send logs msg
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer sends email through an SMTP server (a relay, or a local stand-in such as Mailpit)
+type SMTPMailer struct {
+	addr     string
+	auth     smtp.Auth
+	from     string // From header, e.g. "Inventory Manager <no-reply@example.com>"
+	envelope string // Bare sender address
+}
+
+// NewSMTPMailer creates a mailer for the SMTP server at host:port.
+// Without a username, mail is sent unauthenticated (local stand-ins accept this).
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{addr: net.JoinHostPort(host, port), from: from, envelope: from}
+	if address, err := mail.ParseAddress(from); err == nil {
+		m.envelope = address.Address
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send delivers msg. smtp.SendMail upgrades to TLS when the server offers STARTTLS.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", m.from)
+	fmt.Fprintf(&body, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.addr, m.auth, m.envelope, []string{msg.To}, []byte(body.String()))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send mail to %s: %w", msg.To, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,17 +1,28 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+	"inventory-manager-server/services"
 	"inventory-manager-server/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-// AuthMiddleware is a JWT authentication middleware
+// AuthMiddleware authenticates requests with a JWT bearer token or a service account API key (X-API-Key)
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			authenticateAPIKey(c, apiKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"message": "Authorization header required"})
@@ -35,29 +46,104 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens issued before the user's tokens were revoked
+		if err := services.NewAuthService().ValidateClaims(claims); err != nil {
+			if errors.Is(err, services.ErrSessionRevoked) {
+				c.JSON(http.StatusUnauthorized, gin.H{"message": "Session revoked"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			}
+			c.Abort()
+			return
+		}
+
+		// Impersonation sessions are audit-logged request by request, including refused ones
+		if claims.Impersonator != nil {
+			c.Set("impersonation", &dto.ImpersonationInfo{
+				ImpersonatorID:       claims.Impersonator.UserID,
+				ImpersonatorUsername: claims.Impersonator.Username,
+				ReadOnly:             claims.ReadOnly,
+				ExpiresAt:            claims.ExpiresAt.Time,
+			})
+			c.Set("userID", claims.UserID)
+			c.Set("userName", claims.Username)
+			defer recordImpersonatedRequest(c)
+
+			if claims.ReadOnly && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+				c.JSON(http.StatusForbidden, gin.H{"message": "Impersonation session is read-only"})
+				c.Abort()
+				return
+			}
+		}
+
+		// Resolve the role's permissions on every request, so role changes apply immediately
+		permissions, err := services.NewRoleService().Permissions(claims.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			c.Abort()
+			return
+		}
+
 		// Set user information to context
 		c.Set("userID", claims.UserID)
 		c.Set("userName", claims.Username)
 		c.Set("userEmail", claims.Email)
 		c.Set("userRole", claims.Role)
+		c.Set("permissions", permissions)
 
 		c.Next()
 	}
 }
 
-// ManagerOnly is a manager-only middleware
-func ManagerOnly() gin.HandlerFunc {
+// recordImpersonatedRequest appends the audit log entry of a request made with an impersonation token
+func recordImpersonatedRequest(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	id, _ := userID.(uuid.UUID)
+	if err := services.NewImpersonationService().RecordRequest(Actor(c), id, c.GetString("userName"),
+		c.Request.Method, c.Request.URL.RequestURI(), c.Writer.Status()); err != nil {
+		log.Printf("Warning: failed to audit impersonated request %s: %v", GetRequestID(c), err)
+	}
+}
+
+// authenticateAPIKey authenticates a service account by API key; the key's permissions and
+// stores replace those of a role
+func authenticateAPIKey(c *gin.Context, apiKey string) {
+	principal, err := services.NewServiceAccountService().Authenticate(apiKey)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired API key"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		c.Abort()
+		return
+	}
+
+	permissions := make(services.PermissionSet, len(principal.Permissions))
+	for _, permission := range principal.Permissions {
+		permissions[permission] = true
+	}
+
+	// Set service account information to context (userID is the service account ID)
+	c.Set("userID", principal.ServiceAccountID)
+	c.Set("userName", principal.AccountName)
+	c.Set("apiKeyID", principal.KeyID)
+	c.Set("permissions", permissions)
+
+	c.Next()
+}
+
+// RequirePermission allows the request only if the user's role has every given permission
+func RequirePermission(permissions ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get user role from context (set by AuthMiddleware)
-		role, exists := c.Get("userRole")
-		if !exists {
+		// Get permissions from context (set by AuthMiddleware)
+		if _, exists := c.Get("permissions"); !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
 			c.Abort()
 			return
 		}
 
-		// Check if role is manager
-		if role != "manager" {
+		if !HasPermission(c, permissions...) {
 			c.JSON(http.StatusForbidden, gin.H{"message": "Insufficient permissions"})
 			c.Abort()
 			return
@@ -66,3 +152,112 @@ func ManagerOnly() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireAnyPermission allows the request if the user's role has at least one of the given permissions
+func RequireAnyPermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, permission := range permissions {
+			if HasPermission(c, permission) {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"message": "Insufficient permissions"})
+		c.Abort()
+	}
+}
+
+// RequireStorePermission allows the request if the user's role has the permission, or if the store
+// role of one of the user's active store assignments grants it. StoreScope then only covers the
+// stores where the user holds the permission.
+func RequireStorePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("permissions"); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+			c.Abort()
+			return
+		}
+		c.Set("storePermission", permission)
+
+		if HasPermission(c, permission) {
+			c.Next()
+			return
+		}
+		// Service accounts have no store assignments
+		if _, isAPIKey := c.Get("apiKeyID"); !isAPIKey {
+			userID, _ := c.Get("userID")
+			id, _ := userID.(uuid.UUID)
+			granted, err := services.NewStoreAssignmentService().AssignmentGrants(id, permission)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+				c.Abort()
+				return
+			}
+			if granted {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"message": "Insufficient permissions"})
+		c.Abort()
+	}
+}
+
+// HasPermission reports whether the authenticated user's role has every given permission
+func HasPermission(c *gin.Context, permissions ...string) bool {
+	value, exists := c.Get("permissions")
+	if !exists {
+		return false
+	}
+	set, ok := value.(services.PermissionSet)
+	return ok && set.Has(permissions...)
+}
+
+// StoreScope returns the store scope of the authenticated user for the permission required by
+// RequireStorePermission: every store with inventory.all_stores, otherwise the stores where the
+// user's active assignments (or the API key) grant it
+func StoreScope(c *gin.Context) services.StoreScope {
+	if keyID, exists := c.Get("apiKeyID"); exists {
+		return services.APIKeyStoreScope(keyID.(uuid.UUID), HasPermission(c, models.PermInventoryAllStores))
+	}
+	userID, _ := c.Get("userID")
+	id, _ := userID.(uuid.UUID)
+	value, _ := c.Get("permissions")
+	permissions, _ := value.(services.PermissionSet)
+	return services.UserStoreScope(id, permissions, c.GetString("storePermission"))
+}
+
+// DenyImpersonation refuses requests made with an impersonation token, even one that allows
+// writes; it guards credentials and starting further impersonations
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if Impersonation(c) != nil {
+			c.JSON(http.StatusForbidden, gin.H{"message": "Not allowed while impersonating a user"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Impersonation returns the impersonation details of the request, or nil if the user is not
+// being impersonated
+func Impersonation(c *gin.Context) *dto.ImpersonationInfo {
+	value, _ := c.Get("impersonation")
+	info, _ := value.(*dto.ImpersonationInfo)
+	return info
+}
+
+// Actor returns who is making the request (a user or a service account) and from where,
+// for outbox records and the audit log. In impersonation sessions this is the impersonator.
+func Actor(c *gin.Context) services.Actor {
+	if info := Impersonation(c); info != nil {
+		return services.Actor{ID: info.ImpersonatorID, Name: info.ImpersonatorUsername, ClientIP: c.ClientIP(), RequestID: GetRequestID(c)}
+	}
+	userID, _ := c.Get("userID")
+	userName, _ := c.Get("userName")
+	id, _ := userID.(uuid.UUID)
+	name, _ := userName.(string)
+	return services.Actor{ID: id, Name: name, ClientIP: c.ClientIP(), RequestID: GetRequestID(c)}
+}
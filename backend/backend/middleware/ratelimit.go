@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKey identifies the client a request is counted against
+type RateLimitKey func(c *gin.Context) string
+
+// KeyByIP counts requests per client IP
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser counts requests per authenticated user, falling back to the client IP
+func KeyByUser(c *gin.Context) string {
+	if userID, exists := c.Get("userID"); exists {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return KeyByIP(c)
+}
+
+// KeyByAPIKey counts requests per API key (X-API-Key header), falling back to the user or client IP
+func KeyByAPIKey(c *gin.Context) string {
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		// Never store the raw key
+		sum := sha256.Sum256([]byte(apiKey))
+		return "apikey:" + hex.EncodeToString(sum[:8])
+	}
+	return KeyByUser(c)
+}
+
+// RateLimit limits each client to limit.Limit requests per sliding limit.Window.
+// name separates the budgets of different route groups. Responses carry RateLimit-* headers,
+// and rejected requests get 429 with Retry-After. If the cache is unavailable requests are let through.
+func RateLimit(name string, limit config.RateLimit, key RateLimitKey) gin.HandlerFunc {
+	policy := fmt.Sprintf("%d;w=%d", limit.Limit, int(limit.Window.Seconds()))
+	return func(c *gin.Context) {
+		result, err := cache.AllowRate(name+":"+key(c), limit.Limit, limit.Window)
+		if err != nil {
+			log.Printf("Warning: rate limiter unavailable: %v (allowing request)", err)
+			c.Next()
+			return
+		}
+
+		reset := strconv.Itoa(ceilSeconds(result.Reset))
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", reset)
+		if !result.Allowed {
+			c.Header("Retry-After", reset)
+			c.JSON(http.StatusTooManyRequests, gin.H{"message": "Too many requests"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds (at least 1)
+func ceilSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID; it is echoed on every response
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits request IDs accepted from clients or proxies to safe, short tokens
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestID tags each request with an ID, taken from X-Request-ID when a proxy already set a
+// valid one, so log lines and audit log entries of a request can be correlated
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Set("requestID", id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID of the current request
+func GetRequestID(c *gin.Context) string {
+	id, _ := c.Get("requestID")
+	value, _ := id.(string)
+	return value
+}
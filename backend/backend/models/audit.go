@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditLog is an append-only record of an administrative change: who changed what, from where,
+// and the changed fields. The database rejects updates and deletes outside retention purges.
+type AuditLog struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	ActorID    uuid.UUID `gorm:"column:actor_id;type:uuid;not null;index" json:"actor_id"` // User or service account
+	ActorName  string    `gorm:"not null;size:100" json:"actor_name"`
+	Action     string    `gorm:"not null;size:60;index" json:"action"` // "<target type>.<operation>", e.g. "user.delete"
+	TargetType string    `gorm:"not null;size:30;index:idx_audit_log_target" json:"target_type"`
+	TargetID   uuid.UUID `gorm:"column:target_id;type:uuid;index:idx_audit_log_target" json:"target_id"`
+	TargetName string    `gorm:"size:255" json:"target_name"`
+	Diff       JSON      `gorm:"type:jsonb" json:"diff"` // {"field": {"old": ..., "new": ...}}
+	ClientIP   string    `gorm:"size:45" json:"client_ip"`
+	RequestID  string    `gorm:"size:64;index" json:"request_id"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
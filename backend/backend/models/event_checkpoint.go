@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Event checkpoint names
+const (
+	CheckpointPublished  = "published"  // Newest outbox event published to Kafka
+	CheckpointProjection = "projection" // Newest event applied to the stock summary projection
+)
+
+// EventCheckpoint records the creation time of the newest event that reached a stage of the
+// event pipeline. Checkpoints only move forward, so replayed and redelivered events leave them alone.
+type EventCheckpoint struct {
+	Name    string    `gorm:"size:50;primaryKey" json:"name"`
+	EventAt time.Time `gorm:"not null" json:"event_at"`
+}
+
+func (EventCheckpoint) TableName() string {
+	return "event_checkpoints"
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserTOTP is a user's TOTP authenticator. It is pending until the user confirms a first code;
+// only confirmed authenticators are required at login.
+type UserTOTP struct {
+	UserID       uuid.UUID  `gorm:"type:uuid;primaryKey" json:"user_id"`
+	Secret       string     `gorm:"not null;size:64" json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	LastUsedStep int64      `gorm:"not null;default:0" json:"-"` // Time step of the last accepted code; codes are single-use
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (UserTOTP) TableName() string {
+	return "user_totp"
+}
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the authenticator is lost;
+// only its SHA-256 hash is stored
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
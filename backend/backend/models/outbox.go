@@ -6,11 +6,29 @@ import (
 	"github.com/google/uuid"
 )
 
+// Outbox entity types
+const (
+	EntityInventory      = "inventory"
+	EntitySKU            = "sku"
+	EntityStore          = "store"
+	EntityUser           = "user"
+	EntityStoreUser      = "store_user"
+	EntityRole           = "role"
+	EntityServiceAccount = "service_account"
+	EntityAPIKey         = "api_key"
+)
+
 // Outbox represents an outbox record model (for transactional outbox pattern)
+// Every mutation writes one record identifying the changed entity and a JSON diff of its fields.
+// The inventory-specific columns are only populated for inventory records (SKU/store columns are
+// also filled for SKU, store and staff-assignment records so clients can display names).
 type Outbox struct {
 	ID               uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
 	OperationType    string    `gorm:"not null;size:20" json:"operation_type"` // "create", "update", "adjust", "delete"
 	SenderInstanceID string    `gorm:"not null;size:100;index" json:"sender_instance_id"`
+	EntityType       string    `gorm:"not null;size:30;default:inventory;index" json:"entity_type"` // "inventory", "sku", "store", "user", "store_user", "role", "service_account", "api_key"
+	EntityID         uuid.UUID `gorm:"column:entity_id;type:uuid;index" json:"entity_id"`
+	Diff             JSON      `gorm:"type:jsonb" json:"diff"` // {"field": {"old": ..., "new": ...}}
 	InventoryID      uuid.UUID `gorm:"column:inventory_id;type:uuid;not null;index" json:"inventory_id"`
 	SKUID            uuid.UUID `gorm:"column:sku_id;type:uuid;not null;index" json:"sku_id"`
 	SKUName          string    `gorm:"not null;size:100" json:"sku_name"`
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is a server-side refresh token; only its SHA-256 hash is stored
+// Every use rotates it: the token is revoked and replaced by a new one in the same family.
+// Presenting a revoked token revokes the whole family (the token was stolen or replayed).
+type RefreshToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	FamilyID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"family_id"`
+	TokenHash  string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	RememberMe bool       `gorm:"not null;default:false" json:"remember_me"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	ReplacedBy *uuid.UUID `gorm:"type:uuid" json:"replaced_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
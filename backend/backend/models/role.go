@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Permission names
+const (
+	PermInventoryRead         = "inventory.read"
+	PermInventoryAllStores    = "inventory.all_stores"
+	PermInventoryAdjust       = "inventory.adjust"
+	PermInventoryUpdate       = "inventory.update"
+	PermInventoryManage       = "inventory.manage"
+	PermSKURead               = "sku.read"
+	PermSKUManage             = "sku.manage"
+	PermStoresManage          = "stores.manage"
+	PermUsersManage           = "users.manage"
+	PermUsersImpersonate      = "users.impersonate"
+	PermRolesManage           = "roles.manage"
+	PermServiceAccountsManage = "service_accounts.manage"
+	PermReportsRead           = "reports.read"
+	PermReportsRebuild        = "reports.rebuild"
+	PermSystemMonitor         = "system.monitor"
+	PermAuditRead             = "audit.read"
+)
+
+// PermissionInfo describes a permission
+type PermissionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Permissions lists every permission a role can be granted
+var Permissions = []PermissionInfo{
+	{PermInventoryRead, "View inventory of assigned stores"},
+	{PermInventoryAllStores, "View and adjust inventory of every store, not only assigned ones"},
+	{PermInventoryAdjust, "Adjust inventory quantities"},
+	{PermInventoryUpdate, "Set inventory quantities"},
+	{PermInventoryManage, "Create and delete inventory records"},
+	{PermSKURead, "View SKUs and categories"},
+	{PermSKUManage, "Create, update and delete SKUs"},
+	{PermStoresManage, "Manage stores and their staff"},
+	{PermUsersManage, "Manage users"},
+	{PermUsersImpersonate, "View the application as another user (read-only unless writes are requested)"},
+	{PermRolesManage, "Manage roles and their permissions"},
+	{PermServiceAccountsManage, "Manage service accounts and their API keys"},
+	{PermReportsRead, "View reports"},
+	{PermReportsRebuild, "Rebuild report projections"},
+	{PermSystemMonitor, "View scheduled jobs and cache statistics"},
+	{PermAuditRead, "View and export the audit log"},
+}
+
+// IsPermission reports whether name is a known permission
+func IsPermission(name string) bool {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Built-in role names
+const (
+	RoleManager = "manager"
+	RoleStaff   = "staff"
+)
+
+// BuiltInRoles holds the permissions of the built-in roles; they are synced to the
+// database at startup and cannot be changed through the API
+var BuiltInRoles = map[string][]string{
+	RoleManager: allPermissionNames(),
+	RoleStaff:   {PermInventoryRead, PermInventoryAdjust, PermSKURead},
+}
+
+func allPermissionNames() []string {
+	names := make([]string, len(Permissions))
+	for i, p := range Permissions {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Role is a named set of permissions assigned to users (users.role references roles.name)
+type Role struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	Name        string    `gorm:"uniqueIndex;not null;size:50" json:"name"`
+	Description string    `gorm:"not null;default:'';size:255" json:"description"`
+	BuiltIn     bool      `gorm:"not null;default:false" json:"built_in"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
+
+// RolePermission grants a permission to a role
+type RolePermission struct {
+	RoleID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"role_id"`
+	Permission string    `gorm:"primaryKey;size:50" json:"permission"`
+}
+
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
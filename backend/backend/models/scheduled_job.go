@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// ScheduledJob is the shared schedule and lease of a periodic background job
+// The pod that claims a due run takes the lease and increments the fencing token;
+// results are only recorded while that token is still current.
+type ScheduledJob struct {
+	Name           string     `gorm:"primaryKey;size:100" json:"name"`
+	Schedule       string     `gorm:"size:100;not null" json:"schedule"`
+	NextRunAt      time.Time  `gorm:"not null" json:"next_run_at"`
+	Owner          string     `gorm:"size:255" json:"owner"`
+	FencingToken   int64      `gorm:"not null;default:0" json:"fencing_token"`
+	LeaseUntil     *time.Time `json:"lease_until"`
+	LastStartedAt  *time.Time `json:"last_started_at"`
+	LastFinishedAt *time.Time `json:"last_finished_at"`
+	LastStatus     string     `gorm:"size:20" json:"last_status"`
+	LastError      string     `gorm:"type:text" json:"last_error"`
+}
+
+func (ScheduledJob) TableName() string {
+	return "scheduled_jobs"
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServiceAccount is a non-human API client (POS terminal, scanner, ERP sync job) that
+// authenticates with API keys instead of a username and password
+type ServiceAccount struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	Name        string    `gorm:"uniqueIndex;not null;size:50" json:"name"`
+	Description string    `gorm:"not null;default:'';size:255" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (ServiceAccount) TableName() string {
+	return "service_accounts"
+}
+
+// APIKey authenticates a service account; only the SHA-256 hash of the key is stored.
+// A key grants its own permissions (api_key_permissions) and, without inventory.all_stores,
+// only covers its own stores (api_key_stores).
+type APIKey struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	ServiceAccountID uuid.UUID  `gorm:"type:uuid;not null;index" json:"service_account_id"`
+	Name             string     `gorm:"not null;size:100" json:"name"`
+	Prefix           string     `gorm:"not null;size:16" json:"prefix"` // First characters of the key, to tell keys apart
+	KeyHash          string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// APIKeyPermission grants a permission to an API key
+type APIKeyPermission struct {
+	APIKeyID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"api_key_id"`
+	Permission string    `gorm:"primaryKey;size:50" json:"permission"`
+}
+
+func (APIKeyPermission) TableName() string {
+	return "api_key_permissions"
+}
+
+// APIKeyStore gives an API key access to a store's inventory
+type APIKeyStore struct {
+	APIKeyID uuid.UUID `gorm:"type:uuid;primaryKey" json:"api_key_id"`
+	StoreID  uuid.UUID `gorm:"type:uuid;primaryKey" json:"store_id"`
+}
+
+func (APIKeyStore) TableName() string {
+	return "api_key_stores"
+}
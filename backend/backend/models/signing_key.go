@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SigningKey is a generated JWT signing key, shared by every instance; keys loaded from files
+// are never stored. The newest key signs tokens, older ones only verify them until removed.
+type SigningKey struct {
+	ID         string    `gorm:"primaryKey;size:64" json:"id"` // "kid" header of tokens signed with the key
+	Algorithm  string    `gorm:"not null;size:16" json:"algorithm"`
+	PrivateKey string    `gorm:"not null" json:"-"` // PKCS#8 PEM, encrypted with JWT_KEY_ENCRYPTION_KEY (signing.Seal)
+	CreatedAt  time.Time `gorm:"not null;index" json:"created_at"`
+}
+
+func (SigningKey) TableName() string {
+	return "signing_keys"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StockSummary is a materialised store × category stock projection
+// Rows are maintained by the event consumer and recomputed from inventory by the repair job
+type StockSummary struct {
+	StoreID         uuid.UUID `gorm:"column:store_id;type:uuid;primaryKey" json:"store_id"`
+	Category        string    `gorm:"size:100;primaryKey" json:"category"`
+	SKUCount        int64     `gorm:"column:sku_count;not null;default:0" json:"sku_count"`
+	TotalQuantity   int64     `gorm:"not null;default:0" json:"total_quantity"`
+	OutOfStockCount int64     `gorm:"not null;default:0" json:"out_of_stock_count"`
+	TotalValue      float64   `gorm:"type:decimal(14,2);not null;default:0" json:"total_value"`
+	RefreshedAt     time.Time `gorm:"not null" json:"refreshed_at"`
+}
+
+func (StockSummary) TableName() string {
+	return "stock_summary"
+}
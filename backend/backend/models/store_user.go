@@ -7,17 +7,32 @@ import (
 )
 
 // StoreUser represents a store-user association model
+// An assignment can be limited to a period (seasonal and relief staff) and can grant a store
+// role, whose permissions the user holds in this store only, on top of their own role.
 type StoreUser struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
-	UserID    uuid.UUID `gorm:"column:user_id;type:uuid;not null;index;uniqueIndex:idx_store_user" json:"user_id"`
-	StoreID   uuid.UUID `gorm:"column:store_id;type:uuid;not null;index;uniqueIndex:idx_store_user" json:"store_id"`
-	Version   int       `gorm:"default:1" json:"version"`
-	User      User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Store     Store     `gorm:"foreignKey:StoreID" json:"store,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID    uuid.UUID  `gorm:"column:user_id;type:uuid;not null;index;uniqueIndex:idx_store_user" json:"user_id"`
+	StoreID   uuid.UUID  `gorm:"column:store_id;type:uuid;not null;index;uniqueIndex:idx_store_user" json:"store_id"`
+	Role      *string    `gorm:"size:50" json:"role"`   // Store role; nil grants nothing beyond the user's role
+	ValidFrom *time.Time `json:"valid_from"`            // Nil means since the assignment was made
+	ValidTo   *time.Time `gorm:"index" json:"valid_to"` // Exclusive; nil means until removed
+	Source    string     `gorm:"size:20;not null;default:''" json:"source,omitempty"`
+	Version   int        `gorm:"default:1" json:"version"`
+	User      User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Store     Store      `gorm:"foreignKey:StoreID" json:"store,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
 
+// StoreUserSourceSSO marks assignments made by single sign-on from identity provider groups; only
+// these are removed again by it. Assignments made through the API have no source.
+const StoreUserSourceSSO = "sso"
+
 func (StoreUser) TableName() string {
 	return "store_user"
 }
+
+// ActiveAt reports whether the assignment grants store access at t
+func (su StoreUser) ActiveAt(t time.Time) bool {
+	return (su.ValidFrom == nil || !su.ValidFrom.After(t)) && (su.ValidTo == nil || su.ValidTo.After(t))
+}
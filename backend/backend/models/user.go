@@ -7,14 +7,40 @@ import (
 )
 
 // User represents a user model
+// Users are never deleted, so history keeps pointing at them: they are deactivated (login and
+// tokens are refused) and, after USER_ANONYMIZE_AFTER, their personal data is erased.
 type User struct {
-	ID           uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
-	Username     string    `gorm:"uniqueIndex;not null;size:50" json:"username"`
-	PasswordHash string    `gorm:"not null;size:255" json:"-"`
-	Email        string    `gorm:"uniqueIndex;not null;size:100" json:"email"`
-	Role         string    `gorm:"not null;size:20" json:"role"` // 'manager' or 'staff'
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                    uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	Username              string     `gorm:"uniqueIndex;not null;size:50" json:"username"`
+	PasswordHash          string     `gorm:"not null;size:255" json:"-"`
+	Email                 string     `gorm:"uniqueIndex;not null;size:100" json:"email"`
+	Role                  string     `gorm:"not null;size:50" json:"role"`                          // Name of a role (see Role)
+	TokenVersion          int        `gorm:"not null;default:0" json:"-"`                           // Incremented to revoke every issued token
+	PasswordResetRequired bool       `gorm:"not null;default:false" json:"password_reset_required"` // Set by a forced reset; login is refused until the password is reset
+	DeactivatedAt         *time.Time `gorm:"index" json:"deactivated_at"`                           // Nil while the user is active
+	DeactivationReason    string     `gorm:"size:255" json:"deactivation_reason"`
+	AnonymizedAt          *time.Time `json:"anonymized_at"` // Personal data was erased; the user cannot be reactivated
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// User statuses reported in API responses
+const (
+	UserStatusActive      = "active"
+	UserStatusDeactivated = "deactivated"
+)
+
+// Active reports whether the user may log in
+func (u User) Active() bool {
+	return u.DeactivatedAt == nil
+}
+
+// Status returns UserStatusActive or UserStatusDeactivated
+func (u User) Status() string {
+	if u.Active() {
+		return UserStatusActive
+	}
+	return UserStatusDeactivated
 }
 
 func (User) TableName() string {
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links a user to an account at an external identity provider (OIDC issuer and subject)
+type UserIdentity struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Issuer      string    `gorm:"not null;size:255;uniqueIndex:idx_user_identity" json:"issuer"`
+	Subject     string    `gorm:"not null;size:255;uniqueIndex:idx_user_identity" json:"subject"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -0,0 +1,94 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"log"
+	"math/big"
+)
+
+// jwk is a JSON Web Key (RFC 7517); only the fields of public signing keys are decoded
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// jwkSet is a JSON Web Key Set
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// publicKeys returns the signing keys of the set by key ID; unsupported keys are skipped
+func (s jwkSet) publicKeys() map[string]interface{} {
+	keys := make(map[string]interface{}, len(s.Keys))
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, ok := k.publicKey()
+		if !ok {
+			log.Printf("Warning: skipping unsupported OIDC signing key %q (kty %s)", k.Kid, k.Kty)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys
+}
+
+// publicKey decodes an RSA, EC or Ed25519 public key
+func (k jwk) publicKey() (interface{}, bool) {
+	switch k.Kty {
+	case "RSA":
+		n, okN := decodeBigInt(k.N)
+		e, okE := decodeBigInt(k.E)
+		if !okN || !okE || !e.IsInt64() {
+			return nil, false
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, true
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, false
+		}
+		x, okX := decodeBigInt(k.X)
+		y, okY := decodeBigInt(k.Y)
+		if !okX || !okY {
+			return nil, false
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, true
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, false
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, false
+		}
+		return ed25519.PublicKey(x), true
+	}
+	return nil, false
+}
+
+func decodeBigInt(s string) (*big.Int, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, false
+	}
+	return new(big.Int).SetBytes(b), true
+}
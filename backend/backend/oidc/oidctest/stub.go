@@ -0,0 +1,328 @@
+// Package oidctest provides a stub OpenID Connect identity provider for tests and local
+// development. It is not part of the server binary; cmd/stub-idp serves it on its own.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"inventory-manager-server/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// stubCodeTTL is how long an authorization code of the stub provider can be redeemed
+const stubCodeTTL = time.Minute
+
+// Provider is a minimal OpenID Connect identity provider for local development and tests.
+// It signs in whoever submits its login form, with the claims typed into it; never expose it
+// outside a development environment.
+type Provider struct {
+	issuer       string
+	publicURL    string // Base URL browsers use; may differ from the issuer inside a container network
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+	kid          string
+
+	mu    sync.Mutex
+	codes map[string]stubCode
+}
+
+type stubCode struct {
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	claims        map[string]interface{}
+	expiresAt     time.Time
+}
+
+var stubLoginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Stub identity provider</title></head>
+<body style="font-family: sans-serif; max-width: 28rem; margin: 4rem auto;">
+<h1>Stub identity provider</h1>
+<p>Development only: you are signed in as whoever you type in.</p>
+<form method="post" action="authorize">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}
+<p><label>Username<br><input name="username" value="manager" required></label></p>
+<p><label>Email<br><input name="email" value="manager@example.com" required></label></p>
+<p><label>Name<br><input name="name" value="Stub Manager"></label></p>
+<p><label>Groups (comma-separated)<br><input name="groups" value="inventory-managers"></label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body>
+</html>
+`))
+
+// NewProvider creates a stub provider with a new signing key. publicURL is where browsers
+// reach it (defaults to the issuer).
+func NewProvider(issuer, publicURL, clientID, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := oidc.RandomString(8)
+	if err != nil {
+		return nil, err
+	}
+	if publicURL == "" {
+		publicURL = issuer
+	}
+	return &Provider{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		publicURL:    strings.TrimSuffix(publicURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		key:          key,
+		kid:          kid,
+		codes:        make(map[string]stubCode),
+	}, nil
+}
+
+// NewServer starts a stub provider on a local test server, whose URL is the issuer. Callers
+// close the server when done.
+func NewServer(clientID, clientSecret string) (*Provider, *httptest.Server, error) {
+	var provider *Provider
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider.Handler().ServeHTTP(w, r)
+	}))
+	provider, err := NewProvider(server.URL, "", clientID, clientSecret)
+	if err != nil {
+		server.Close()
+		return nil, nil, err
+	}
+	return provider, server, nil
+}
+
+// SignIn submits the login form for the authorization URL of a relying party, as a browser
+// would, and returns the redirect URL carrying the code and state
+func SignIn(authURL, username, email string, groups ...string) (*url.URL, error) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return nil, err
+	}
+	form := parsed.Query()
+	form.Set("username", username)
+	form.Set("email", email)
+	form.Set("groups", strings.Join(groups, ","))
+	parsed.RawQuery = ""
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.PostForm(parsed.String(), form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return nil, fmt.Errorf("stub login failed with status %d", resp.StatusCode)
+	}
+	return url.Parse(resp.Header.Get("Location"))
+}
+
+// Handler returns the HTTP handler serving discovery, login, token and key set endpoints
+func (s *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.handleDiscovery)
+	mux.HandleFunc("/authorize", s.handleAuthorize)
+	mux.HandleFunc("/token", s.handleToken)
+	mux.HandleFunc("/jwks", s.handleJWKS)
+	return mux
+}
+
+func (s *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.publicURL + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile", "email", "groups"},
+	})
+}
+
+// handleAuthorize shows the login form (GET) and issues an authorization code (POST).
+// Tests can POST the form fields directly.
+func (s *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	params := map[string]string{}
+	for _, name := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"} {
+		params[name] = r.Form.Get(name)
+	}
+	if params["response_type"] != "code" || params["client_id"] != s.clientID || params["redirect_uri"] == "" {
+		http.Error(w, "unsupported response_type, unknown client_id or missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if params["code_challenge"] == "" || params["code_challenge_method"] != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		stubLoginPage.Execute(w, map[string]interface{}{"Params": params})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := strings.TrimSpace(r.Form.Get("username"))
+	if username == "" {
+		http.Error(w, "username is required", http.StatusBadRequest)
+		return
+	}
+	var groups []string
+	for _, group := range strings.Split(r.Form.Get("groups"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	claims := map[string]interface{}{
+		"sub":                "stub|" + username,
+		"preferred_username": username,
+		"email":              strings.TrimSpace(r.Form.Get("email")),
+		"email_verified":     true,
+		"name":               strings.TrimSpace(r.Form.Get("name")),
+		"groups":             groups,
+	}
+
+	code, err := oidc.RandomString(32)
+	if err != nil {
+		http.Error(w, "failed to generate code", http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	s.codes[code] = stubCode{
+		redirectURI:   params["redirect_uri"],
+		codeChallenge: params["code_challenge"],
+		nonce:         params["nonce"],
+		claims:        claims,
+		expiresAt:     time.Now().Add(stubCodeTTL),
+	}
+	s.mu.Unlock()
+
+	redirect, err := url.Parse(params["redirect_uri"])
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	query := redirect.Query()
+	query.Set("code", code)
+	query.Set("state", params["state"])
+	redirect.RawQuery = query.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.clientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	// Codes are single-use
+	s.mu.Lock()
+	code, found := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+	if !found || time.Now().After(code.expiresAt) || code.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != code.codeChallenge {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   s.issuer,
+		"aud":   s.clientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": code.nonce,
+	}
+	for name, value := range code.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.kid
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		http.Error(w, "failed to sign ID token", http.StatusInternalServerError)
+		return
+	}
+	accessToken, _ := oidc.RandomString(32)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (s *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	public := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": s.kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}}})
+}
+
+func writeTokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
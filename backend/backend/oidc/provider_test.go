@@ -0,0 +1,94 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"inventory-manager-server/oidc"
+	"inventory-manager-server/oidc/oidctest"
+)
+
+const redirectURL = "http://app.test/oidc/callback"
+
+func newTestProvider(t *testing.T) *oidc.Provider {
+	t.Helper()
+	_, server, err := oidctest.NewServer("inventory-manager", "stub-secret")
+	if err != nil {
+		t.Fatalf("failed to start stub provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+	return oidc.NewProvider(server.URL, "inventory-manager", "stub-secret", redirectURL, []string{"openid", "email"})
+}
+
+// signIn starts a login with a new PKCE pair and returns the code and verifier
+func signIn(t *testing.T, provider *oidc.Provider, nonce string) (code, verifier string) {
+	t.Helper()
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", nonce, challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	redirect, err := oidctest.SignIn(authURL, "alice", "alice@example.com", "inventory-staff")
+	if err != nil {
+		t.Fatalf("SignIn: %v", err)
+	}
+	if got := redirect.Query().Get("state"); got != "state-1" {
+		t.Fatalf("redirect state = %q, want %q", got, "state-1")
+	}
+	return redirect.Query().Get("code"), verifier
+}
+
+func TestExchangeReturnsVerifiedClaims(t *testing.T) {
+	provider := newTestProvider(t)
+	code, verifier := signIn(t, provider, "nonce-1")
+
+	claims, err := provider.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if got := claims.String("email"); got != "alice@example.com" {
+		t.Errorf("email = %q, want %q", got, "alice@example.com")
+	}
+	if groups := claims.Strings("groups"); len(groups) != 1 || groups[0] != "inventory-staff" {
+		t.Errorf("groups = %v, want [inventory-staff]", groups)
+	}
+}
+
+func TestExchangeRequiresPKCEVerifier(t *testing.T) {
+	provider := newTestProvider(t)
+	code, _ := signIn(t, provider, "nonce-1")
+
+	otherVerifier, _, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Exchange(context.Background(), code, otherVerifier, "nonce-1"); err == nil {
+		t.Fatal("Exchange with another code verifier succeeded")
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	provider := newTestProvider(t)
+	code, verifier := signIn(t, provider, "nonce-1")
+
+	_, err := provider.Exchange(context.Background(), code, verifier, "nonce-2")
+	if !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Fatalf("Exchange error = %v, want %v", err, oidc.ErrInvalidIDToken)
+	}
+}
+
+func TestExchangeCodeIsSingleUse(t *testing.T) {
+	provider := newTestProvider(t)
+	code, verifier := signIn(t, provider, "nonce-1")
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-1"); err != nil {
+		t.Fatalf("first Exchange: %v", err)
+	}
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-1"); err == nil {
+		t.Fatal("second Exchange of the same code succeeded")
+	}
+}
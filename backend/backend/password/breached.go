@@ -0,0 +1,93 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed common-passwords.txt
+var commonPasswords string
+
+var (
+	breachedMu sync.RWMutex
+	// breached holds the upper-case hex SHA-1 of every breached password
+	breached = map[string]struct{}{}
+)
+
+func init() {
+	if err := loadBreached(strings.NewReader(commonPasswords)); err != nil {
+		panic(err)
+	}
+}
+
+// LoadBreachedList adds the passwords of a local list to the breached passwords. Each line is
+// either a plain password or a hex SHA-1 hash, optionally followed by ":count" (the format of
+// the Have I Been Pwned downloads). Blank lines and lines starting with # are ignored.
+func LoadBreachedList(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+	if err := loadBreached(file); err != nil {
+		return fmt.Errorf("failed to read breached password list %s: %w", path, err)
+	}
+	return nil
+}
+
+// Breached reports whether a password, or its lower-case form, appears in a breached list
+func Breached(plain string) bool {
+	breachedMu.RLock()
+	defer breachedMu.RUnlock()
+	if _, found := breached[sha1Hex(plain)]; found {
+		return true
+	}
+	_, found := breached[sha1Hex(strings.ToLower(plain))]
+	return found
+}
+
+func loadBreached(r io.Reader) error {
+	hashes := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1Hex(hash) {
+			hashes = append(hashes, strings.ToUpper(hash))
+		} else {
+			hashes = append(hashes, sha1Hex(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	breachedMu.Lock()
+	defer breachedMu.Unlock()
+	for _, hash := range hashes {
+		breached[hash] = struct{}{}
+	}
+	return nil
+}
+
+func sha1Hex(plain string) string {
+	sum := sha1.Sum([]byte(plain))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 2*sha1.Size {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,121 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2id parameters (OWASP recommendation: 19 MiB of memory, 2 iterations, 1 thread)
+const (
+	argonMemory  uint32 = 19 * 1024
+	argonTime    uint32 = 2
+	argonThreads uint8  = 1
+	argonSaltLen        = 16
+	argonKeyLen  uint32 = 32
+)
+
+// ErrUnknownFormat is returned when a stored hash is neither bcrypt nor argon2id
+var ErrUnknownFormat = errors.New("unknown password hash format")
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// DummyHash returns an argon2id hash of a random password with the current parameters. Verifying
+// against it takes as long as verifying a real password, so callers can answer unknown users as
+// slowly as known ones.
+func DummyHash() string {
+	dummyHashOnce.Do(func() {
+		secret := make([]byte, 16)
+		if _, err := rand.Read(secret); err != nil {
+			panic(fmt.Sprintf("failed to generate dummy password: %v", err))
+		}
+		hash, err := Hash(base64.RawStdEncoding.EncodeToString(secret))
+		if err != nil {
+			panic(fmt.Sprintf("failed to hash dummy password: %v", err))
+		}
+		dummyHash = hash
+	})
+	return dummyHash
+}
+
+// Hash hashes a password with argon2id, encoded in the PHC string format
+// ($argon2id$v=19$m=...,t=...,p=...$salt$key)
+func Hash(plain string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(plain), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether plain matches an argon2id or bcrypt hash. needsRehash is set when the
+// password matched a bcrypt hash or argon2id parameters other than the current ones, so the
+// caller can store a fresh Hash.
+func Verify(plain, encoded string) (ok bool, needsRehash bool, err error) {
+	switch {
+	case encoded == "":
+		// Users without a local password (anonymized or single sign-on only), answered as slowly
+		// as the others
+		verifyArgon2id(plain, DummyHash())
+		return false, false, nil
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return verifyArgon2id(plain, encoded)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(plain))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+	default:
+		return false, false, ErrUnknownFormat
+	}
+}
+
+func verifyArgon2id(plain, encoded string) (bool, bool, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false, ErrUnknownFormat
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, ErrUnknownFormat
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, ErrUnknownFormat
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrUnknownFormat
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false, ErrUnknownFormat
+	}
+
+	derived := argon2.IDKey([]byte(plain), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(derived, key) != 1 {
+		return false, false, nil
+	}
+	needsRehash := memory != argonMemory || time != argonTime || threads != argonThreads ||
+		len(salt) != argonSaltLen || uint32(len(key)) != argonKeyLen
+	return true, needsRehash, nil
+}
@@ -0,0 +1,94 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashVerifiesOnlyItsPassword(t *testing.T) {
+	hash, err := Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		t.Fatalf("hash %q is not argon2id", hash)
+	}
+
+	ok, needsRehash, err := Verify("correct horse", hash)
+	if err != nil || !ok || needsRehash {
+		t.Errorf("Verify(password) = %v, %v, %v, want true, false, nil", ok, needsRehash, err)
+	}
+	ok, needsRehash, err = Verify("wrong horse", hash)
+	if err != nil || ok || needsRehash {
+		t.Errorf("Verify(wrong password) = %v, %v, %v, want false, false, nil", ok, needsRehash, err)
+	}
+}
+
+func TestVerifyRejectsMalformedHashes(t *testing.T) {
+	hash, err := Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	parts := strings.Split(hash, "$")
+
+	for name, encoded := range map[string]string{
+		"unknown algorithm": "$scrypt$ln=15,r=8,p=1$c2FsdA$a2V5",
+		"missing key":       strings.Join(parts[:5], "$"),
+		"other version":     strings.Replace(hash, "$v=19$", "$v=16$", 1),
+		"bad parameters":    strings.Replace(hash, parts[3], "m=lots", 1),
+		"bad salt":          strings.Replace(hash, parts[4], "not base64!", 1),
+		"empty key":         strings.Join(append(parts[:5:5], ""), "$"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ok, _, err := Verify("correct horse", encoded)
+			if ok || !errors.Is(err, ErrUnknownFormat) {
+				t.Errorf("Verify(%q) = %v, %v, want false, %v", encoded, ok, err, ErrUnknownFormat)
+			}
+		})
+	}
+}
+
+func TestVerifyAsksToRehashLegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, needsRehash, err := Verify("correct horse", string(legacy))
+	if err != nil || !ok || !needsRehash {
+		t.Errorf("Verify(password) = %v, %v, %v, want true, true, nil", ok, needsRehash, err)
+	}
+	ok, _, err = Verify("wrong horse", string(legacy))
+	if err != nil || ok {
+		t.Errorf("Verify(wrong password) = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestVerifyAsksToRehashChangedParameters(t *testing.T) {
+	// The same password hashed with fewer iterations, as by an earlier version
+	salt := []byte("sixteen byte sal")
+	key := argon2.IDKey([]byte("correct horse"), salt, argonTime-1, argonMemory, argonThreads, argonKeyLen)
+	outdated := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime-1, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+	ok, needsRehash, err := Verify("correct horse", outdated)
+	if err != nil || !ok || !needsRehash {
+		t.Errorf("Verify(outdated hash) = %v, %v, %v, want true, true, nil", ok, needsRehash, err)
+	}
+}
+
+func TestVerifyWithoutPasswordFails(t *testing.T) {
+	if ok, _, err := Verify("", ""); ok || err != nil {
+		t.Errorf("Verify of a user without a password = %v, %v, want false, nil", ok, err)
+	}
+	if ok, _, err := Verify("anything", DummyHash()); ok || err != nil {
+		t.Errorf("Verify against the dummy hash = %v, %v, want false, nil", ok, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/events"
+)
+
+// runReplay implements the "replay" subcommand:
+//
+//	server replay --from=2025-01-20T00:00:00Z [--to=...] [--source=kafka|outbox] [--topic=...]
+//	              [--sinks=log,cache,projection,webhook] [--webhook-url=...] [--entity=inventory] [--rate=50] [--dry-run]
+func runReplay(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	from := fs.String("from", "", "replay events created at or after this RFC3339 timestamp (required)")
+	to := fs.String("to", "", "replay events created at or before this RFC3339 timestamp (default: now)")
+	source := fs.String("source", events.SourceKafka, "where to read events from: kafka or outbox")
+	topic := fs.String("topic", cfg.KafkaTopic, "Kafka topic to read from")
+	sinkNames := fs.String("sinks", "log", "comma-separated sinks to apply events to: log, cache, projection, webhook")
+	webhookURL := fs.String("webhook-url", "", "URL events are POSTed to by the webhook sink")
+	entityType := fs.String("entity", "", "only replay events for this entity type (inventory, sku, store, user, store_user)")
+	rate := fs.Int("rate", 0, "maximum events applied per second (0 = unlimited)")
+	dryRun := fs.Bool("dry-run", false, "log the events that would be replayed without applying them")
+	fs.Parse(args)
+
+	if *from == "" {
+		return fmt.Errorf("--from is required")
+	}
+	opts := events.ReplayOptions{
+		Source:     *source,
+		Brokers:    []string{cfg.KafkaBrokers},
+		Topic:      *topic,
+		EntityType: *entityType,
+		DryRun:     *dryRun,
+		Rate:       *rate,
+	}
+	var err error
+	if opts.From, err = time.Parse(time.RFC3339, *from); err != nil {
+		return fmt.Errorf("invalid --from: %w", err)
+	}
+	if *to != "" {
+		if opts.To, err = time.Parse(time.RFC3339, *to); err != nil {
+			return fmt.Errorf("invalid --to: %w", err)
+		}
+	}
+
+	// Only connect to what the selected source and sinks need
+	needsDB := opts.Source == events.SourceOutbox
+	for _, name := range strings.Split(*sinkNames, ",") {
+		switch strings.TrimSpace(name) {
+		case "log":
+			opts.Sinks = append(opts.Sinks, events.NewLogSink())
+		case "cache":
+			opts.Sinks = append(opts.Sinks, events.NewCacheSink())
+			needsDB = true
+			if !opts.DryRun {
+				if err := cache.InitRedis(cfg.RedisHost, cfg.RedisPort); err != nil {
+					return err
+				}
+			}
+		case "projection":
+			opts.Sinks = append(opts.Sinks, events.NewProjectionSink())
+			needsDB = true
+		case "webhook":
+			if *webhookURL == "" {
+				return fmt.Errorf("--webhook-url is required for the webhook sink")
+			}
+			opts.Sinks = append(opts.Sinks, events.NewWebhookSink(*webhookURL))
+		case "":
+		default:
+			return fmt.Errorf("unknown sink %q", name)
+		}
+	}
+	if needsDB {
+		if err := database.InitDB(databaseDSN(cfg)); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Replaying %s events from %s (sinks: %s, dry run: %t)", opts.Source, opts.From.Format(time.RFC3339), *sinkNames, opts.DryRun)
+	stats, err := events.Replay(ctx, opts)
+	log.Printf("Replay finished: %d read, %d applied, %d skipped, %d failed", stats.Read, stats.Applied, stats.Skipped, stats.Failed)
+	return err
+}
@@ -1,8 +1,12 @@
 package routes
 
 import (
+	"log"
+
+	"inventory-manager-server/config"
 	"inventory-manager-server/handlers"
 	"inventory-manager-server/middleware"
+	"inventory-manager-server/models"
 	"inventory-manager-server/websocket"
 
 	"github.com/gin-gonic/gin"
@@ -12,12 +16,14 @@ import (
 func SetupRoutes() *gin.Engine {
 	// Create Gin engine
 	router := gin.New()
+	trustProxies(router, config.CONFIG.TrustedProxies)
 	// Setup middleware (CORS, logging, etc.)
 	// Skip logging for health check endpoint to reduce noise
 	router.Use(gin.LoggerWithConfig(gin.LoggerConfig{
-		SkipPaths: []string{"/health"},
+		SkipPaths: []string{"/health", "/ready"},
 	}))
 	router.Use(gin.Recovery())
+	router.Use(middleware.RequestID())
 
 	// CORS middleware - permissive configuration
 	router.Use(func(c *gin.Context) {
@@ -30,8 +36,9 @@ func SetupRoutes() *gin.Engine {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		}
 
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-API-Key, X-Request-ID, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After, X-Request-ID, Content-Disposition")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -45,13 +52,31 @@ func SetupRoutes() *gin.Engine {
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
-	router.POST("/testInfra", handlers.TestInfra)
-
-	// Public route
-	router.POST("/api/auth/login", handlers.Login)
+	router.GET("/ready", handlers.Ready)
+	// Rate limits (per route group; budgets are shared by all instances)
+	publicLimit := middleware.RateLimit("public", config.CONFIG.RateLimitPublic, middleware.KeyByIP)
+	loginLimit := middleware.RateLimit("login", config.CONFIG.RateLimitLogin, middleware.KeyByIP)
+	apiLimit := middleware.RateLimit("api", config.CONFIG.RateLimitAPI, middleware.KeyByAPIKey)
+	// Counted before authentication, so invalid tokens and API keys cannot bypass the API budget
+	apiIPLimit := middleware.RateLimit("api-ip", config.CONFIG.RateLimitAPIIP, middleware.KeyByIP)
+
+	router.POST("/testInfra", publicLimit, handlers.TestInfra)
+	router.GET("/.well-known/jwks.json", publicLimit, handlers.GetJWKS)
+
+	// Public routes
+	router.POST("/api/auth/login", loginLimit, handlers.Login)
+	router.POST("/api/auth/refresh", publicLimit, handlers.RefreshToken)
+	router.POST("/api/auth/logout", publicLimit, handlers.Logout)
+	router.POST("/api/auth/forgot-password", loginLimit, handlers.ForgotPassword)
+	router.POST("/api/auth/reset-password", loginLimit, handlers.ResetPassword)
+	router.POST("/api/auth/2fa/verify", loginLimit, handlers.VerifyMFA)
+	router.POST("/api/auth/2fa/enroll", loginLimit, handlers.EnrollMFAChallenge)
+	router.GET("/api/auth/oidc/config", publicLimit, handlers.GetOIDCConfig)
+	router.POST("/api/auth/oidc/authorize", loginLimit, handlers.StartOIDCLogin)
+	router.POST("/api/auth/oidc/callback", loginLimit, handlers.CompleteOIDCLogin)
 
 	// WebSocket route (uses query parameter authentication, not middleware)
-	router.GET("/api/ws", func(c *gin.Context) {
+	router.GET("/api/ws", publicLimit, func(c *gin.Context) {
 		websocket.ServeWS(c.Writer, c.Request)
 	})
 
@@ -61,28 +86,70 @@ func SetupRoutes() *gin.Engine {
 
 	// Authenticated route
 	authed := router.Group("/api")
-	authed.Use(middleware.AuthMiddleware())
+	authed.Use(apiIPLimit, middleware.AuthMiddleware(), apiLimit)
 
 	// User profile route
 	userProfile := authed.Group("/profile")
 	{
 		userProfile.GET("", handlers.GetProfile)
-		userProfile.PUT("/password", handlers.ChangePassword)
+		userProfile.GET("/2fa", handlers.GetMFAStatus)
+		userProfile.GET("/stores", handlers.ListMyStores)
+	}
+	// Credentials cannot be changed in impersonation sessions, even ones that allow writes
+	credentials := userProfile.Group("", middleware.DenyImpersonation())
+	{
+		credentials.PUT("/password", handlers.ChangePassword)
+		credentials.POST("/2fa/enroll", handlers.EnrollMFA)
+		credentials.POST("/2fa/confirm", handlers.ConfirmMFA)
+		credentials.POST("/2fa/recovery-codes", handlers.RegenerateRecoveryCodes)
+		credentials.POST("/2fa/disable", handlers.DisableMFA)
+		credentials.POST("/sso/link", handlers.StartOIDCLink)
+		credentials.POST("/sso/link/callback", handlers.CompleteOIDCLink)
 	}
 
 	// User management route
 	userManagement := authed.Group("/manager/users")
-	userManagement.Use(middleware.ManagerOnly())
+	userManagement.Use(middleware.RequirePermission(models.PermUsersManage))
 	{
 		userManagement.GET("", handlers.ListUsers)
 		userManagement.POST("", handlers.CreateUser)
 		userManagement.PUT("", handlers.UpdateUser)
 		userManagement.DELETE("/:id", handlers.DeleteUser)
+		userManagement.POST("/:id/deactivate", handlers.DeactivateUser)
+		userManagement.POST("/:id/reactivate", handlers.ReactivateUser)
+		userManagement.POST("/:id/reset-password", handlers.ForcePasswordReset)
+		userManagement.DELETE("/:id/2fa", handlers.ResetUserMFA)
+	}
+
+	// Impersonation route ("view as user"; not possible from within an impersonation session)
+	authed.POST("/manager/users/:id/impersonate", middleware.RequirePermission(models.PermUsersImpersonate),
+		middleware.DenyImpersonation(), handlers.ImpersonateUser)
+
+	// Role management route (listing is also needed to assign roles to users and store roles to staff)
+	roleManagement := authed.Group("/manager/roles")
+	{
+		roleManagement.GET("", middleware.RequireAnyPermission(models.PermRolesManage, models.PermUsersManage, models.PermStoresManage), handlers.ListRoles)
+		roleManagement.GET("/permissions", middleware.RequireAnyPermission(models.PermRolesManage, models.PermUsersManage), handlers.ListPermissions)
+		roleManagement.POST("", middleware.RequirePermission(models.PermRolesManage), handlers.CreateRole)
+		roleManagement.PUT("/:name", middleware.RequirePermission(models.PermRolesManage), handlers.UpdateRole)
+		roleManagement.DELETE("/:name", middleware.RequirePermission(models.PermRolesManage), handlers.DeleteRole)
+	}
+
+	// Service account management route (API keys for POS terminals and integrations)
+	serviceAccounts := authed.Group("/manager/service-accounts")
+	serviceAccounts.Use(middleware.RequirePermission(models.PermServiceAccountsManage))
+	{
+		serviceAccounts.GET("", handlers.ListServiceAccounts)
+		serviceAccounts.POST("", handlers.CreateServiceAccount)
+		serviceAccounts.PUT("/:id", handlers.UpdateServiceAccount)
+		serviceAccounts.DELETE("/:id", handlers.DeleteServiceAccount)
+		serviceAccounts.POST("/:id/keys", handlers.CreateAPIKey)
+		serviceAccounts.DELETE("/:id/keys/:keyId", handlers.RevokeAPIKey)
 	}
 
 	// Store & Staff management route
 	storeManagement := authed.Group("/manager/stores")
-	storeManagement.Use(middleware.ManagerOnly())
+	storeManagement.Use(middleware.RequirePermission(models.PermStoresManage))
 	{
 		storeManagement.GET("", handlers.ListStores)
 		storeManagement.POST("", handlers.CreateStore)
@@ -91,44 +158,85 @@ func SetupRoutes() *gin.Engine {
 		{
 			staffManagement.GET("", handlers.ListStoreStaff)
 			staffManagement.POST("", handlers.AddStaffToStore)
+			staffManagement.PUT("/:id", handlers.UpdateStoreStaff)
 			staffManagement.DELETE("/:id", handlers.DeleteStaffFromStore)
 		}
 	}
 
-	// SKU routes (all authenticated users can view)
+	// Store route (staff read the stores they are assigned to; see also /profile/stores)
+	authed.GET("/stores/:id", handlers.GetStore)
+
+	// SKU routes
 	skus := authed.Group("/skus")
+	skus.Use(middleware.RequirePermission(models.PermSKURead))
 	{
 		skus.GET("", handlers.ListSKUs)
 		skus.GET("/categories", handlers.ListSKUCategories)
 		skus.GET("/:id", handlers.GetSKU)
 	}
 
-	// SKU management route (manager only - create, update, delete)
+	// SKU management route (create, update, delete)
 	skuManagement := authed.Group("/manager/skus")
-	skuManagement.Use(middleware.ManagerOnly())
+	skuManagement.Use(middleware.RequirePermission(models.PermSKUManage))
 	{
 		skuManagement.POST("", handlers.CreateSKU)
 		skuManagement.PUT("/:id", handlers.UpdateSKU)
 		skuManagement.DELETE("/:id", handlers.DeleteSKU)
 	}
 
-	// Inventory management route (all authenticated users can query)
+	// Inventory route (without inventory.all_stores, limited to the user's assigned stores;
+	// store roles grant permissions in their store only)
 	inventory := authed.Group("/inventory")
 	{
-		inventory.GET("", handlers.GetInventory)
-		inventory.GET("/:id", handlers.GetInventoryByID)
-		// Adjust endpoint - staff can only adjust their store's inventory
-		inventory.POST("/:id/adjust", handlers.AdjustInventory)
+		inventory.GET("", middleware.RequireStorePermission(models.PermInventoryRead), handlers.GetInventory)
+		inventory.GET("/:id", middleware.RequireStorePermission(models.PermInventoryRead), handlers.GetInventoryByID)
+		inventory.POST("/:id/adjust", middleware.RequireStorePermission(models.PermInventoryAdjust), handlers.AdjustInventory)
 	}
 
-	// Inventory management route (manager only - create, update, delete)
+	// Inventory management route (create, update, delete)
 	inventoryManagement := authed.Group("/manager/inventory")
-	inventoryManagement.Use(middleware.ManagerOnly())
 	{
-		inventoryManagement.POST("", handlers.CreateInventory)
-		inventoryManagement.PUT("/:id", handlers.UpdateInventory)
-		inventoryManagement.DELETE("/:id", handlers.DeleteInventory)
+		inventoryManagement.POST("", middleware.RequireStorePermission(models.PermInventoryManage), handlers.CreateInventory)
+		inventoryManagement.PUT("/:id", middleware.RequireStorePermission(models.PermInventoryUpdate), handlers.UpdateInventory)
+		inventoryManagement.DELETE("/:id", middleware.RequireStorePermission(models.PermInventoryManage), handlers.DeleteInventory)
+	}
+
+	// Report route (served from summary projection tables)
+	reports := authed.Group("/manager/reports")
+	{
+		reports.GET("/stock-summary", middleware.RequirePermission(models.PermReportsRead), handlers.GetStockSummary)
+		reports.POST("/stock-summary/rebuild", middleware.RequirePermission(models.PermReportsRebuild), handlers.RebuildStockSummary)
+	}
+
+	// Audit log route (administrative changes; format=csv exports every matching entry)
+	audit := authed.Group("/manager/audit")
+	audit.Use(middleware.RequirePermission(models.PermAuditRead))
+	{
+		audit.GET("", handlers.ListAuditLog)
+	}
+
+	// Job route (scheduled background jobs)
+	jobs := authed.Group("/manager/jobs")
+	jobs.Use(middleware.RequirePermission(models.PermSystemMonitor))
+	{
+		jobs.GET("", handlers.ListJobs)
+	}
+
+	// Cache route (per-instance hit ratios)
+	cacheStats := authed.Group("/manager/cache")
+	cacheStats.Use(middleware.RequirePermission(models.PermSystemMonitor))
+	{
+		cacheStats.GET("/stats", handlers.GetCacheStats)
 	}
 
 	return router
 }
+
+// trustProxies makes client IPs (rate limits, audit log) come from X-Forwarded-For only when a
+// listed proxy sent the request; Gin would otherwise believe the header from anyone
+func trustProxies(router *gin.Engine, proxies []string) {
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		log.Printf("Warning: invalid TRUSTED_PROXIES: %v (ignoring X-Forwarded-For)", err)
+		router.SetTrustedProxies(nil)
+	}
+}
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"inventory-manager-server/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitKey returns the rate limit key of a request from remoteAddr with an X-Forwarded-For header
+func rateLimitKey(t *testing.T, proxies []string, remoteAddr, forwardedFor string) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	trustProxies(router, proxies)
+	var key string
+	router.GET("/", func(c *gin.Context) { key = middleware.KeyByIP(c) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	return key
+}
+
+func TestSpoofedForwardedForKeepsRateLimitKey(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		proxies []string
+		remote  string
+		want    string
+	}{
+		{"no trusted proxies", nil, "203.0.113.7:4321", "ip:203.0.113.7"},
+		{"client is not a trusted proxy", []string{"10.0.0.0/8"}, "203.0.113.7:4321", "ip:203.0.113.7"},
+		{"invalid trusted proxies", []string{"not-an-ip"}, "203.0.113.7:4321", "ip:203.0.113.7"},
+		{"request through a trusted proxy", []string{"10.0.0.0/8"}, "10.1.2.3:4321", "ip:198.51.100.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := rateLimitKey(t, tc.proxies, tc.remote, "198.51.100.1"); got != tc.want {
+				t.Errorf("rate limit key = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes the run times of a job
+type Schedule interface {
+	// Next returns the first run time strictly after t
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a schedule spec:
+//
+//	"*/15 * * * *"  standard 5-field cron (minute hour day-of-month month day-of-week)
+//	"@every 2s"     fixed interval
+//	"@hourly", "@daily", "@weekly", "@monthly"
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if interval, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid interval in %q", spec)
+		}
+		return everySchedule(d), nil
+	}
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron spec %q must have 5 fields", spec)
+	}
+	var c cronSchedule
+	var err error
+	if c.minute, _, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, _, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.dom, c.domAny, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, _, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.dow, c.dowAny, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// 7 is an alias for Sunday
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// everySchedule runs at a fixed interval
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cronSchedule holds one bit per allowed value of each field
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+func (c cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{} // Never matches (e.g. 31 February)
+}
+
+// dayMatches applies cron's rule: if both day fields are restricted, either may match
+func (c cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// parseField parses a comma-separated list of values, ranges (a-b) and steps (*/n, a-b/n)
+// It reports whether the field was "*"
+func parseField(field string, min, max int) (uint64, bool, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, false, fmt.Errorf("invalid step in cron field %q", field)
+			}
+			step = n
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			lowStr, highStr, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowStr); err != nil {
+				return 0, false, fmt.Errorf("invalid value in cron field %q", field)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highStr); err != nil {
+					return 0, false, fmt.Errorf("invalid range in cron field %q", field)
+				}
+			} else if hasStep {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, false, fmt.Errorf("cron field %q out of range %d-%d", field, min, max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, field == "*", nil
+}
@@ -0,0 +1,222 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// Jobs are registered by every pod; the schedule and lease live in the scheduled_jobs
+// table, so each occurrence is claimed by exactly one pod. Claiming a run advances
+// next_run_at and increments the job's fencing token in one UPDATE; the run's outcome
+// is only recorded while its token is still current, so a pod that lost its lease
+// (e.g. paused past the lease) cannot overwrite a newer run.
+
+// pollInterval is how often each pod looks for due jobs
+const pollInterval = time.Second
+
+// Job is a named periodic task
+type Job struct {
+	Name     string
+	Schedule string        // See ParseSchedule
+	Timeout  time.Duration // Lease length; the run's context is cancelled when it expires
+	Run      func(ctx context.Context, run Run) error
+}
+
+// Run identifies one claimed execution of a job
+type Run struct {
+	FencingToken int64
+}
+
+type registeredJob struct {
+	Job
+	schedule Schedule
+}
+
+var (
+	mu   sync.Mutex
+	jobs = make(map[string]*registeredJob)
+)
+
+// Register adds a job to the scheduler (before Start)
+func Register(job Job) error {
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = time.Minute
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, exists := jobs[job.Name]; exists {
+		return fmt.Errorf("job %s already registered", job.Name)
+	}
+	jobs[job.Name] = &registeredJob{Job: job, schedule: schedule}
+	return nil
+}
+
+// Start runs due jobs until ctx is cancelled, then waits for running jobs to return
+func Start(ctx context.Context) {
+	if database.DB == nil {
+		log.Println("Warning: database not initialized, job scheduler disabled")
+		return
+	}
+	if err := syncJobs(); err != nil {
+		log.Printf("Warning: failed to register scheduled jobs: %v (scheduler disabled)", err)
+		return
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var running sync.WaitGroup
+	log.Printf("Job scheduler started with %d jobs", len(jobs))
+	for {
+		select {
+		case <-ticker.C:
+			runDueJobs(ctx, &running)
+		case <-ctx.Done():
+			running.Wait()
+			log.Println("Job scheduler stopped")
+			return
+		}
+	}
+}
+
+// syncJobs creates the rows of newly registered jobs and updates changed schedules
+func syncJobs() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	for _, job := range jobs {
+		row := models.ScheduledJob{Name: job.Name, Schedule: job.Job.Schedule, NextRunAt: now}
+		if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		// A changed schedule takes effect from now
+		err := database.DB.Model(&models.ScheduledJob{}).
+			Where("name = ? AND schedule <> ?", job.Name, job.Job.Schedule).
+			Updates(map[string]interface{}{"schedule": job.Job.Schedule, "next_run_at": job.schedule.Next(now)}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runDueJobs claims and starts every due job that is not leased by another run
+func runDueJobs(ctx context.Context, running *sync.WaitGroup) {
+	now := time.Now()
+	var due []string
+	err := database.DB.Model(&models.ScheduledJob{}).
+		Where("next_run_at <= ? AND (lease_until IS NULL OR lease_until < ?)", now, now).
+		Pluck("name", &due).Error
+	if err != nil {
+		log.Printf("Warning: failed to query due jobs: %v", err)
+		return
+	}
+
+	for _, name := range due {
+		mu.Lock()
+		job, ok := jobs[name]
+		mu.Unlock()
+		if !ok {
+			continue // Registered by another version of the server
+		}
+		token, claimed, err := claim(job, now)
+		if err != nil {
+			log.Printf("Warning: failed to claim job %s: %v", name, err)
+			continue
+		}
+		if !claimed {
+			continue // Another pod got it
+		}
+
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			execute(ctx, job, Run{FencingToken: token})
+		}()
+	}
+}
+
+// claim takes the lease of a due run; it returns the run's fencing token
+func claim(job *registeredJob, now time.Time) (int64, bool, error) {
+	var tokens []int64
+	err := database.DB.Raw(`
+		UPDATE scheduled_jobs
+		SET owner = ?, fencing_token = fencing_token + 1, lease_until = ?, next_run_at = ?, last_started_at = ?
+		WHERE name = ? AND next_run_at <= ? AND (lease_until IS NULL OR lease_until < ?)
+		RETURNING fencing_token`,
+		config.CONFIG.InstanceID, now.Add(job.Timeout), job.schedule.Next(now), now,
+		job.Name, now, now,
+	).Scan(&tokens).Error
+	if err != nil || len(tokens) == 0 {
+		return 0, false, err
+	}
+	return tokens[0], true, nil
+}
+
+// execute runs a claimed job and records its outcome if the lease is still ours
+func execute(ctx context.Context, job *registeredJob, run Run) {
+	runCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+
+	status, message := "succeeded", ""
+	if err := job.Run(runCtx, run); err != nil {
+		status, message = "failed", err.Error()
+		log.Printf("Job %s failed: %v", job.Name, err)
+	}
+
+	result := database.DB.Model(&models.ScheduledJob{}).
+		Where("name = ? AND fencing_token = ?", job.Name, run.FencingToken).
+		Updates(map[string]interface{}{
+			"lease_until":      nil,
+			"last_finished_at": time.Now(),
+			"last_status":      status,
+			"last_error":       message,
+		})
+	if result.Error != nil {
+		log.Printf("Warning: failed to record run of job %s: %v", job.Name, result.Error)
+	} else if result.RowsAffected == 0 {
+		log.Printf("Warning: job %s lost its lease (token %d) before finishing", job.Name, run.FencingToken)
+	}
+}
+
+// ListJobs returns the state of every scheduled job
+func ListJobs() ([]dto.JobResponse, error) {
+	var rows []models.ScheduledJob
+	if err := database.DB.Order("name").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query scheduled jobs: %w", err)
+	}
+
+	now := time.Now()
+	items := make([]dto.JobResponse, len(rows))
+	for i, row := range rows {
+		items[i] = dto.JobResponse{
+			Name:           row.Name,
+			Schedule:       row.Schedule,
+			Owner:          row.Owner,
+			Running:        row.LeaseUntil != nil && row.LeaseUntil.After(now),
+			FencingToken:   row.FencingToken,
+			LastStartedAt:  row.LastStartedAt,
+			LastFinishedAt: row.LastFinishedAt,
+			LastStatus:     row.LastStatus,
+			LastError:      row.LastError,
+			NextRunAt:      row.NextRunAt,
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidAuditFilter is returned for malformed audit log filters
+var ErrInvalidAuditFilter = errors.New("invalid audit log filter")
+
+// auditPurgeBatchSize is the number of audit log rows deleted per retention statement
+const auditPurgeBatchSize = 5000
+
+// Actor identifies who made a change (a user or a service account) and the request it was made
+// in. Changes made outside a request, such as just-in-time provisioning, have no client IP or
+// request ID.
+type Actor struct {
+	ID        uuid.UUID
+	Name      string
+	ClientIP  string
+	RequestID string
+}
+
+// recordAudit appends the audit log entry of an entity change within tx
+func recordAudit(tx *gorm.DB, change EntityChange, diff models.JSON) error {
+	entry := models.AuditLog{
+		ActorID:    change.Actor.ID,
+		ActorName:  change.Actor.Name,
+		Action:     change.EntityType + "." + change.OperationType,
+		TargetType: change.EntityType,
+		TargetID:   change.EntityID,
+		TargetName: auditTargetName(change),
+		Diff:       diff,
+		ClientIP:   change.Actor.ClientIP,
+		RequestID:  change.Actor.RequestID,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to create audit log entry: %w", err)
+	}
+	return nil
+}
+
+// recordAuditEvent appends the audit log entry of an event that changes no entity, such as an
+// impersonation, within tx; the fields of change.After are recorded as new values
+func recordAuditEvent(tx *gorm.DB, change EntityChange) error {
+	diff, err := BuildDiff(nil, change.After)
+	if err != nil {
+		return err
+	}
+	return recordAudit(tx, change, diff)
+}
+
+// auditTargetName returns a readable name of the changed entity from its snapshots
+func auditTargetName(change EntityChange) string {
+	switch change.EntityType {
+	case models.EntitySKU:
+		return change.SKUName
+	case models.EntityStore:
+		return change.StoreName
+	}
+	for _, snapshot := range []map[string]interface{}{change.After, change.Before} {
+		for _, field := range []string{"username", "name"} {
+			if name, ok := snapshot[field].(string); ok && name != "" {
+				if change.EntityType == models.EntityStoreUser && change.StoreName != "" {
+					return name + " @ " + change.StoreName
+				}
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// AuditService searches, exports and purges the audit log
+type AuditService struct {
+	// Using global database instance
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService() *AuditService {
+	return &AuditService{}
+}
+
+// Search returns one page of audit log entries matching the filters, newest first
+func (s *AuditService) Search(params dto.AuditQueryParams) (*dto.AuditListResponse, error) {
+	query, err := s.filter(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count audit log entries: %w", err)
+	}
+
+	var entries []models.AuditLog
+	offset := (params.Page - 1) * params.PageSize
+	if err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(params.PageSize).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+
+	items := make([]dto.AuditLogResponse, len(entries))
+	for i, entry := range entries {
+		items[i] = auditLogResponse(entry)
+	}
+	return &dto.AuditListResponse{
+		Items:      items,
+		Total:      total,
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: int((total + int64(params.PageSize) - 1) / int64(params.PageSize)),
+	}, nil
+}
+
+// ExportCSV writes every audit log entry matching the filters to w as CSV, newest first.
+// Paging parameters are ignored.
+func (s *AuditService) ExportCSV(ctx context.Context, params dto.AuditQueryParams, w io.Writer) error {
+	query, err := s.filter(params)
+	if err != nil {
+		return err
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"time", "actor_id", "actor", "action", "target_type", "target_id", "target", "changes", "client_ip", "request_id"}); err != nil {
+		return err
+	}
+
+	rows, err := query.WithContext(ctx).Order("created_at DESC, id DESC").Rows()
+	if err != nil {
+		return fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry models.AuditLog
+		if err := database.DB.ScanRows(rows, &entry); err != nil {
+			return fmt.Errorf("failed to read audit log entry: %w", err)
+		}
+		record := []string{
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			entry.ActorID.String(),
+			entry.ActorName,
+			entry.Action,
+			entry.TargetType,
+			entry.TargetID.String(),
+			entry.TargetName,
+			string(entry.Diff),
+			entry.ClientIP,
+			entry.RequestID,
+		}
+		for i := range record {
+			record[i] = csvSafe(record[i])
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// PurgeAuditLog deletes audit log entries older than AUDIT_RETENTION (0 keeps them forever).
+// Rows are deleted in batches, each in a transaction that lifts the append-only trigger.
+func (s *AuditService) PurgeAuditLog(ctx context.Context) error {
+	retention := config.CONFIG.AuditRetention
+	if retention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-retention)
+
+	var purged int64
+	for ctx.Err() == nil {
+		var deleted int64
+		err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT set_config(?, 'on', true)", database.AuditPurgeSetting).Error; err != nil {
+				return err
+			}
+			result := tx.Exec(`DELETE FROM audit_log WHERE id IN (
+				SELECT id FROM audit_log WHERE created_at < ? LIMIT ?)`, cutoff, auditPurgeBatchSize)
+			deleted = result.RowsAffected
+			return result.Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to purge audit log: %w", err)
+		}
+		purged += deleted
+		if deleted < auditPurgeBatchSize {
+			break
+		}
+	}
+	if purged > 0 {
+		log.Printf("Purged %d audit log entries older than %s", purged, retention)
+	}
+	return nil
+}
+
+// filter builds the audit log query for the given filters
+func (s *AuditService) filter(params dto.AuditQueryParams) (*gorm.DB, error) {
+	query := database.DB.Model(&models.AuditLog{})
+	if params.ActorID != "" {
+		actorID, err := uuid.Parse(params.ActorID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid actor_id", ErrInvalidAuditFilter)
+		}
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if params.Actor != "" {
+		query = query.Where("actor_name ILIKE ?", "%"+escapeLike(params.Actor)+"%")
+	}
+	if params.Action != "" {
+		if strings.Contains(params.Action, ".") {
+			query = query.Where("action = ?", params.Action)
+		} else {
+			query = query.Where("target_type = ?", params.Action)
+		}
+	}
+	if params.TargetType != "" {
+		query = query.Where("target_type = ?", params.TargetType)
+	}
+	if params.TargetID != "" {
+		targetID, err := uuid.Parse(params.TargetID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid target_id", ErrInvalidAuditFilter)
+		}
+		query = query.Where("target_id = ?", targetID)
+	}
+	if params.RequestID != "" {
+		query = query.Where("request_id = ?", params.RequestID)
+	}
+	if params.From != nil {
+		query = query.Where("created_at >= ?", *params.From)
+	}
+	if params.To != nil {
+		query = query.Where("created_at <= ?", *params.To)
+	}
+	return query, nil
+}
+
+func auditLogResponse(entry models.AuditLog) dto.AuditLogResponse {
+	return dto.AuditLogResponse{
+		ID:         entry.ID,
+		ActorID:    entry.ActorID,
+		ActorName:  entry.ActorName,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		TargetName: entry.TargetName,
+		Diff:       entry.Diff,
+		ClientIP:   entry.ClientIP,
+		RequestID:  entry.RequestID,
+		CreatedAt:  entry.CreatedAt,
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// csvSafe prefixes values that spreadsheet applications would evaluate as formulas
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// AnonymizeUser replaces a user's name with pseudonym in the audit log within tx: entries they
+// made lose their name and client IP, and entries about them (or their store assignments) lose
+// their name and the personal fields of their diffs
+func (s *AuditService) AnonymizeUser(tx *gorm.DB, userID uuid.UUID, pseudonym string) error {
+	if err := tx.Exec("SELECT set_config(?, 'on', true)", database.AuditAnonymizeSetting).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec(`UPDATE audit_log SET actor_name = ?, client_ip = NULL WHERE actor_id = ?`,
+		pseudonym, userID).Error; err != nil {
+		return fmt.Errorf("failed to anonymize audit log actor: %w", err)
+	}
+	if err := tx.Exec(`UPDATE audit_log SET target_name = ?, diff = diff - 'username' - 'email' - 'deactivation_reason'
+		WHERE target_type = ? AND target_id = ?`, pseudonym, models.EntityUser, userID).Error; err != nil {
+		return fmt.Errorf("failed to anonymize audit log targets: %w", err)
+	}
+	// Store assignment names are "<username> @ <store>"; only their creation and deletion
+	// entries carry the user ID
+	if err := tx.Exec(`UPDATE audit_log
+		SET target_name = CASE WHEN position(' @ ' IN target_name) > 0
+			THEN ? || substring(target_name FROM position(' @ ' IN target_name)) ELSE ? END,
+			diff = diff - 'username'
+		WHERE target_type = ? AND target_id IN (
+			SELECT target_id FROM audit_log WHERE target_type = ?
+			AND (diff -> 'user_id' ->> 'old' = ? OR diff -> 'user_id' ->> 'new' = ?))`,
+		pseudonym, pseudonym, models.EntityStoreUser, models.EntityStoreUser, userID.String(), userID.String()).Error; err != nil {
+		return fmt.Errorf("failed to anonymize audit log store assignments: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+	"inventory-manager-server/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationChannel carries the IDs of users whose tokens were revoked, so every
+// instance can close their WebSocket connections
+const RevocationChannel = "auth:revoked"
+
+// tokenVersionTTL bounds how long a cached token version is trusted
+const tokenVersionTTL = 10 * time.Minute
+
+// deletedTokenVersion is cached for deleted users; no token carries it
+const deletedTokenVersion = -1
+
+// refreshReuseGrace is how long after rotation a reused refresh token is treated as a benign
+// concurrent refresh (e.g. two tabs) rather than theft
+const refreshReuseGrace = 10 * time.Second
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrSessionRevoked is returned for access tokens issued before a revocation
+	ErrSessionRevoked = errors.New("session revoked")
+)
+
+// AuthService issues, rotates and revokes access and refresh tokens
+type AuthService struct {
+	// Using global database and cache instances
+}
+
+// NewAuthService creates a new auth service
+func NewAuthService() *AuthService {
+	return &AuthService{}
+}
+
+func tokenVersionKey(userID uuid.UUID) string {
+	return "auth:tv:" + userID.String()
+}
+
+// hashToken returns the stored form of a refresh token, password reset token or recovery code
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueTokens creates an access token and starts a new refresh token family for user
+func (s *AuthService) IssueTokens(user models.User, rememberMe bool) (*dto.LoginResponse, error) {
+	var response *dto.LoginResponse
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		response, _, err = s.issue(tx, user, rememberMe, uuid.New())
+		return err
+	})
+	return response, err
+}
+
+// issue creates an access token and a refresh token in the given family
+func (s *AuthService) issue(tx *gorm.DB, user models.User, rememberMe bool, familyID uuid.UUID) (*dto.LoginResponse, *models.RefreshToken, error) {
+	if !user.Active() {
+		return nil, nil, ErrUserDeactivated
+	}
+	permissions, err := NewRoleService().Permissions(user.Role)
+	if err != nil {
+		return nil, nil, err
+	}
+	stores, err := NewStoreService().AssignedStores(user.ID, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessToken, err := utils.GenerateToken(user.ID, user.Username, user.Email, user.Role, user.TokenVersion, config.CONFIG.AccessTokenTTL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	rawRefreshToken := base64.RawURLEncoding.EncodeToString(secret)
+
+	ttl := config.CONFIG.RefreshTokenTTL
+	if rememberMe {
+		ttl = config.CONFIG.RefreshTokenRememberTTL
+	}
+	refreshToken := models.RefreshToken{
+		UserID:     user.ID,
+		FamilyID:   familyID,
+		TokenHash:  hashToken(rawRefreshToken),
+		RememberMe: rememberMe,
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+	if err := tx.Create(&refreshToken).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &dto.LoginResponse{
+		Token:        accessToken,
+		RefreshToken: rawRefreshToken,
+		ExpiresIn:    int(config.CONFIG.AccessTokenTTL.Seconds()),
+		User: dto.UserResponse{
+			ID:          user.ID,
+			Username:    user.Username,
+			Email:       user.Email,
+			Role:        user.Role,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+			Permissions: permissions.Names(),
+		},
+		Stores: stores,
+	}, &refreshToken, nil
+}
+
+// Refresh rotates a refresh token and issues a new access token with the user's current role.
+// Reusing an already rotated token revokes its whole family.
+func (s *AuthService) Refresh(rawRefreshToken string) (*dto.LoginResponse, error) {
+	var response *dto.LoginResponse
+	reused := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the token so concurrent refreshes with it are serialised
+		var current models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashToken(rawRefreshToken)).First(&current).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if current.RevokedAt != nil {
+			reused = current.ReplacedBy == nil || time.Since(*current.RevokedAt) > refreshReuseGrace
+			return ErrInvalidRefreshToken
+		}
+		if time.Now().After(current.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		var user models.User
+		if err := tx.First(&user, "id = ?", current.UserID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if !user.Active() {
+			return ErrInvalidRefreshToken
+		}
+
+		var next *models.RefreshToken
+		var err error
+		response, next, err = s.issue(tx, user, current.RememberMe, current.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.RefreshToken{}).Where("id = ?", current.ID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by": next.ID}).Error
+	})
+
+	if reused {
+		log.Printf("Warning: rotated refresh token reused, revoking its family")
+		if err := s.revokeFamily(rawRefreshToken); err != nil {
+			log.Printf("Warning: failed to revoke refresh token family: %v", err)
+		}
+	}
+	return response, err
+}
+
+// revokeFamily revokes every refresh token in the family of rawRefreshToken
+func (s *AuthService) revokeFamily(rawRefreshToken string) error {
+	return database.DB.Model(&models.RefreshToken{}).
+		Where("family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = ?) AND revoked_at IS NULL", hashToken(rawRefreshToken)).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Logout revokes the session of rawRefreshToken, or every session of its user if all is set
+func (s *AuthService) Logout(rawRefreshToken string, all bool) error {
+	var token models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", hashToken(rawRefreshToken)).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+
+	if !all {
+		return s.revokeFamily(rawRefreshToken)
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return s.RevokeUserTokens(tx, token.UserID)
+	}); err != nil {
+		return err
+	}
+	s.PublishRevocation(token.UserID)
+	return nil
+}
+
+// PurgeRefreshTokens deletes refresh tokens that expired more than refreshReuseGrace ago
+func (s *AuthService) PurgeRefreshTokens(ctx context.Context) error {
+	result := database.DB.WithContext(ctx).
+		Where("expires_at < ?", time.Now().Add(-refreshReuseGrace)).
+		Delete(&models.RefreshToken{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to purge refresh tokens: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Purged %d expired refresh tokens", result.RowsAffected)
+	}
+	return nil
+}
+
+// RevokeUserTokens invalidates every access and refresh token of a user within tx.
+// Call PublishRevocation after the transaction commits.
+func (s *AuthService) RevokeUserTokens(tx *gorm.DB, userID uuid.UUID) error {
+	if err := s.BumpTokenVersion(tx, userID); err != nil {
+		return err
+	}
+	return tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// BumpTokenVersion invalidates every access token of a user within tx (refresh tokens stay
+// valid, so clients obtain a new access token reflecting the user's current role).
+// Call PublishRevocation after the transaction commits.
+func (s *AuthService) BumpTokenVersion(tx *gorm.DB, userID uuid.UUID) error {
+	return tx.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+}
+
+// PublishRevocation makes a committed revocation effective on every instance: it refreshes
+// the cached token version and closes the user's WebSocket connections
+func (s *AuthService) PublishRevocation(userID uuid.UUID) {
+	version := deletedTokenVersion
+	var user models.User
+	if err := database.DB.Select("token_version").First(&user, "id = ?", userID).Error; err == nil {
+		version = user.TokenVersion
+	}
+
+	// Overwrite rather than delete, so a reader that loaded the old version cannot cache it again
+	if err := cache.Set(tokenVersionKey(userID), version, tokenVersionTTL); err != nil {
+		cache.Delete(tokenVersionKey(userID))
+	}
+	if err := cache.Default.Publish(RevocationChannel, userID.String()); err != nil {
+		log.Printf("Warning: failed to publish token revocation for user %s: %v", userID, err)
+	}
+}
+
+// ValidateClaims rejects access tokens issued before the user's tokens were revoked; revoking
+// the impersonator's tokens also ends their impersonation sessions
+func (s *AuthService) ValidateClaims(claims *utils.JWTClaims) error {
+	version, err := s.currentTokenVersion(claims.UserID)
+	if err != nil {
+		return err
+	}
+	if claims.TokenVersion != version {
+		return ErrSessionRevoked
+	}
+	if claims.Impersonator != nil {
+		version, err := s.currentTokenVersion(claims.Impersonator.UserID)
+		if err != nil {
+			return err
+		}
+		if claims.Impersonator.TokenVersion != version {
+			return ErrSessionRevoked
+		}
+	}
+	return nil
+}
+
+// currentTokenVersion returns the user's token version from cache, falling back to the database
+func (s *AuthService) currentTokenVersion(userID uuid.UUID) (int, error) {
+	if cached, err := cache.Get(tokenVersionKey(userID)); err == nil {
+		if version, err := strconv.Atoi(cached); err == nil {
+			return version, nil
+		}
+	}
+
+	version := deletedTokenVersion
+	var user models.User
+	if err := database.DB.Select("token_version").First(&user, "id = ?", userID).Error; err == nil {
+		version = user.TokenVersion
+	} else if err != gorm.ErrRecordNotFound {
+		return 0, fmt.Errorf("failed to load token version: %w", err)
+	}
+	// SetNX: never overwrite a version written by a concurrent revocation
+	cache.SetNX(tokenVersionKey(userID), strconv.Itoa(version), tokenVersionTTL)
+	return version, nil
+}
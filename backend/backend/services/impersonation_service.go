@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+	"inventory-manager-server/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrImpersonateSelf is returned when a user tries to impersonate themselves
+	ErrImpersonateSelf = errors.New("cannot impersonate yourself")
+	// ErrImpersonationEscalation is returned when the impersonated user's role, or a store role
+	// of their active assignments, has permissions the impersonator's role lacks
+	ErrImpersonationEscalation = errors.New("user has permissions the impersonator lacks")
+)
+
+// ImpersonationService issues impersonation tokens, with which managers view the application
+// as another user (with their role and store assignments) to reproduce what they see. Starting
+// a session and every request made in it are recorded in the audit log.
+type ImpersonationService struct {
+	// Using global database instance
+}
+
+// NewImpersonationService creates a new impersonation service
+func NewImpersonationService() *ImpersonationService {
+	return &ImpersonationService{}
+}
+
+// Start issues an impersonation token for the target user on behalf of actor, whose role has
+// actorPermissions. The session is read-only unless writes are requested.
+func (s *ImpersonationService) Start(actor Actor, actorPermissions PermissionSet, targetID uuid.UUID, req dto.ImpersonateUserRequest) (*dto.ImpersonationResponse, error) {
+	if targetID == actor.ID {
+		return nil, ErrImpersonateSelf
+	}
+
+	var impersonator, user models.User
+	if err := database.DB.First(&impersonator, "id = ?", actor.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load impersonator: %w", err)
+	}
+	if err := database.DB.First(&user, "id = ?", targetID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if !user.Active() {
+		return nil, ErrUserDeactivated
+	}
+
+	// Impersonation must not grant the impersonator anything their own role does not, neither
+	// through the user's role nor through the store roles the user holds in their stores
+	permissions, err := NewRoleService().Permissions(user.Role)
+	if err != nil {
+		return nil, err
+	}
+	storePermissions, err := NewStoreAssignmentService().storeRolePermissions(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, set := range []PermissionSet{permissions, storePermissions} {
+		for permission := range set {
+			if !actorPermissions.Has(permission) {
+				return nil, ErrImpersonationEscalation
+			}
+		}
+	}
+
+	readOnly := !req.AllowWrites
+	ttl := config.CONFIG.ImpersonationTTL
+	expiresAt := time.Now().Add(ttl)
+	token, err := utils.GenerateImpersonationToken(user.ID, user.Username, user.Email, user.Role, user.TokenVersion,
+		utils.ImpersonatorClaims{UserID: impersonator.ID, Username: impersonator.Username, TokenVersion: impersonator.TokenVersion},
+		readOnly, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate impersonation token: %w", err)
+	}
+
+	if err := recordAuditEvent(database.DB, EntityChange{
+		EntityType:    models.EntityUser,
+		EntityID:      user.ID,
+		OperationType: "impersonate",
+		After: map[string]interface{}{
+			"username":   user.Username,
+			"read_only":  readOnly,
+			"reason":     req.Reason,
+			"expires_at": expiresAt.UTC().Format(time.RFC3339),
+		},
+		Actor: actor,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &dto.ImpersonationResponse{
+		Token:     token,
+		ExpiresIn: int(ttl.Seconds()),
+		User: dto.UserResponse{
+			ID:          user.ID,
+			Username:    user.Username,
+			Email:       user.Email,
+			Role:        user.Role,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+			Permissions: permissions.Names(),
+			Impersonation: &dto.ImpersonationInfo{
+				ImpersonatorID:       impersonator.ID,
+				ImpersonatorUsername: impersonator.Username,
+				ReadOnly:             readOnly,
+				ExpiresAt:            expiresAt,
+			},
+		},
+	}, nil
+}
+
+// RecordRequest appends the audit log entry of a request made in an impersonation session;
+// actor is the impersonator
+func (s *ImpersonationService) RecordRequest(actor Actor, userID uuid.UUID, username, method, path string, status int) error {
+	return recordAuditEvent(database.DB, EntityChange{
+		EntityType:    models.EntityUser,
+		EntityID:      userID,
+		OperationType: "impersonated_request",
+		After: map[string]interface{}{
+			"username": username,
+			"method":   method,
+			"path":     path,
+			"status":   status,
+		},
+		Actor: actor,
+	})
+}
@@ -1,9 +1,9 @@
 package services
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"sort"
 	"strconv"
 	"strings"
@@ -17,6 +17,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // InventoryService handles inventory operations
@@ -30,48 +31,48 @@ func NewInventoryService() *InventoryService {
 }
 
 // GetInventory gets inventory with caching, filtering, sorting, and pagination
-// userID and userRole are used to determine allowed stores for staff members
-func (s *InventoryService) GetInventory(params dto.InventoryQueryParams, storeID *uuid.UUID, skuID *uuid.UUID, userID *uuid.UUID, userRole string) (*dto.InventoryListResponse, error) {
+// The query is limited to the stores in scope; it returns ErrStoreForbidden if storeID is outside the scope
+func (s *InventoryService) GetInventory(scope StoreScope, params dto.InventoryQueryParams, storeID *uuid.UUID, skuID *uuid.UUID) (*dto.InventoryListResponse, error) {
+	if storeID != nil {
+		if err := scope.CheckStore(*storeID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Build cache key
-	cacheKey := s.buildCacheKey(params, storeID, skuID, userID, userRole)
-
-	// Try to get from cache
-	if cache.Client != nil {
-		cached, err := cache.Get(cacheKey)
-		if err == nil && cached != "" {
-			var result dto.InventoryListResponse
-			if err := json.Unmarshal([]byte(cached), &result); err == nil {
-				return &result, nil
-			}
+	cacheKey, cacheable := s.buildCacheKey(scope, params, storeID, skuID)
+	if !cacheable {
+		return s.queryInventory(scope, params, storeID, skuID)
+	}
+
+	// Concurrent misses for the same key share a single database query
+	cached, err := cache.GetOrLoad(cacheKey, 5*time.Minute, func() (string, error) {
+		result, err := s.queryInventory(scope, params, storeID, skuID)
+		if err != nil {
+			return "", err
 		}
+		data, err := json.Marshal(result)
+		return string(data), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result dto.InventoryListResponse
+	if err := json.Unmarshal([]byte(cached), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode cached inventory: %w", err)
 	}
+	return &result, nil
+}
 
-	// Build query
-	query := database.DB.Model(&models.Inventory{})
+// queryInventory loads one page of inventory from the database
+func (s *InventoryService) queryInventory(scope StoreScope, params dto.InventoryQueryParams, storeID *uuid.UUID, skuID *uuid.UUID) (*dto.InventoryListResponse, error) {
+	// Build query (limited to the stores in scope)
+	query := scope.Inventory(database.DB)
 
 	// Apply filters
-	// StoreID: nil means all stores (for manager) or all assigned stores (for staff), non-nil means specific store
+	// StoreID: nil means every store in scope, non-nil means specific store
 	if storeID != nil {
 		query = query.Where("store_id = ?", *storeID)
-	} else if userRole == "staff" && userID != nil {
-		// For staff without specific store_id, query their allowed stores
-		var storeUsers []models.StoreUser
-		if err := database.DB.Where("user_id = ?", *userID).Find(&storeUsers).Error; err == nil && len(storeUsers) > 0 {
-			storeIDs := make([]uuid.UUID, len(storeUsers))
-			for i, su := range storeUsers {
-				storeIDs[i] = su.StoreID
-			}
-			query = query.Where("store_id IN ?", storeIDs)
-		} else {
-			// Staff has no stores, return empty result
-			return &dto.InventoryListResponse{
-				Items:      []dto.InventoryResponse{},
-				Total:      0,
-				Page:       params.Page,
-				PageSize:   params.PageSize,
-				TotalPages: 0,
-			}, nil
-		}
 	}
 	// SKUID: nil means all SKUs, non-nil means specific SKU
 	// When SKUID is nil, return all SKUs' inventory for the specified store(s)
@@ -160,21 +161,14 @@ func (s *InventoryService) GetInventory(params dto.InventoryQueryParams, storeID
 		TotalPages: totalPages,
 	}
 
-	// Cache the result
-	if cache.Client != nil {
-		if data, err := json.Marshal(result); err == nil {
-			cache.Set(cacheKey, string(data), 5*time.Minute)
-		}
-	}
-
 	return result, nil
 }
 
-// GetInventoryByID gets a single inventory by ID
-func (s *InventoryService) GetInventoryByID(id uuid.UUID) (*dto.InventoryResponse, error) {
+// GetInventoryByID gets a single inventory by ID; inventory outside the scope is reported as not found
+func (s *InventoryService) GetInventoryByID(scope StoreScope, id uuid.UUID) (*dto.InventoryResponse, error) {
 	var inventory models.Inventory
-	if err := database.DB.Preload("SKU").Preload("Store").
-		First(&inventory, "id = ?", id).Error; err != nil {
+	if err := scope.Inventory(database.DB).Preload("SKU").Preload("Store").
+		First(&inventory, "inventory.id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("inventory not found")
 		}
@@ -217,11 +211,15 @@ func (s *InventoryService) GetInventoryByID(id uuid.UUID) (*dto.InventoryRespons
 	return response, nil
 }
 
-// CreateInventory creates a new inventory record
-func (s *InventoryService) CreateInventory(req dto.CreateInventoryRequest, userID uuid.UUID, userName string) (*dto.InventoryResponse, error) {
+// CreateInventory creates a new inventory record; it returns ErrStoreForbidden if the store is outside the scope
+func (s *InventoryService) CreateInventory(scope StoreScope, req dto.CreateInventoryRequest, userID uuid.UUID, userName string) (*dto.InventoryResponse, error) {
+	if err := scope.CheckStore(req.StoreID); err != nil {
+		return nil, err
+	}
+
 	// Check if inventory already exists
 	var existing models.Inventory
-	if err := database.DB.Where("sku_id = ? AND store_id = ?", req.SKUID, req.StoreID).
+	if err := scope.Inventory(database.DB).Where("sku_id = ? AND store_id = ?", req.SKUID, req.StoreID).
 		First(&existing).Error; err == nil {
 		return nil, fmt.Errorf("inventory already exists for this SKU and store")
 	} else if err != gorm.ErrRecordNotFound {
@@ -261,9 +259,16 @@ func (s *InventoryService) CreateInventory(req dto.CreateInventoryRequest, userI
 		}
 
 		// Create outbox record
+		diff, err := BuildDiff(nil, map[string]interface{}{"quantity": req.Quantity})
+		if err != nil {
+			return err
+		}
 		outbox := models.Outbox{
 			OperationType:    "create",
 			SenderInstanceID: config.CONFIG.InstanceID,
+			EntityType:       models.EntityInventory,
+			EntityID:         inventory.ID,
+			Diff:             diff,
 			InventoryID:      inventory.ID,
 			SKUID:            req.SKUID,
 			SKUName:          sku.Name,
@@ -287,7 +292,7 @@ func (s *InventoryService) CreateInventory(req dto.CreateInventoryRequest, userI
 	}
 
 	// Delete cache
-	s.invalidateCache(req.StoreID, nil)
+	s.invalidateCache(req.StoreID)
 
 	// Return response
 	response := &dto.InventoryResponse{
@@ -304,16 +309,16 @@ func (s *InventoryService) CreateInventory(req dto.CreateInventoryRequest, userI
 }
 
 // UpdateInventory updates inventory quantity with optimistic locking
-func (s *InventoryService) UpdateInventory(id uuid.UUID, quantity int, userID uuid.UUID, userName string) (*dto.InventoryResponse, error) {
+func (s *InventoryService) UpdateInventory(scope StoreScope, id uuid.UUID, quantity int, userID uuid.UUID, userName string) (*dto.InventoryResponse, error) {
 	var inventory models.Inventory
 	var sku models.SKU
 	var store models.Store
 
 	err := database.DB.Transaction(func(tx *gorm.DB) error {
 		// Get inventory with lock
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").
+		if err := scope.Inventory(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
 			Preload("SKU").Preload("Store").
-			First(&inventory, "id = ?", id).Error; err != nil {
+			First(&inventory, "inventory.id = ?", id).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				return fmt.Errorf("inventory not found")
 			}
@@ -325,11 +330,16 @@ func (s *InventoryService) UpdateInventory(id uuid.UUID, quantity int, userID uu
 
 		// Calculate delta
 		deltaQuantity := quantity - inventory.Quantity
+		diff, err := BuildDiff(map[string]interface{}{"quantity": inventory.Quantity}, map[string]interface{}{"quantity": quantity})
+		if err != nil {
+			return err
+		}
 
 		// Update inventory
 		inventory.Quantity = quantity
 		inventory.Version++
-		if err := tx.Save(&inventory).Error; err != nil {
+		if err := tx.Scopes(scope.Apply("inventory.store_id")).
+			Select("quantity", "version", "updated_at").Updates(&inventory).Error; err != nil {
 			return fmt.Errorf("failed to update inventory: %w", err)
 		}
 
@@ -337,6 +347,9 @@ func (s *InventoryService) UpdateInventory(id uuid.UUID, quantity int, userID uu
 		outbox := models.Outbox{
 			OperationType:    "update",
 			SenderInstanceID: config.CONFIG.InstanceID,
+			EntityType:       models.EntityInventory,
+			EntityID:         inventory.ID,
+			Diff:             diff,
 			InventoryID:      inventory.ID,
 			SKUID:            inventory.SKUID,
 			SKUName:          sku.Name,
@@ -360,7 +373,7 @@ func (s *InventoryService) UpdateInventory(id uuid.UUID, quantity int, userID uu
 	}
 
 	// Delete cache
-	s.invalidateCache(inventory.StoreID, &inventory.SKUID)
+	s.invalidateCache(inventory.StoreID)
 
 	// Return response
 	response := &dto.InventoryResponse{
@@ -377,16 +390,16 @@ func (s *InventoryService) UpdateInventory(id uuid.UUID, quantity int, userID uu
 }
 
 // AdjustInventory adjusts inventory quantity by delta
-func (s *InventoryService) AdjustInventory(id uuid.UUID, deltaQuantity int, userID uuid.UUID, userName string) (*dto.InventoryResponse, error) {
+func (s *InventoryService) AdjustInventory(scope StoreScope, id uuid.UUID, deltaQuantity int, userID uuid.UUID, userName string) (*dto.InventoryResponse, error) {
 	var inventory models.Inventory
 	var sku models.SKU
 	var store models.Store
 
 	err := database.DB.Transaction(func(tx *gorm.DB) error {
 		// Get inventory with lock
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").
+		if err := scope.Inventory(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
 			Preload("SKU").Preload("Store").
-			First(&inventory, "id = ?", id).Error; err != nil {
+			First(&inventory, "inventory.id = ?", id).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				return fmt.Errorf("inventory not found")
 			}
@@ -401,11 +414,16 @@ func (s *InventoryService) AdjustInventory(id uuid.UUID, deltaQuantity int, user
 		if newQuantity < 0 {
 			return fmt.Errorf("insufficient inventory: current quantity is %d, cannot adjust by %d", inventory.Quantity, deltaQuantity)
 		}
+		diff, err := BuildDiff(map[string]interface{}{"quantity": inventory.Quantity}, map[string]interface{}{"quantity": newQuantity})
+		if err != nil {
+			return err
+		}
 
 		// Update inventory
 		inventory.Quantity = newQuantity
 		inventory.Version++
-		if err := tx.Save(&inventory).Error; err != nil {
+		if err := tx.Scopes(scope.Apply("inventory.store_id")).
+			Select("quantity", "version", "updated_at").Updates(&inventory).Error; err != nil {
 			return fmt.Errorf("failed to update inventory: %w", err)
 		}
 
@@ -413,6 +431,9 @@ func (s *InventoryService) AdjustInventory(id uuid.UUID, deltaQuantity int, user
 		outbox := models.Outbox{
 			OperationType:    "adjust",
 			SenderInstanceID: config.CONFIG.InstanceID,
+			EntityType:       models.EntityInventory,
+			EntityID:         inventory.ID,
+			Diff:             diff,
 			InventoryID:      inventory.ID,
 			SKUID:            inventory.SKUID,
 			SKUName:          sku.Name,
@@ -436,7 +457,7 @@ func (s *InventoryService) AdjustInventory(id uuid.UUID, deltaQuantity int, user
 	}
 
 	// Delete cache
-	s.invalidateCache(inventory.StoreID, &inventory.SKUID)
+	s.invalidateCache(inventory.StoreID)
 
 	// Return response
 	response := &dto.InventoryResponse{
@@ -453,15 +474,15 @@ func (s *InventoryService) AdjustInventory(id uuid.UUID, deltaQuantity int, user
 }
 
 // DeleteInventory deletes an inventory record
-func (s *InventoryService) DeleteInventory(id uuid.UUID, userID uuid.UUID, userName string) error {
+func (s *InventoryService) DeleteInventory(scope StoreScope, id uuid.UUID, userID uuid.UUID, userName string) error {
 	var inventory models.Inventory
 	var sku models.SKU
 	var store models.Store
 
 	err := database.DB.Transaction(func(tx *gorm.DB) error {
 		// Get inventory
-		if err := tx.Preload("SKU").Preload("Store").
-			First(&inventory, "id = ?", id).Error; err != nil {
+		if err := scope.Inventory(tx).Preload("SKU").Preload("Store").
+			First(&inventory, "inventory.id = ?", id).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				return fmt.Errorf("inventory not found")
 			}
@@ -472,14 +493,21 @@ func (s *InventoryService) DeleteInventory(id uuid.UUID, userID uuid.UUID, userN
 		store = inventory.Store
 
 		// Delete inventory
-		if err := tx.Delete(&inventory).Error; err != nil {
+		if err := tx.Scopes(scope.Apply("inventory.store_id")).Delete(&inventory).Error; err != nil {
 			return fmt.Errorf("failed to delete inventory: %w", err)
 		}
 
 		// Create outbox record (marking deletion)
+		diff, err := BuildDiff(map[string]interface{}{"quantity": inventory.Quantity}, nil)
+		if err != nil {
+			return err
+		}
 		outbox := models.Outbox{
 			OperationType:    "delete",
 			SenderInstanceID: config.CONFIG.InstanceID,
+			EntityType:       models.EntityInventory,
+			EntityID:         inventory.ID,
+			Diff:             diff,
 			InventoryID:      inventory.ID,
 			SKUID:            inventory.SKUID,
 			SKUName:          sku.Name,
@@ -503,31 +531,63 @@ func (s *InventoryService) DeleteInventory(id uuid.UUID, userID uuid.UUID, userN
 	}
 
 	// Delete cache
-	s.invalidateCache(inventory.StoreID, &inventory.SKUID)
+	s.invalidateCache(inventory.StoreID)
 
 	return nil
 }
 
+// Inventory cache generation counters (see cache.BumpGenerations)
+const globalGenerationKey = "inventory:gen:all"
+
+// storeGenerationKey returns the generation counter key of a store
+func storeGenerationKey(storeID string) string {
+	return "inventory:gen:store:" + storeID
+}
+
 // buildCacheKey builds a cache key for inventory query
-func (s *InventoryService) buildCacheKey(params dto.InventoryQueryParams, storeID *uuid.UUID, skuID *uuid.UUID, userID *uuid.UUID, userRole string) string {
+// The key embeds the generation of every store it covers, so a write to one store only
+// invalidates queries that include that store. It returns false if generations are unavailable.
+func (s *InventoryService) buildCacheKey(scope StoreScope, params dto.InventoryQueryParams, storeID *uuid.UUID, skuID *uuid.UUID) (string, bool) {
 	parts := []string{"inventory"}
 	if storeID != nil {
-		parts = append(parts, "store", storeID.String())
-	} else if userRole == "staff" && userID != nil {
-		// For staff, query allowed stores and include in cache key
-		var storeUsers []models.StoreUser
-		if err := database.DB.Where("user_id = ?", *userID).Find(&storeUsers).Error; err == nil && len(storeUsers) > 0 {
-			storeIDStrs := make([]string, len(storeUsers))
-			for i, su := range storeUsers {
-				storeIDStrs[i] = su.StoreID.String()
+		generations, err := cache.GetGenerations(storeGenerationKey(storeID.String()))
+		if err != nil {
+			return "", false
+		}
+		parts = append(parts, "store", storeID.String(), "g", strconv.FormatInt(generations[0], 10))
+	} else if !scope.AllStores() {
+		// Without all stores, include the stores in scope with their generations in cache key
+		storeIDs, err := scope.StoreIDs()
+		if err != nil {
+			return "", false
+		}
+		if len(storeIDs) > 0 {
+			storeIDStrs := make([]string, len(storeIDs))
+			for i, id := range storeIDs {
+				storeIDStrs[i] = id.String()
 			}
 			sort.Strings(storeIDStrs)
+			generationKeys := make([]string, len(storeIDStrs))
+			for i, id := range storeIDStrs {
+				generationKeys[i] = storeGenerationKey(id)
+			}
+			generations, err := cache.GetGenerations(generationKeys...)
+			if err != nil {
+				return "", false
+			}
+			for i := range storeIDStrs {
+				storeIDStrs[i] += "@" + strconv.FormatInt(generations[i], 10)
+			}
 			parts = append(parts, "allowed", strings.Join(storeIDStrs, ","))
 		} else {
 			parts = append(parts, "store", "none")
 		}
 	} else {
-		parts = append(parts, "store", "all")
+		generations, err := cache.GetGenerations(globalGenerationKey)
+		if err != nil {
+			return "", false
+		}
+		parts = append(parts, "store", "all", "g", strconv.FormatInt(generations[0], 10))
 	}
 	if skuID != nil {
 		parts = append(parts, "sku", skuID.String())
@@ -536,76 +596,37 @@ func (s *InventoryService) buildCacheKey(params dto.InventoryQueryParams, storeI
 	parts = append(parts, "size", strconv.Itoa(params.PageSize))
 	parts = append(parts, "sort", params.SortBy)
 	parts = append(parts, "order", params.Order)
-	return strings.Join(parts, ":")
+	return strings.Join(parts, ":"), true
+}
+
+// InvalidateCache invalidates cached inventory queries affected by a change to a store
+func (s *InventoryService) InvalidateCache(storeID uuid.UUID) {
+	s.invalidateCache(storeID)
 }
 
-// invalidateCache invalidates cache for a store
-func (s *InventoryService) invalidateCache(storeID uuid.UUID, skuID *uuid.UUID) {
-	if cache.Client == nil {
+// InvalidateSKU invalidates cached inventory queries of every store stocking a SKU, since
+// cached entries embed the SKU's name and price
+func (s *InventoryService) InvalidateSKU(skuID uuid.UUID) {
+	var storeIDs []uuid.UUID
+	if err := SystemScope.Inventory(database.DB).Where("sku_id = ?", skuID).
+		Distinct("store_id").Pluck("store_id", &storeIDs).Error; err != nil {
+		log.Printf("Warning: failed to query stores to invalidate inventory cache for SKU %s: %v", skuID, err)
 		return
 	}
-
-	ctx := context.Background()
-
-	// Delete all cache keys matching inventory patterns
-	// Use SCAN to avoid blocking Redis
-	var cursor uint64
-	var keys []string
-
-	// Scan for store-specific cache keys
-	pattern := fmt.Sprintf("inventory:store:%s:*", storeID.String())
-	for {
-		var err error
-		keys, cursor, err = cache.Client.Scan(ctx, cursor, pattern, 100).Result()
-		if err != nil {
-			break
-		}
-		if len(keys) > 0 {
-			cache.Client.Del(ctx, keys...)
-		}
-		if cursor == 0 {
-			break
-		}
+	if len(storeIDs) > 0 {
+		s.invalidateCache(storeIDs...)
 	}
+}
 
-	// Also delete general inventory cache (without store filter)
-	cursor = 0
-	pattern = "inventory:page:*"
-	for {
-		var err error
-		keys, cursor, err = cache.Client.Scan(ctx, cursor, pattern, 100).Result()
-		if err != nil {
-			break
-		}
-		if len(keys) > 0 {
-			cache.Client.Del(ctx, keys...)
-		}
-		if cursor == 0 {
-			break
-		}
+// invalidateCache invalidates cache for stores by bumping their generations and the
+// all-stores generation; entries built with older generations are never read again
+func (s *InventoryService) invalidateCache(storeIDs ...uuid.UUID) {
+	keys := make([]string, 0, len(storeIDs)+1)
+	for _, id := range storeIDs {
+		keys = append(keys, storeGenerationKey(id.String()))
 	}
-
-	// Delete inventory cache without store prefix
-	cursor = 0
-	pattern = "inventory:*"
-	for {
-		var err error
-		keys, cursor, err = cache.Client.Scan(ctx, cursor, pattern, 100).Result()
-		if err != nil {
-			break
-		}
-		// Filter out store-specific keys we already deleted
-		filteredKeys := []string{}
-		for _, key := range keys {
-			if !strings.Contains(key, fmt.Sprintf("store:%s", storeID.String())) {
-				filteredKeys = append(filteredKeys, key)
-			}
-		}
-		if len(filteredKeys) > 0 {
-			cache.Client.Del(ctx, filteredKeys...)
-		}
-		if cursor == 0 {
-			break
-		}
+	keys = append(keys, globalGenerationKey)
+	if err := cache.BumpGenerations(keys...); err != nil {
+		log.Printf("Warning: failed to invalidate inventory cache for stores %v: %v", storeIDs, err)
 	}
 }
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/config"
+)
+
+// LoginAttemptService tracks failed logins per username and locks a username out
+// after too many failures. State lives in the shared cache so every instance sees it;
+// if the cache is unavailable logins are not throttled.
+type LoginAttemptService struct {
+	maxAttempts int
+	lockout     time.Duration
+}
+
+// NewLoginAttemptService creates a new login attempt service
+func NewLoginAttemptService() *LoginAttemptService {
+	return &LoginAttemptService{
+		maxAttempts: config.CONFIG.LoginMaxAttempts,
+		lockout:     config.CONFIG.LoginLockout,
+	}
+}
+
+func loginFailuresKey(username string) string {
+	return "login:failures:" + strings.ToLower(username)
+}
+
+func loginLockKey(username string) string {
+	return "login:lock:" + strings.ToLower(username)
+}
+
+// LockedFor returns how long the username remains locked out (0 if not locked)
+func (s *LoginAttemptService) LockedFor(username string) time.Duration {
+	value, err := cache.Get(loginLockKey(username))
+	if err != nil {
+		return 0
+	}
+	// The lock holds its expiry time so the remaining duration can be reported
+	until, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	if remaining := time.Until(time.Unix(until, 0)); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// RecordFailure counts a failed login and returns the lockout duration if the username is now locked out
+func (s *LoginAttemptService) RecordFailure(username string) time.Duration {
+	// Failures are counted within the lockout period
+	failures, err := cache.Default.IncrExpire(loginFailuresKey(username), s.lockout)
+	if err != nil || int(failures) < s.maxAttempts {
+		return 0
+	}
+
+	until := time.Now().Add(s.lockout)
+	if err := cache.Set(loginLockKey(username), until.Unix(), s.lockout); err != nil {
+		return 0
+	}
+	cache.Delete(loginFailuresKey(username))
+	log.Printf("Login locked for username %q after %d failed attempts", username, failures)
+	return s.lockout
+}
+
+// Reset clears the failure count after a successful login
+func (s *LoginAttemptService) Reset(username string) {
+	cache.Delete(loginFailuresKey(username))
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+	"inventory-manager-server/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recoveryCodeCount is how many recovery codes a user gets at a time
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var (
+	// ErrInvalidChallenge is returned for unknown, expired or outdated login challenge tokens
+	ErrInvalidChallenge = errors.New("invalid or expired login challenge")
+	// ErrInvalidMFACode is returned for wrong, expired or already used TOTP and recovery codes
+	ErrInvalidMFACode = errors.New("invalid two-factor code")
+	// ErrMFAAlreadyEnabled is returned when enrolling a user whose authenticator is already confirmed
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFANotEnabled is returned when confirming or using an authenticator that does not exist
+	ErrMFANotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrMFARequired is returned when disabling two-factor authentication the user's role requires
+	ErrMFARequired = errors.New("two-factor authentication is required for this role")
+)
+
+// MFAService manages TOTP authenticators and recovery codes, and completes two-step logins
+type MFAService struct {
+	// Using global database instance
+}
+
+// NewMFAService creates a new MFA service
+func NewMFAService() *MFAService {
+	return &MFAService{}
+}
+
+// Required reports whether the policy (MFA_REQUIRED_ROLES) requires two-factor authentication for a role
+func (s *MFAService) Required(role string) bool {
+	for _, required := range config.CONFIG.MFARequiredRoles {
+		if required == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled reports whether a user has a confirmed authenticator
+func (s *MFAService) Enabled(userID uuid.UUID) (bool, error) {
+	var count int64
+	if err := database.DB.Model(&models.UserTOTP{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL", userID).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to query authenticator: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Status returns the two-factor authentication state of a user
+func (s *MFAService) Status(user models.User) (*dto.MFAStatusResponse, error) {
+	enabled, err := s.Enabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	var remaining int64
+	if err := database.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Count(&remaining).Error; err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return &dto.MFAStatusResponse{
+		Enabled:                enabled,
+		Required:               s.Required(user.Role),
+		RecoveryCodesRemaining: int(remaining),
+	}, nil
+}
+
+// Challenge returns a login challenge if the user must pass a second factor, or nil if the
+// password is enough
+func (s *MFAService) Challenge(user models.User, rememberMe bool) (*dto.MFAChallengeResponse, error) {
+	enabled, err := s.Enabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled && !s.Required(user.Role) {
+		return nil, nil
+	}
+
+	token, err := utils.GenerateMFAChallenge(user.ID, user.TokenVersion, rememberMe, config.CONFIG.MFAChallengeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate login challenge: %w", err)
+	}
+	return &dto.MFAChallengeResponse{
+		MFARequired:        true,
+		EnrollmentRequired: !enabled,
+		ChallengeToken:     token,
+		ExpiresIn:          int(config.CONFIG.MFAChallengeTTL.Seconds()),
+	}, nil
+}
+
+// ResolveChallenge returns the user of a login challenge token and whether they asked to be remembered.
+// Challenges issued before the user's tokens were revoked are rejected.
+func (s *MFAService) ResolveChallenge(challengeToken string) (models.User, bool, error) {
+	var user models.User
+	claims, err := utils.ValidateMFAChallenge(challengeToken)
+	if err != nil {
+		return user, false, ErrInvalidChallenge
+	}
+	if err := database.DB.First(&user, "id = ?", claims.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return user, false, ErrInvalidChallenge
+		}
+		return user, false, fmt.Errorf("failed to query user: %w", err)
+	}
+	if user.TokenVersion != claims.TokenVersion || user.PasswordResetRequired || !user.Active() {
+		return user, false, ErrInvalidChallenge
+	}
+	return user, claims.RememberMe, nil
+}
+
+// CompleteChallenge checks the second factor of a login. Users enrolling during login confirm
+// their new authenticator instead, and receive their recovery codes.
+func (s *MFAService) CompleteChallenge(user models.User, code string) ([]string, error) {
+	enabled, err := s.Enabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, s.Verify(user.ID, code)
+	}
+	if !s.Required(user.Role) {
+		// 2FA was disabled after the challenge was issued
+		return nil, ErrInvalidChallenge
+	}
+	codes, err := s.Confirm(user, code)
+	if errors.Is(err, ErrMFANotEnabled) {
+		return nil, ErrInvalidChallenge
+	}
+	return codes, err
+}
+
+// Enroll creates a new pending authenticator for a user, replacing any earlier pending one
+func (s *MFAService) Enroll(user models.User) (*dto.MFAEnrollmentResponse, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.UserTOTP
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&existing, "user_id = ?", user.ID).Error
+		if err == nil && existing.ConfirmedAt != nil {
+			return ErrMFAAlreadyEnabled
+		}
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		totp := models.UserTOTP{UserID: user.ID, Secret: secret}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"secret", "confirmed_at", "last_used_step", "updated_at"}),
+		}).Create(&totp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MFAEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPURI(config.CONFIG.MFAIssuer, user.Username, secret),
+	}, nil
+}
+
+// Confirm enables a user's pending authenticator with a first code and returns new recovery codes
+func (s *MFAService) Confirm(user models.User, code string) ([]string, error) {
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var totp models.UserTOTP
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&totp, "user_id = ?", user.ID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrMFANotEnabled
+			}
+			return err
+		}
+		if totp.ConfirmedAt != nil {
+			return ErrMFAAlreadyEnabled
+		}
+		step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		if err := tx.Model(&totp).Updates(map[string]interface{}{
+			"confirmed_at":   time.Now(),
+			"last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if codes, err = s.replaceRecoveryCodes(tx, user.ID); err != nil {
+			return err
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityUser,
+			EntityID:      user.ID,
+			OperationType: "enable_mfa",
+			Before:        map[string]interface{}{"mfa_enabled": false},
+			After:         map[string]interface{}{"mfa_enabled": true},
+			Actor:         Actor{ID: user.ID, Name: user.Username},
+		})
+	})
+	return codes, err
+}
+
+// Verify checks a TOTP code or an unused recovery code of a user; either can be used only once
+func (s *MFAService) Verify(userID uuid.UUID, code string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return s.verify(tx, userID, code)
+	})
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes after checking a code
+func (s *MFAService) RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error) {
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.verify(tx, userID, code); err != nil {
+			return err
+		}
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// Disable removes a user's authenticator and recovery codes after checking a code
+func (s *MFAService) Disable(user models.User, code string) error {
+	if s.Required(user.Role) {
+		return ErrMFARequired
+	}
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.verify(tx, user.ID, code); err != nil {
+			return err
+		}
+		return s.remove(tx, user.ID, "disable_mfa", Actor{ID: user.ID, Name: user.Username})
+	})
+}
+
+// Reset removes a user's authenticator and recovery codes on behalf of a manager, e.g. after
+// the user lost their device. Users whose role requires 2FA enrol again at their next login.
+func (s *MFAService) Reset(userID uuid.UUID, actor Actor) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		return s.remove(tx, user.ID, "reset_mfa", actor)
+	})
+}
+
+// verify checks a TOTP code or recovery code within tx and consumes it
+func (s *MFAService) verify(tx *gorm.DB, userID uuid.UUID, code string) error {
+	var totp models.UserTOTP
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&totp, "user_id = ? AND confirmed_at IS NOT NULL", userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+
+	// Steps at or before the last accepted one were already used
+	if step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now()); ok && step > totp.LastUsedStep {
+		return tx.Model(&totp).Update("last_used_step", step).Error
+	}
+
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// remove deletes a user's authenticator and recovery codes within tx
+func (s *MFAService) remove(tx *gorm.DB, userID uuid.UUID, operation string, actor Actor) error {
+	result := tx.Where("user_id = ?", userID).Delete(&models.UserTOTP{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	return RecordEntityChange(tx, EntityChange{
+		EntityType:    models.EntityUser,
+		EntityID:      userID,
+		OperationType: operation,
+		Before:        map[string]interface{}{"mfa_enabled": true},
+		After:         map[string]interface{}{"mfa_enabled": false},
+		Actor:         actor,
+	})
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes within tx and returns new ones
+func (s *MFAService) replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		secret := make([]byte, 10)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(secret))
+		codes[i] = encoded[:8] + "-" + encoded[8:]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashToken(encoded)}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode strips the separator and case users may type differently
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,487 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/models"
+	"inventory-manager-server/oidc"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// oidcStateTTL bounds how long a user may take to sign in at the identity provider
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	// ErrOIDCDisabled is returned when single sign-on is not configured
+	ErrOIDCDisabled = errors.New("single sign-on is not configured")
+	// ErrInvalidOIDCState is returned for unknown, expired or already used login states
+	ErrInvalidOIDCState = errors.New("invalid or expired login state")
+	// ErrOIDCProvider is returned when the identity provider cannot be reached or rejects the code
+	ErrOIDCProvider = errors.New("identity provider login failed")
+	// ErrOIDCNotAuthorized is returned for identity provider accounts without a mapped role
+	ErrOIDCNotAuthorized = errors.New("account is not authorized for this application")
+	// ErrOIDCMissingEmail is returned when the ID token has no email claim
+	ErrOIDCMissingEmail = errors.New("identity provider did not share an email address")
+	// ErrOIDCAccountConflict is returned when a new user's email is taken by an unlinked account
+	ErrOIDCAccountConflict = errors.New("an account with this email address already exists")
+	// ErrOIDCLinkRequired is returned when the email belongs to an account that signs in with a
+	// password or second factor; its owner must link the identity provider account from their profile
+	ErrOIDCLinkRequired = errors.New("sign in with your password to link this account")
+	// ErrOIDCIdentityLinked is returned when linking an identity provider account of another user
+	ErrOIDCIdentityLinked = errors.New("identity provider account is linked to another user")
+)
+
+// oidcLoginState is kept in the shared cache between starting and completing a login
+type oidcLoginState struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+	RememberMe   bool   `json:"remember_me"`
+	// BindingHash is the hash of the secret kept by the browser that started the login, so a
+	// login started by someone else cannot be completed in it (login CSRF)
+	BindingHash string `json:"binding_hash"`
+	// LinkUserID is set when a signed-in user links their identity provider account
+	LinkUserID uuid.UUID `json:"link_user_id"`
+}
+
+// OIDCService signs users in through the configured OpenID Connect identity provider and
+// provisions them just in time
+type OIDCService struct {
+	// Using global database, cache and identity provider instances
+}
+
+// NewOIDCService creates a new OIDC service
+func NewOIDCService() *OIDCService {
+	return &OIDCService{}
+}
+
+func oidcStateKey(state string) string {
+	return "oidc:state:" + state
+}
+
+// Enabled reports whether single sign-on is configured
+func (s *OIDCService) Enabled() bool {
+	return oidc.Default != nil
+}
+
+// Begin starts a login and returns the identity provider URL to send the user to, and the
+// binding secret the browser must present with the callback
+func (s *OIDCService) Begin(ctx context.Context, rememberMe bool) (string, string, error) {
+	return s.begin(ctx, oidcLoginState{RememberMe: rememberMe})
+}
+
+// BeginLink starts linking an identity provider account to a signed-in user and returns the
+// identity provider URL to send them to, and the binding secret as Begin
+func (s *OIDCService) BeginLink(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	return s.begin(ctx, oidcLoginState{LinkUserID: userID})
+}
+
+func (s *OIDCService) begin(ctx context.Context, loginState oidcLoginState) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", ErrOIDCDisabled
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	binding, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+	loginState.CodeVerifier, loginState.Nonce, loginState.BindingHash = verifier, nonce, hashToken(binding)
+
+	// Any instance may receive the callback, so the state lives in the shared cache
+	data, err := json.Marshal(loginState)
+	if err != nil {
+		return "", "", err
+	}
+	if err := cache.Set(oidcStateKey(state), string(data), oidcStateTTL); err != nil {
+		return "", "", fmt.Errorf("failed to store login state: %w", err)
+	}
+
+	authURL, err := oidc.Default.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrOIDCProvider, err)
+	}
+	return authURL, binding, nil
+}
+
+// Complete finishes a login with the authorization code sent back by the identity provider and
+// the binding secret returned by Begin. It returns the (possibly new) user and whether they
+// asked to be remembered.
+func (s *OIDCService) Complete(ctx context.Context, code, state, binding string) (models.User, bool, error) {
+	loginState, claims, err := s.redeem(ctx, code, state, binding, uuid.Nil)
+	if err != nil {
+		return models.User{}, false, err
+	}
+	user, err := s.provision(claims)
+	return user, loginState.RememberMe, err
+}
+
+// CompleteLink finishes linking the identity provider account that signed in to the given user,
+// who must be the one that started the link
+func (s *OIDCService) CompleteLink(ctx context.Context, code, state, binding string, user models.User) error {
+	_, claims, err := s.redeem(ctx, code, state, binding, user.ID)
+	if err != nil {
+		return err
+	}
+	return s.link(user, claims)
+}
+
+// redeem consumes a login state and exchanges the authorization code for the verified ID token
+// claims. The binding must be the one returned with the state, and linkUserID the user the
+// state was started for (uuid.Nil for logins).
+func (s *OIDCService) redeem(ctx context.Context, code, state, binding string, linkUserID uuid.UUID) (oidcLoginState, oidc.Claims, error) {
+	if !s.Enabled() {
+		return oidcLoginState{}, nil, ErrOIDCDisabled
+	}
+
+	// States are single-use: of concurrent callbacks with the same state only one gets it
+	raw, err := cache.GetDel(oidcStateKey(state))
+	if err != nil {
+		return oidcLoginState{}, nil, ErrInvalidOIDCState
+	}
+	var loginState oidcLoginState
+	if err := json.Unmarshal([]byte(raw), &loginState); err != nil {
+		return oidcLoginState{}, nil, ErrInvalidOIDCState
+	}
+	// Only the browser that started the login may complete it
+	if subtle.ConstantTimeCompare([]byte(hashToken(binding)), []byte(loginState.BindingHash)) != 1 {
+		return oidcLoginState{}, nil, ErrInvalidOIDCState
+	}
+	// A link started by one user cannot sign anyone in, or link to another user
+	if loginState.LinkUserID != linkUserID {
+		return oidcLoginState{}, nil, ErrInvalidOIDCState
+	}
+
+	claims, err := oidc.Default.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidIDToken) {
+			return oidcLoginState{}, nil, err
+		}
+		return oidcLoginState{}, nil, fmt.Errorf("%w: %v", ErrOIDCProvider, err)
+	}
+	return loginState, claims, nil
+}
+
+// link records the identity provider account as a way for the user to sign in
+func (s *OIDCService) link(user models.User, claims oidc.Claims) error {
+	issuer, subject := oidc.Default.Issuer(), claims.String("sub")
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+		if err == nil {
+			if identity.UserID != user.ID {
+				return ErrOIDCIdentityLinked
+			}
+			return nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		identity = models.UserIdentity{UserID: user.ID, Issuer: issuer, Subject: subject}
+		if err := tx.Create(&identity).Error; err != nil {
+			return err
+		}
+		return recordAuditEvent(tx, EntityChange{
+			EntityType:    models.EntityUser,
+			EntityID:      user.ID,
+			OperationType: "link_sso",
+			After: map[string]interface{}{
+				"username": user.Username,
+				"issuer":   issuer,
+				"email":    claims.String("email"),
+			},
+			Actor: Actor{ID: user.ID, Name: user.Username},
+		})
+	})
+}
+
+// provision finds or creates the user of an identity provider account and syncs their store
+// assignments, and the role of accounts it provisioned, from the account's groups
+func (s *OIDCService) provision(claims oidc.Claims) (models.User, error) {
+	groups := claims.Strings(config.CONFIG.OIDCGroupsClaim)
+	role := s.mapRole(groups)
+	if role == "" {
+		return models.User{}, ErrOIDCNotAuthorized
+	}
+	exists, err := NewRoleService().Exists(role)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !exists {
+		return models.User{}, fmt.Errorf("mapped role %q does not exist", role)
+	}
+	email := claims.String("email")
+	if email == "" {
+		return models.User{}, ErrOIDCMissingEmail
+	}
+
+	issuer, subject := oidc.Default.Issuer(), claims.String("sub")
+	authService := NewAuthService()
+	var user models.User
+	roleChanged := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+		switch {
+		case err == nil:
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", identity.UserID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&identity).Update("last_login_at", time.Now()).Error; err != nil {
+				return err
+			}
+		case err == gorm.ErrRecordNotFound:
+			if err := s.linkOrCreateUser(tx, &user, claims, role); err != nil {
+				return err
+			}
+			identity = models.UserIdentity{UserID: user.ID, Issuer: issuer, Subject: subject, LastLoginAt: time.Now()}
+			if err := tx.Create(&identity).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+		if !user.Active() {
+			return ErrUserDeactivated
+		}
+
+		// The identity provider is the source of truth for the role of the accounts it provisioned
+		// (no local password); linked local accounts keep the role given to them here
+		if user.PasswordHash == "" && user.Role != role {
+			before := UserSnapshot(user)
+			user.Role = role
+			if err := tx.Model(&user).Update("role", role).Error; err != nil {
+				return err
+			}
+			// Access tokens carry the role, so a role change invalidates them
+			if err := authService.BumpTokenVersion(tx, user.ID); err != nil {
+				return err
+			}
+			roleChanged = true
+			if err := RecordEntityChange(tx, EntityChange{
+				EntityType:    models.EntityUser,
+				EntityID:      user.ID,
+				OperationType: "update",
+				Before:        before,
+				After:         UserSnapshot(user),
+				Actor:         Actor{ID: user.ID, Name: user.Username},
+			}); err != nil {
+				return err
+			}
+		}
+
+		if len(config.CONFIG.OIDCStoreMapping) > 0 {
+			if err := s.syncStores(tx, user, groups); err != nil {
+				return err
+			}
+		}
+		// Reload for the current token version
+		return tx.First(&user, "id = ?", user.ID).Error
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	if roleChanged {
+		authService.PublishRevocation(user.ID)
+	}
+	if len(config.CONFIG.OIDCStoreMapping) > 0 {
+		NewStoreAssignmentService().Invalidate(user.ID)
+	}
+	return user, nil
+}
+
+// linkOrCreateUser links an existing SSO-only user with the same verified email address, or
+// creates a new user without a local password. Users that sign in with a password or second
+// factor must link the account themselves (see BeginLink), so that whoever controls the address
+// at the identity provider cannot take over their account.
+func (s *OIDCService) linkOrCreateUser(tx *gorm.DB, user *models.User, claims oidc.Claims, role string) error {
+	email := claims.String("email")
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("email = ?", email).First(user).Error
+	if err == nil {
+		// Only trust the address if the identity provider verified it
+		if !claims.Bool("email_verified") {
+			return ErrOIDCAccountConflict
+		}
+		if user.PasswordHash != "" {
+			return ErrOIDCLinkRequired
+		}
+		mfaEnabled, err := NewMFAService().Enabled(user.ID)
+		if err != nil {
+			return err
+		}
+		if mfaEnabled {
+			return ErrOIDCLinkRequired
+		}
+		return nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	username, err := s.availableUsername(tx, claims)
+	if err != nil {
+		return err
+	}
+	*user = models.User{
+		Username: username,
+		Email:    email,
+		Role:     role,
+		// Empty hash: password login and password resets are impossible for SSO-only users
+		PasswordHash: "",
+	}
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+	return RecordEntityChange(tx, EntityChange{
+		EntityType:    models.EntityUser,
+		EntityID:      user.ID,
+		OperationType: "create",
+		After:         UserSnapshot(*user),
+		Actor:         Actor{ID: user.ID, Name: user.Username},
+	})
+}
+
+// availableUsername derives a free username from the preferred username or email address
+func (s *OIDCService) availableUsername(tx *gorm.DB, claims oidc.Claims) (string, error) {
+	base := claims.String("preferred_username")
+	if base == "" {
+		base, _, _ = strings.Cut(claims.String("email"), "@")
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		if attempt > 0 {
+			suffix := make([]byte, 2)
+			if _, err := rand.Read(suffix); err != nil {
+				return "", err
+			}
+			candidate = base + "-" + hex.EncodeToString(suffix)
+		}
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+	return "", ErrOIDCAccountConflict
+}
+
+// mapRole returns the role of the first role mapping matching one of the groups, or the default role
+func (s *OIDCService) mapRole(groups []string) string {
+	for _, mapping := range config.CONFIG.OIDCRoleMapping {
+		for _, group := range groups {
+			if group == mapping.Group {
+				return mapping.Value
+			}
+		}
+	}
+	return config.CONFIG.OIDCDefaultRole
+}
+
+// syncStores makes the user's single sign-on assignments match the stores mapped from their
+// groups. Assignments made through the API, e.g. relief staff or time-bounded ones, are left alone.
+func (s *OIDCService) syncStores(tx *gorm.DB, user models.User, groups []string) error {
+	var names []string
+	for _, mapping := range config.CONFIG.OIDCStoreMapping {
+		for _, group := range groups {
+			if group == mapping.Group {
+				names = append(names, mapping.Value)
+			}
+		}
+	}
+	wanted := make(map[uuid.UUID]models.Store)
+	if len(names) > 0 {
+		var stores []models.Store
+		if err := tx.Where("name IN ?", names).Find(&stores).Error; err != nil {
+			return err
+		}
+		if len(stores) < len(names) {
+			log.Printf("Warning: OIDC_STORE_MAPPING names stores that do not exist (mapped for user %s: %v)", user.Username, names)
+		}
+		for _, store := range stores {
+			wanted[store.ID] = store
+		}
+	}
+
+	var current []models.StoreUser
+	if err := tx.Preload("Store").Where("user_id = ?", user.ID).Find(&current).Error; err != nil {
+		return err
+	}
+	for _, storeUser := range current {
+		if _, ok := wanted[storeUser.StoreID]; ok {
+			delete(wanted, storeUser.StoreID)
+			continue
+		}
+		if storeUser.Source != models.StoreUserSourceSSO {
+			continue
+		}
+		if err := tx.Delete(&storeUser).Error; err != nil {
+			return err
+		}
+		if err := RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityStoreUser,
+			EntityID:      storeUser.ID,
+			OperationType: "delete",
+			Version:       storeUser.Version + 1,
+			Before:        StoreUserSnapshot(storeUser, user.Username),
+			Actor:         Actor{ID: user.ID, Name: user.Username},
+			StoreID:       storeUser.StoreID,
+			StoreName:     storeUser.Store.Name,
+		}); err != nil {
+			return err
+		}
+	}
+	for _, store := range wanted {
+		storeUser := models.StoreUser{StoreID: store.ID, UserID: user.ID, Source: models.StoreUserSourceSSO}
+		if err := tx.Create(&storeUser).Error; err != nil {
+			return err
+		}
+		if err := RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityStoreUser,
+			EntityID:      storeUser.ID,
+			OperationType: "create",
+			Version:       storeUser.Version,
+			After:         StoreUserSnapshot(storeUser, user.Username),
+			Actor:         Actor{ID: user.ID, Name: user.Username},
+			StoreID:       store.ID,
+			StoreName:     store.Name,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/oidc"
+	"inventory-manager-server/oidc/oidctest"
+
+	"github.com/google/uuid"
+)
+
+// newTestOIDCService points single sign-on at a stub provider for the duration of the test;
+// login states live in the default in-memory cache
+func newTestOIDCService(t *testing.T) *OIDCService {
+	t.Helper()
+	_, server, err := oidctest.NewServer("inventory-manager", "stub-secret")
+	if err != nil {
+		t.Fatalf("failed to start stub provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	previous := oidc.Default
+	oidc.Use(oidc.NewProvider(server.URL, "inventory-manager", "stub-secret", "http://app.test/oidc/callback", []string{"openid", "email"}))
+	t.Cleanup(func() { oidc.Use(previous) })
+	return NewOIDCService()
+}
+
+// signInAt completes the login form of the stub for an authorization URL and returns the code
+// and state the browser would be redirected back with
+func signInAt(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	redirect, err := oidctest.SignIn(authURL, "alice", "alice@example.com", "inventory-staff")
+	if err != nil {
+		t.Fatalf("SignIn: %v", err)
+	}
+	return redirect.Query().Get("code"), redirect.Query().Get("state")
+}
+
+// editLoginState rewrites the cached state of a login, as if it had been started differently
+func editLoginState(t *testing.T, state string, edit func(*oidcLoginState)) {
+	t.Helper()
+	raw, err := cache.Get(oidcStateKey(state))
+	if err != nil {
+		t.Fatalf("login state not cached: %v", err)
+	}
+	var loginState oidcLoginState
+	if err := json.Unmarshal([]byte(raw), &loginState); err != nil {
+		t.Fatal(err)
+	}
+	edit(&loginState)
+	data, _ := json.Marshal(loginState)
+	if err := cache.Set(oidcStateKey(state), string(data), oidcStateTTL); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOIDCBeginSendsPKCEAndNonceButNotBinding(t *testing.T) {
+	s := newTestOIDCService(t)
+	authURL, binding, err := s.Begin(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		t.Errorf("authorization URL lacks an S256 code challenge: %s", authURL)
+	}
+	if query.Get("nonce") == "" || query.Get("state") == "" {
+		t.Errorf("authorization URL lacks nonce or state: %s", authURL)
+	}
+	for name, values := range query {
+		for _, value := range values {
+			if value == binding {
+				t.Errorf("binding is sent to the identity provider as %q", name)
+			}
+		}
+	}
+}
+
+func TestOIDCRedeemCompletesLogin(t *testing.T) {
+	s := newTestOIDCService(t)
+	authURL, binding, err := s.Begin(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, state := signInAt(t, authURL)
+
+	loginState, claims, err := s.redeem(context.Background(), code, state, binding, uuid.Nil)
+	if err != nil {
+		t.Fatalf("redeem: %v", err)
+	}
+	if !loginState.RememberMe {
+		t.Error("RememberMe was not kept across the redirect")
+	}
+	if got := claims.String("email"); got != "alice@example.com" {
+		t.Errorf("email = %q, want %q", got, "alice@example.com")
+	}
+}
+
+func TestOIDCStateIsSingleUse(t *testing.T) {
+	s := newTestOIDCService(t)
+	authURL, binding, err := s.Begin(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, state := signInAt(t, authURL)
+
+	if _, _, err := s.redeem(context.Background(), code, state, binding, uuid.Nil); err != nil {
+		t.Fatalf("first redeem: %v", err)
+	}
+	if _, _, err := s.redeem(context.Background(), code, state, binding, uuid.Nil); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("second redeem error = %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
+
+func TestOIDCConcurrentCallbacksRedeemStateOnce(t *testing.T) {
+	s := newTestOIDCService(t)
+	authURL, binding, err := s.Begin(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, state := signInAt(t, authURL)
+
+	const callbacks = 8
+	results := make(chan error, callbacks)
+	for i := 0; i < callbacks; i++ {
+		go func() {
+			_, _, err := s.redeem(context.Background(), code, state, binding, uuid.Nil)
+			results <- err
+		}()
+	}
+	redeemed := 0
+	for i := 0; i < callbacks; i++ {
+		switch err := <-results; {
+		case err == nil:
+			redeemed++
+		case !errors.Is(err, ErrInvalidOIDCState):
+			t.Errorf("redeem error = %v, want %v", err, ErrInvalidOIDCState)
+		}
+	}
+	if redeemed != 1 {
+		t.Fatalf("%d concurrent callbacks redeemed the state, want 1", redeemed)
+	}
+}
+
+func TestOIDCRejectsUnknownState(t *testing.T) {
+	s := newTestOIDCService(t)
+	if _, _, err := s.redeem(context.Background(), "code", "unknown", "binding", uuid.Nil); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("redeem error = %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
+
+func TestOIDCStateIsBoundToBrowser(t *testing.T) {
+	s := newTestOIDCService(t)
+	// The victim's browser is sent back with a state started by someone else
+	authURL, binding, err := s.Begin(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, state := signInAt(t, authURL)
+	_, otherBinding, err := s.Begin(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	for _, b := range []string{otherBinding, ""} {
+		if _, _, err := s.redeem(context.Background(), code, state, b, uuid.Nil); !errors.Is(err, ErrInvalidOIDCState) {
+			t.Fatalf("redeem with binding %q error = %v, want %v", b, err, ErrInvalidOIDCState)
+		}
+	}
+	// A rejected attempt still uses up the state
+	if _, _, err := s.redeem(context.Background(), code, state, binding, uuid.Nil); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("redeem after rejection error = %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
+
+func TestOIDCLinkStateIsBoundToUser(t *testing.T) {
+	s := newTestOIDCService(t)
+	owner, other := uuid.New(), uuid.New()
+
+	for _, tc := range []struct {
+		name       string
+		linkUserID uuid.UUID
+		wantErr    error
+	}{
+		{"login", uuid.Nil, ErrInvalidOIDCState},
+		{"another user", other, ErrInvalidOIDCState},
+		{"owner", owner, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			authURL, binding, err := s.BeginLink(context.Background(), owner)
+			if err != nil {
+				t.Fatalf("BeginLink: %v", err)
+			}
+			code, state := signInAt(t, authURL)
+			if _, _, err := s.redeem(context.Background(), code, state, binding, tc.linkUserID); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("redeem error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCRedeemChecksNonce(t *testing.T) {
+	s := newTestOIDCService(t)
+	authURL, binding, err := s.Begin(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, state := signInAt(t, authURL)
+	// The ID token carries the nonce of the authorization request, not of this login
+	editLoginState(t, state, func(ls *oidcLoginState) { ls.Nonce = "replayed" })
+
+	if _, _, err := s.redeem(context.Background(), code, state, binding, uuid.Nil); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Fatalf("redeem error = %v, want %v", err, oidc.ErrInvalidIDToken)
+	}
+}
+
+func TestOIDCRedeemChecksPKCE(t *testing.T) {
+	s := newTestOIDCService(t)
+	authURL, binding, err := s.Begin(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, state := signInAt(t, authURL)
+	// An intercepted code is useless without the verifier of the login that requested it
+	editLoginState(t, state, func(ls *oidcLoginState) {
+		ls.CodeVerifier, _, _ = oidc.NewPKCE()
+	})
+
+	if _, _, err := s.redeem(context.Background(), code, state, binding, uuid.Nil); !errors.Is(err, ErrOIDCProvider) {
+		t.Fatalf("redeem error = %v, want %v", err, ErrOIDCProvider)
+	}
+}
@@ -1,7 +1,11 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"reflect"
 	"time"
 
 	"inventory-manager-server/config"
@@ -9,9 +13,85 @@ import (
 	"inventory-manager-server/kafka"
 	"inventory-manager-server/models"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// FieldChange holds the previous and new value of a changed field
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// EntityChange describes a mutation of a SKU, store, user, role or staff assignment
+// Before is nil for creations and After is nil for deletions
+type EntityChange struct {
+	EntityType    string
+	EntityID      uuid.UUID
+	OperationType string
+	Version       int
+	Before        map[string]interface{}
+	After         map[string]interface{}
+	Actor         Actor
+	SKUID         uuid.UUID
+	SKUName       string
+	StoreID       uuid.UUID
+	StoreName     string
+}
+
+// BuildDiff builds a JSON diff containing only the fields that differ between before and after
+func BuildDiff(before, after map[string]interface{}) (models.JSON, error) {
+	diff := make(map[string]FieldChange)
+	for field, newValue := range after {
+		oldValue, existed := before[field]
+		if !existed || !reflect.DeepEqual(oldValue, newValue) {
+			diff[field] = FieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	for field, oldValue := range before {
+		if _, exists := after[field]; !exists {
+			diff[field] = FieldChange{Old: oldValue, New: nil}
+		}
+	}
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal diff: %w", err)
+	}
+	return models.JSON(data), nil
+}
+
+// RecordEntityChange writes an outbox record and an audit log entry for an entity change within
+// the given transaction
+func RecordEntityChange(tx *gorm.DB, change EntityChange) error {
+	diff, err := BuildDiff(change.Before, change.After)
+	if err != nil {
+		return err
+	}
+	version := change.Version
+	if version == 0 {
+		version = 1
+	}
+
+	outbox := models.Outbox{
+		OperationType:    change.OperationType,
+		SenderInstanceID: config.CONFIG.InstanceID,
+		EntityType:       change.EntityType,
+		EntityID:         change.EntityID,
+		Diff:             diff,
+		SKUID:            change.SKUID,
+		SKUName:          change.SKUName,
+		StoreID:          change.StoreID,
+		StoreName:        change.StoreName,
+		UserID:           change.Actor.ID,
+		UserName:         change.Actor.Name,
+		Version:          version,
+	}
+	if err := tx.Create(&outbox).Error; err != nil {
+		return fmt.Errorf("failed to create outbox record: %w", err)
+	}
+	return recordAudit(tx, change, diff)
+}
+
 // OutboxService handles outbox operations
 type OutboxService struct {
 	// Using global database and kafka instances
@@ -22,39 +102,68 @@ func NewOutboxService() *OutboxService {
 	return &OutboxService{}
 }
 
-// ProcessOutbox processes outbox records
-func (s *OutboxService) ProcessOutbox() error {
+// outboxBatchSize is the number of outbox records published per query
+const outboxBatchSize = 100
+
+// ProcessOutbox publishes pending outbox records of all instances in batches until the outbox
+// is drained, a batch is not fully published (e.g. Kafka is down; the next run retries) or ctx
+// is cancelled. It runs as a scheduled job, so only one pod publishes at a time.
+func (s *OutboxService) ProcessOutbox(ctx context.Context) error {
+	for ctx.Err() == nil {
+		published, err := s.processOutboxBatch()
+		if err != nil || published < outboxBatchSize {
+			return err
+		}
+	}
+	return nil
+}
+
+// processOutboxBatch publishes one batch of outbox records and returns how many were published
+// and deleted
+func (s *OutboxService) processOutboxBatch() (int, error) {
 	if kafka.Producer == nil {
 		// Kafka not initialized, skip processing
 		log.Println("Warning: Kafka producer not initialized, skipping outbox processing")
-		return nil
+		return 0, nil
 	}
 
-	// Query outbox records, filtered by sender_instance_id, ordered by version (maintain consistency)
+	// Query outbox records of every instance (records of terminated pods are published too),
+	// ordered by version (maintain consistency)
 	var outboxRecords []models.Outbox
-	if err := database.DB.Where("sender_instance_id = ?", config.CONFIG.InstanceID).
+	if err := database.DB.
 		Order("version ASC, created_at ASC").
-		Limit(100). // Process in batches
+		Limit(outboxBatchSize). // Process in batches
 		Find(&outboxRecords).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil // No records to process
+			return 0, nil // No records to process
 		}
-		return err
+		return 0, err
 	}
 
 	if len(outboxRecords) == 0 {
-		return nil // No records to process
+		return 0, nil // No records to process
 	}
 
 	// Process each record
+	published := 0
+	var newestPublished time.Time
 	for _, record := range outboxRecords {
-		// Send to Kafka
-		if err := kafka.PublishMessage(config.CONFIG.KafkaTopic, record); err != nil {
+		// Send to Kafka (the record id is the event id consumers deduplicate on, so a
+		// republish after a failed delete below is applied only once)
+		key := ""
+		if record.EntityID != uuid.Nil {
+			key = record.EntityID.String()
+		}
+		if err := kafka.PublishEvent(config.CONFIG.KafkaTopic, record.ID.String(), key, record); err != nil {
 			log.Printf("Failed to publish outbox record %s to Kafka: %v", record.ID, err)
 			// Continue processing other records even if one fails
 			continue
 		}
 
+		if record.CreatedAt.After(newestPublished) {
+			newestPublished = record.CreatedAt
+		}
+
 		// Delete the processed record
 		if err := database.DB.Delete(&record).Error; err != nil {
 			log.Printf("Failed to delete processed outbox record %s: %v", record.ID, err)
@@ -62,29 +171,26 @@ func (s *OutboxService) ProcessOutbox() error {
 			continue
 		}
 
-		log.Printf("Processed outbox record %s (SKU: %s, Store: %s, Delta: %d)",
-			record.ID, record.SKUName, record.StoreName, record.DeltaQuantity)
+		published++
+		log.Printf("Processed outbox record %s (%s %s: %s)",
+			record.ID, record.EntityType, record.OperationType, record.EntityID)
 	}
 
-	return nil
-}
-
-// StartOutboxProcessor starts the outbox processor (background task)
-func (s *OutboxService) StartOutboxProcessor() {
-	ticker := time.NewTicker(2 * time.Second) // Process every 5 seconds
-	defer ticker.Stop()
-
-	log.Println("Outbox processor started")
-
-	// Process immediately on start
-	if err := s.ProcessOutbox(); err != nil {
-		log.Printf("Error processing outbox on startup: %v", err)
+	if !newestPublished.IsZero() {
+		if err := advanceCheckpoint(database.DB, models.CheckpointPublished, newestPublished); err != nil {
+			log.Printf("Warning: %v", err)
+		}
 	}
 
-	// Process periodically
-	for range ticker.C {
-		if err := s.ProcessOutbox(); err != nil {
-			log.Printf("Error processing outbox: %v", err)
-		}
+	return published, nil
+}
+
+// advanceCheckpoint moves an event checkpoint forward to eventAt; older times are ignored
+func advanceCheckpoint(tx *gorm.DB, name string, eventAt time.Time) error {
+	err := tx.Exec(`INSERT INTO event_checkpoints (name, event_at) VALUES (?, ?)
+ON CONFLICT (name) DO UPDATE SET event_at = GREATEST(event_checkpoints.event_at, EXCLUDED.event_at)`, name, eventAt).Error
+	if err != nil {
+		return fmt.Errorf("failed to advance %s event checkpoint: %w", name, err)
 	}
+	return nil
 }
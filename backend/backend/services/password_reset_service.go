@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/mailer"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mailTimeout bounds how long sending a reset email may take
+const mailTimeout = 10 * time.Second
+
+var (
+	// ErrInvalidResetToken is returned for unknown, expired or already used reset tokens
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	// ErrUserNotFound is returned when resetting the password of an unknown user
+	ErrUserNotFound = errors.New("user not found")
+	// ErrResetMailFailed is returned when a forced reset took effect but its email could not be sent
+	ErrResetMailFailed = errors.New("failed to send password reset email")
+)
+
+// PasswordResetService issues and redeems password reset tokens
+type PasswordResetService struct {
+	// Using global database instance and mailer
+}
+
+// NewPasswordResetService creates a new password reset service
+func NewPasswordResetService() *PasswordResetService {
+	return &PasswordResetService{}
+}
+
+// RequestReset emails a reset link to the user with the given email address.
+// Unknown addresses, single sign-on users and deactivated users are silently ignored, so
+// callers cannot tell which addresses exist.
+func (s *PasswordResetService) RequestReset(email string) error {
+	var user models.User
+	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return fmt.Errorf("failed to query user: %w", err)
+	}
+	// Single sign-on users have no local password to reset
+	if user.PasswordHash == "" || !user.Active() {
+		return nil
+	}
+
+	var rawToken string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		rawToken, err = s.issue(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return s.sendResetMail(user, rawToken, false)
+}
+
+// ForceReset revokes every session of a user and requires a new password before the next
+// login; the user is emailed a reset link. Call it on behalf of a manager.
+func (s *PasswordResetService) ForceReset(userID uuid.UUID, actor Actor) error {
+	var user models.User
+	var rawToken string
+	authService := NewAuthService()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if !user.Active() {
+			return ErrUserDeactivated
+		}
+		before := user.PasswordResetRequired
+		if err := tx.Model(&user).Update("password_reset_required", true).Error; err != nil {
+			return err
+		}
+		if err := authService.RevokeUserTokens(tx, user.ID); err != nil {
+			return err
+		}
+		var err error
+		if rawToken, err = s.issue(tx, user.ID); err != nil {
+			return err
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityUser,
+			EntityID:      user.ID,
+			OperationType: "force_reset",
+			Before:        map[string]interface{}{"password_reset_required": before},
+			After:         map[string]interface{}{"password_reset_required": true},
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	authService.PublishRevocation(user.ID)
+	if err := s.sendResetMail(user, rawToken, true); err != nil {
+		return fmt.Errorf("%w: %v", ErrResetMailFailed, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token. The token is consumed, and every
+// session of the user is revoked. Passwords violating the policy return ErrPasswordPolicy and
+// leave the token usable.
+func (s *PasswordResetService) ResetPassword(rawToken, newPassword string) error {
+	var userID uuid.UUID
+	authService := NewAuthService()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var token models.PasswordResetToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(rawToken), time.Now()).
+			First(&token).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		userID = token.UserID
+
+		// Consume this and every other outstanding token of the user
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deactivated_at IS NULL", token.UserID).
+			First(&user).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		if err := NewPasswordService().Set(tx, &user, newPassword); err != nil {
+			return err
+		}
+		return authService.RevokeUserTokens(tx, token.UserID)
+	})
+	if err != nil {
+		return err
+	}
+	authService.PublishRevocation(userID)
+	return nil
+}
+
+// PurgeResetTokens deletes expired and used reset tokens
+func (s *PasswordResetService) PurgeResetTokens(ctx context.Context) error {
+	result := database.DB.WithContext(ctx).
+		Where("expires_at < ? OR used_at IS NOT NULL", time.Now()).
+		Delete(&models.PasswordResetToken{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to purge password reset tokens: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Purged %d password reset tokens", result.RowsAffected)
+	}
+	return nil
+}
+
+// issue creates a reset token for a user within tx; earlier unused tokens stop working
+func (s *PasswordResetService) issue(tx *gorm.DB, userID uuid.UUID) (string, error) {
+	if err := tx.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error; err != nil {
+		return "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate reset token: %w", err)
+	}
+	rawToken := base64.RawURLEncoding.EncodeToString(secret)
+	token := models.PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hashToken(rawToken),
+		ExpiresAt: time.Now().Add(config.CONFIG.PasswordResetTTL),
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return "", fmt.Errorf("failed to store reset token: %w", err)
+	}
+	return rawToken, nil
+}
+
+// sendResetMail emails a reset link to a user
+func (s *PasswordResetService) sendResetMail(user models.User, rawToken string, forced bool) error {
+	link := config.CONFIG.PasswordResetURL + "?token=" + url.QueryEscape(rawToken)
+	intro := "We received a request to reset your Inventory Manager password."
+	outro := "If you did not request this, you can ignore this email."
+	if forced {
+		intro = "An administrator requires you to choose a new Inventory Manager password. You have been signed out of all sessions."
+		outro = "You cannot sign in until you have chosen a new password."
+	}
+	body := fmt.Sprintf("Hello %s,\n\n%s\n\nTo choose a new password, open this link within %s:\n\n%s\n\n%s\n",
+		user.Username, intro, config.CONFIG.PasswordResetTTL, link, outro)
+
+	ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+	defer cancel()
+	return mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    body,
+	})
+}
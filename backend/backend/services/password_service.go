@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/models"
+	"inventory-manager-server/password"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrPasswordPolicy wraps every password policy violation; the wrapping error names the rule
+var ErrPasswordPolicy = errors.New("password does not meet the password policy")
+
+// PasswordService enforces the password policy (PASSWORD_MIN_LENGTH, PASSWORD_MAX_LENGTH,
+// PASSWORD_BREACHED_LIST, PASSWORD_HISTORY) and stores argon2id password hashes
+type PasswordService struct {
+	// Using global database instance
+}
+
+// NewPasswordService creates a new password service
+func NewPasswordService() *PasswordService {
+	return &PasswordService{}
+}
+
+// Validate checks a new password of user against the policy within tx. Users being created
+// have no ID yet, so only their username and email address are compared.
+func (s *PasswordService) Validate(tx *gorm.DB, user models.User, plain string) error {
+	length := utf8.RuneCountInString(plain)
+	if length < config.CONFIG.PasswordMinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrPasswordPolicy, config.CONFIG.PasswordMinLength)
+	}
+	if length > config.CONFIG.PasswordMaxLength {
+		return fmt.Errorf("%w: must be at most %d characters", ErrPasswordPolicy, config.CONFIG.PasswordMaxLength)
+	}
+	lower := strings.ToLower(plain)
+	if (user.Username != "" && lower == strings.ToLower(user.Username)) ||
+		(user.Email != "" && lower == strings.ToLower(user.Email)) {
+		return fmt.Errorf("%w: must not be the username or email address", ErrPasswordPolicy)
+	}
+	if password.Breached(plain) {
+		return fmt.Errorf("%w: appears in a list of breached passwords", ErrPasswordPolicy)
+	}
+
+	if user.ID == uuid.Nil || config.CONFIG.PasswordHistory <= 0 {
+		return nil
+	}
+	// The current password may predate the history
+	previous := []string{user.PasswordHash}
+	var history []string
+	if err := tx.Model(&models.PasswordHistory{}).
+		Where("user_id = ?", user.ID).
+		Order("created_at DESC").
+		Limit(config.CONFIG.PasswordHistory).
+		Pluck("password_hash", &history).Error; err != nil {
+		return fmt.Errorf("failed to query password history: %w", err)
+	}
+	for _, hash := range append(previous, history...) {
+		if ok, _, _ := password.Verify(plain, hash); ok {
+			return fmt.Errorf("%w: must not be one of the last %d passwords", ErrPasswordPolicy, config.CONFIG.PasswordHistory)
+		}
+	}
+	return nil
+}
+
+// Hash validates a new password of user and returns its argon2id hash
+func (s *PasswordService) Hash(tx *gorm.DB, user models.User, plain string) (string, error) {
+	if err := s.Validate(tx, user, plain); err != nil {
+		return "", err
+	}
+	hash, err := password.Hash(plain)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return hash, nil
+}
+
+// Set validates and stores a new password of user within tx
+func (s *PasswordService) Set(tx *gorm.DB, user *models.User, plain string) error {
+	hash, err := s.Hash(tx, *user, plain)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"password_hash":           hash,
+		"password_reset_required": false,
+	}).Error; err != nil {
+		return err
+	}
+	return s.Remember(tx, user.ID, hash)
+}
+
+// Remember adds a password hash to the user's history within tx, keeping the last PASSWORD_HISTORY
+func (s *PasswordService) Remember(tx *gorm.DB, userID uuid.UUID, hash string) error {
+	keep := config.CONFIG.PasswordHistory
+	if keep <= 0 {
+		return nil
+	}
+	if err := tx.Create(&models.PasswordHistory{UserID: userID, PasswordHash: hash}).Error; err != nil {
+		return fmt.Errorf("failed to record password history: %w", err)
+	}
+	if err := tx.Where("user_id = ? AND id NOT IN (?)", userID,
+		tx.Model(&models.PasswordHistory{}).Select("id").
+			Where("user_id = ?", userID).
+			Order("created_at DESC").
+			Limit(keep),
+	).Delete(&models.PasswordHistory{}).Error; err != nil {
+		return fmt.Errorf("failed to trim password history: %w", err)
+	}
+	return nil
+}
+
+// Upgrade replaces the stored hash of a user who just logged in with plain when it is a bcrypt
+// hash or uses outdated argon2id parameters. Failures are logged only; the login goes ahead.
+func (s *PasswordService) Upgrade(user models.User, plain string) {
+	hash, err := password.Hash(plain)
+	if err != nil {
+		log.Printf("Warning: failed to upgrade password hash of user %s: %v", user.ID, err)
+		return
+	}
+	// Skip the update if the password changed since it was verified
+	if err := database.DB.Model(&models.User{}).
+		Where("id = ? AND password_hash = ?", user.ID, user.PasswordHash).
+		Update("password_hash", hash).Error; err != nil {
+		log.Printf("Warning: failed to upgrade password hash of user %s: %v", user.ID, err)
+	}
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rolePermissionsTTL bounds how long a role's cached permissions are trusted
+const rolePermissionsTTL = 10 * time.Minute
+
+var (
+	// ErrRoleNotFound is returned for unknown role names
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrRoleExists is returned when creating a role whose name is taken
+	ErrRoleExists = errors.New("role already exists")
+	// ErrBuiltInRole is returned when changing or deleting a built-in role
+	ErrBuiltInRole = errors.New("built-in roles cannot be modified")
+	// ErrRoleInUse is returned when deleting a role that is still assigned to users or store assignments
+	ErrRoleInUse = errors.New("role is assigned to users")
+	// ErrUnknownPermission is returned for permission names that do not exist
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+// PermissionSet holds the permissions of a role
+type PermissionSet map[string]bool
+
+// Has reports whether every given permission is in the set
+func (p PermissionSet) Has(permissions ...string) bool {
+	for _, permission := range permissions {
+		if !p[permission] {
+			return false
+		}
+	}
+	return true
+}
+
+// Names returns the permissions in the set, sorted
+func (p PermissionSet) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RoleService manages roles and resolves their permissions
+type RoleService struct {
+	// Using global database and cache instances
+}
+
+// NewRoleService creates a new role service
+func NewRoleService() *RoleService {
+	return &RoleService{}
+}
+
+func rolePermissionsKey(role string) string {
+	return "rbac:role:" + role
+}
+
+// SyncBuiltInRoles creates the built-in roles and resets their permissions to models.BuiltInRoles
+func (s *RoleService) SyncBuiltInRoles() error {
+	for name, permissions := range models.BuiltInRoles {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			role := models.Role{Name: name, BuiltIn: true}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&role).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", name).First(&role).Error; err != nil {
+				return err
+			}
+			return s.setPermissions(tx, role.ID, permissions)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to sync role %s: %w", name, err)
+		}
+		cache.Invalidate(rolePermissionsKey(name))
+	}
+	return nil
+}
+
+// Permissions returns the permissions of a role (empty for unknown roles)
+func (s *RoleService) Permissions(role string) (PermissionSet, error) {
+	var names []string
+	if !cache.GetJSON(rolePermissionsKey(role), &names) {
+		err := database.DB.Model(&models.RolePermission{}).
+			Joins("JOIN roles ON roles.id = role_permissions.role_id").
+			Where("roles.name = ?", role).
+			Pluck("role_permissions.permission", &names).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to load role permissions: %w", err)
+		}
+		cache.SetJSON(rolePermissionsKey(role), names, rolePermissionsTTL)
+	}
+
+	set := make(PermissionSet, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set, nil
+}
+
+// Exists reports whether a role with the given name exists
+func (s *RoleService) Exists(name string) (bool, error) {
+	var count int64
+	if err := database.DB.Model(&models.Role{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Name returns the name of the role with the given ID
+func (s *RoleService) Name(id uuid.UUID) (string, error) {
+	var role models.Role
+	if err := database.DB.Select("name").Where("id = ?", id).Take(&role).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", ErrRoleNotFound
+		}
+		return "", err
+	}
+	return role.Name, nil
+}
+
+// ListRoles returns every role with its permissions
+func (s *RoleService) ListRoles() ([]dto.RoleResponse, error) {
+	var roles []models.Role
+	if err := database.DB.Order("built_in DESC, name").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	var grants []models.RolePermission
+	if err := database.DB.Order("permission").Find(&grants).Error; err != nil {
+		return nil, fmt.Errorf("failed to query role permissions: %w", err)
+	}
+
+	permissions := make(map[uuid.UUID][]string)
+	for _, grant := range grants {
+		permissions[grant.RoleID] = append(permissions[grant.RoleID], grant.Permission)
+	}
+	responses := make([]dto.RoleResponse, len(roles))
+	for i, role := range roles {
+		responses[i] = roleResponse(role, permissions[role.ID])
+	}
+	return responses, nil
+}
+
+// CreateRole creates a custom role
+func (s *RoleService) CreateRole(req dto.CreateRoleRequest, actor Actor) (*dto.RoleResponse, error) {
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := models.Role{Name: req.Name, Description: req.Description}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&role)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRoleExists
+		}
+		if err := s.setPermissions(tx, role.ID, permissions); err != nil {
+			return err
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityRole,
+			EntityID:      role.ID,
+			OperationType: "create",
+			After:         roleSnapshot(role, permissions),
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := roleResponse(role, permissions)
+	return &response, nil
+}
+
+// UpdateRole changes the description and/or permissions of a custom role.
+// Permission changes apply to the role's users on their next request.
+func (s *RoleService) UpdateRole(name string, req dto.UpdateRoleRequest, actor Actor) (*dto.RoleResponse, error) {
+	var permissions []string
+	if req.Permissions != nil {
+		var err error
+		if permissions, err = normalizePermissions(req.Permissions); err != nil {
+			return nil, err
+		}
+	}
+
+	var role models.Role
+	var current []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", name).First(&role).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrRoleNotFound
+			}
+			return err
+		}
+		if role.BuiltIn {
+			return ErrBuiltInRole
+		}
+		if err := tx.Model(&models.RolePermission{}).Where("role_id = ?", role.ID).
+			Order("permission").Pluck("permission", &current).Error; err != nil {
+			return err
+		}
+		before := roleSnapshot(role, current)
+
+		if req.Description != nil {
+			role.Description = *req.Description
+		}
+		if err := tx.Save(&role).Error; err != nil {
+			return err
+		}
+		if permissions != nil {
+			if err := s.setPermissions(tx, role.ID, permissions); err != nil {
+				return err
+			}
+			current = permissions
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityRole,
+			EntityID:      role.ID,
+			OperationType: "update",
+			Before:        before,
+			After:         roleSnapshot(role, current),
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	cache.Invalidate(rolePermissionsKey(name))
+
+	response := roleResponse(role, current)
+	return &response, nil
+}
+
+// DeleteRole deletes a custom role that is not assigned to any user
+func (s *RoleService) DeleteRole(name string, actor Actor) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var role models.Role
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", name).First(&role).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrRoleNotFound
+			}
+			return err
+		}
+		if role.BuiltIn {
+			return ErrBuiltInRole
+		}
+		var users int64
+		if err := tx.Model(&models.User{}).Where("role = ?", name).Count(&users).Error; err != nil {
+			return err
+		}
+		if users == 0 {
+			if err := tx.Model(&models.StoreUser{}).Where("role = ?", name).Count(&users).Error; err != nil {
+				return err
+			}
+		}
+		if users > 0 {
+			return ErrRoleInUse
+		}
+
+		var permissions []string
+		if err := tx.Model(&models.RolePermission{}).Where("role_id = ?", role.ID).
+			Order("permission").Pluck("permission", &permissions).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", role.ID).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&role).Error; err != nil {
+			return err
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityRole,
+			EntityID:      role.ID,
+			OperationType: "delete",
+			Before:        roleSnapshot(role, permissions),
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	cache.Invalidate(rolePermissionsKey(name))
+	return nil
+}
+
+// setPermissions replaces the permissions of a role within tx
+func (s *RoleService) setPermissions(tx *gorm.DB, roleID uuid.UUID, permissions []string) error {
+	query := tx.Where("role_id = ?", roleID)
+	if len(permissions) > 0 {
+		query = query.Where("permission NOT IN ?", permissions)
+	}
+	if err := query.Delete(&models.RolePermission{}).Error; err != nil {
+		return err
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	grants := make([]models.RolePermission, len(permissions))
+	for i, permission := range permissions {
+		grants[i] = models.RolePermission{RoleID: roleID, Permission: permission}
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&grants).Error
+}
+
+// normalizePermissions validates, deduplicates and sorts permission names
+func normalizePermissions(permissions []string) ([]string, error) {
+	seen := make(map[string]bool, len(permissions))
+	normalized := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !models.IsPermission(permission) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPermission, permission)
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			normalized = append(normalized, permission)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func roleResponse(role models.Role, permissions []string) dto.RoleResponse {
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return dto.RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		BuiltIn:     role.BuiltIn,
+		Permissions: permissions,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
+
+// roleSnapshot returns the role fields tracked in outbox diffs
+func roleSnapshot(role models.Role, permissions []string) map[string]interface{} {
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return map[string]interface{}{
+		"name":        role.Name,
+		"description": role.Description,
+		"permissions": permissions,
+	}
+}
@@ -0,0 +1,482 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// apiKeyPrefix marks API keys, so leaked keys are easy to recognise
+const apiKeyPrefix = "imk_"
+
+// apiKeyTTL bounds how long a cached API key is trusted
+const apiKeyTTL = time.Minute
+
+// apiKeyTouchInterval is the resolution of last-used tracking
+const apiKeyTouchInterval = time.Minute
+
+// apiKeyTouchesSize bounds the keys whose recent use an instance remembers; a key evicted early
+// only has its use recorded again
+const apiKeyTouchesSize = 10000
+
+var (
+	// ErrServiceAccountNotFound is returned for unknown service accounts
+	ErrServiceAccountNotFound = errors.New("service account not found")
+	// ErrServiceAccountExists is returned when creating a service account whose name is taken
+	ErrServiceAccountExists = errors.New("service account already exists")
+	// ErrAPIKeyNotFound is returned for unknown API keys
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidAPIKey is returned for unknown, expired or revoked API keys
+	ErrInvalidAPIKey = errors.New("invalid or expired API key")
+	// ErrUnknownStore is returned when an API key is given a store that does not exist
+	ErrUnknownStore = errors.New("unknown store")
+)
+
+// APIKeyPrincipal is the identity of a request authenticated with an API key
+type APIKeyPrincipal struct {
+	KeyID            uuid.UUID  `json:"key_id"`
+	ServiceAccountID uuid.UUID  `json:"service_account_id"`
+	AccountName      string     `json:"account_name"`
+	Permissions      []string   `json:"permissions"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+}
+
+// ServiceAccountService manages service accounts and authenticates their API keys
+type ServiceAccountService struct {
+	// Using global database and cache instances
+}
+
+// NewServiceAccountService creates a new service account service
+func NewServiceAccountService() *ServiceAccountService {
+	return &ServiceAccountService{}
+}
+
+// apiKeyTouches holds the keys whose use this instance recorded in the last apiKeyTouchInterval
+var apiKeyTouches = cache.NewLRU(apiKeyTouchesSize)
+
+func apiKeyCacheKey(keyHash string) string {
+	return "apikey:" + keyHash
+}
+
+// hashAPIKey returns the stored form of an API key
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate resolves an API key to its principal and records its use
+func (s *ServiceAccountService) Authenticate(rawKey string) (*APIKeyPrincipal, error) {
+	keyHash := hashAPIKey(rawKey)
+	var principal APIKeyPrincipal
+	if !cache.GetJSON(apiKeyCacheKey(keyHash), &principal) {
+		var key models.APIKey
+		if err := database.DB.Where("key_hash = ? AND revoked_at IS NULL", keyHash).First(&key).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrInvalidAPIKey
+			}
+			return nil, fmt.Errorf("failed to query API key: %w", err)
+		}
+		var account models.ServiceAccount
+		if err := database.DB.First(&account, "id = ?", key.ServiceAccountID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrInvalidAPIKey
+			}
+			return nil, fmt.Errorf("failed to query service account: %w", err)
+		}
+		var permissions []string
+		if err := database.DB.Model(&models.APIKeyPermission{}).Where("api_key_id = ?", key.ID).
+			Order("permission").Pluck("permission", &permissions).Error; err != nil {
+			return nil, fmt.Errorf("failed to load API key permissions: %w", err)
+		}
+		principal = APIKeyPrincipal{
+			KeyID:            key.ID,
+			ServiceAccountID: account.ID,
+			AccountName:      account.Name,
+			Permissions:      permissions,
+			ExpiresAt:        key.ExpiresAt,
+		}
+		cache.SetJSON(apiKeyCacheKey(keyHash), principal, apiKeyTTL)
+	}
+
+	if principal.ExpiresAt != nil && !principal.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidAPIKey
+	}
+	s.touch(principal.KeyID)
+	return &principal, nil
+}
+
+// touch records that a key was used, at most once per apiKeyTouchInterval per instance
+func (s *ServiceAccountService) touch(keyID uuid.UUID) {
+	if _, ok := apiKeyTouches.Get(keyID.String()); ok {
+		return
+	}
+	apiKeyTouches.Set(keyID.String(), "", apiKeyTouchInterval)
+	now := time.Now()
+	if err := database.DB.Model(&models.APIKey{}).Where("id = ?", keyID).
+		Update("last_used_at", now).Error; err != nil {
+		log.Printf("Warning: failed to record API key use: %v", err)
+	}
+}
+
+// ListServiceAccounts returns every service account with its API keys
+func (s *ServiceAccountService) ListServiceAccounts() ([]dto.ServiceAccountResponse, error) {
+	var accounts []models.ServiceAccount
+	if err := database.DB.Order("name").Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("failed to query service accounts: %w", err)
+	}
+	var keys []models.APIKey
+	if err := database.DB.Order("created_at").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	keyResponses, err := s.keyResponses(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	byAccount := make(map[uuid.UUID][]dto.APIKeyResponse)
+	for i, key := range keys {
+		byAccount[key.ServiceAccountID] = append(byAccount[key.ServiceAccountID], keyResponses[i])
+	}
+	responses := make([]dto.ServiceAccountResponse, len(accounts))
+	for i, account := range accounts {
+		responses[i] = serviceAccountResponse(account, byAccount[account.ID])
+	}
+	return responses, nil
+}
+
+// CreateServiceAccount creates a service account without keys
+func (s *ServiceAccountService) CreateServiceAccount(req dto.CreateServiceAccountRequest, actor Actor) (*dto.ServiceAccountResponse, error) {
+	account := models.ServiceAccount{Name: req.Name, Description: req.Description}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrServiceAccountExists
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityServiceAccount,
+			EntityID:      account.ID,
+			OperationType: "create",
+			After:         serviceAccountSnapshot(account),
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := serviceAccountResponse(account, nil)
+	return &response, nil
+}
+
+// UpdateServiceAccount changes the description of a service account
+func (s *ServiceAccountService) UpdateServiceAccount(id uuid.UUID, req dto.UpdateServiceAccountRequest, actor Actor) (*dto.ServiceAccountResponse, error) {
+	var account models.ServiceAccount
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "id = ?", id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrServiceAccountNotFound
+			}
+			return err
+		}
+		before := serviceAccountSnapshot(account)
+		if req.Description != nil {
+			account.Description = *req.Description
+		}
+		if err := tx.Save(&account).Error; err != nil {
+			return err
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityServiceAccount,
+			EntityID:      account.ID,
+			OperationType: "update",
+			Before:        before,
+			After:         serviceAccountSnapshot(account),
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []models.APIKey
+	if err := database.DB.Where("service_account_id = ?", id).Order("created_at").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	keyResponses, err := s.keyResponses(keys)
+	if err != nil {
+		return nil, err
+	}
+	response := serviceAccountResponse(account, keyResponses)
+	return &response, nil
+}
+
+// DeleteServiceAccount deletes a service account and all of its API keys
+func (s *ServiceAccountService) DeleteServiceAccount(id uuid.UUID, actor Actor) error {
+	var keys []models.APIKey
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var account models.ServiceAccount
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "id = ?", id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrServiceAccountNotFound
+			}
+			return err
+		}
+		if err := tx.Where("service_account_id = ?", id).Find(&keys).Error; err != nil {
+			return err
+		}
+		keyIDs := make([]uuid.UUID, len(keys))
+		for i, key := range keys {
+			keyIDs[i] = key.ID
+		}
+		if len(keyIDs) > 0 {
+			if err := tx.Where("api_key_id IN ?", keyIDs).Delete(&models.APIKeyPermission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("api_key_id IN ?", keyIDs).Delete(&models.APIKeyStore{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", keyIDs).Delete(&models.APIKey{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&account).Error; err != nil {
+			return err
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityServiceAccount,
+			EntityID:      account.ID,
+			OperationType: "delete",
+			Before:        serviceAccountSnapshot(account),
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		cache.Invalidate(apiKeyCacheKey(key.KeyHash))
+	}
+	return nil
+}
+
+// CreateAPIKey creates an API key for a service account. The key is only returned here;
+// the caller is responsible for checking that it may grant the permissions and stores.
+func (s *ServiceAccountService) CreateAPIKey(accountID uuid.UUID, req dto.CreateAPIKeyRequest, actor Actor) (*dto.CreatedAPIKeyResponse, error) {
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	storeIDs := req.StoreIDs
+	if storeIDs == nil {
+		storeIDs = []uuid.UUID{}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	rawKey := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := models.APIKey{
+		ServiceAccountID: accountID,
+		Name:             req.Name,
+		Prefix:           rawKey[:12],
+		KeyHash:          hashAPIKey(rawKey),
+		ExpiresAt:        req.ExpiresAt,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var account models.ServiceAccount
+		if err := tx.First(&account, "id = ?", accountID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrServiceAccountNotFound
+			}
+			return err
+		}
+		if len(storeIDs) > 0 {
+			var count int64
+			if err := tx.Model(&models.Store{}).Where("id IN ?", storeIDs).Count(&count).Error; err != nil {
+				return err
+			}
+			if int(count) != len(storeIDs) {
+				return ErrUnknownStore
+			}
+		}
+		if err := tx.Create(&key).Error; err != nil {
+			return err
+		}
+		if len(permissions) > 0 {
+			grants := make([]models.APIKeyPermission, len(permissions))
+			for i, permission := range permissions {
+				grants[i] = models.APIKeyPermission{APIKeyID: key.ID, Permission: permission}
+			}
+			if err := tx.Create(&grants).Error; err != nil {
+				return err
+			}
+		}
+		if len(storeIDs) > 0 {
+			stores := make([]models.APIKeyStore, len(storeIDs))
+			for i, storeID := range storeIDs {
+				stores[i] = models.APIKeyStore{APIKeyID: key.ID, StoreID: storeID}
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&stores).Error; err != nil {
+				return err
+			}
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityAPIKey,
+			EntityID:      key.ID,
+			OperationType: "create",
+			After:         apiKeySnapshot(key, account, permissions, storeIDs),
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.CreatedAPIKeyResponse{
+		APIKeyResponse: apiKeyResponse(key, permissions, storeIDs),
+		Key:            rawKey,
+	}, nil
+}
+
+// RevokeAPIKey revokes an API key of a service account; revoked keys are rejected immediately
+func (s *ServiceAccountService) RevokeAPIKey(accountID, keyID uuid.UUID, actor Actor) error {
+	var key models.APIKey
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&key, "id = ? AND service_account_id = ?", keyID, accountID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrAPIKeyNotFound
+			}
+			return err
+		}
+		if key.RevokedAt != nil {
+			return nil
+		}
+		now := time.Now()
+		key.RevokedAt = &now
+		if err := tx.Model(&key).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityAPIKey,
+			EntityID:      key.ID,
+			OperationType: "revoke",
+			Before:        map[string]interface{}{"revoked": false},
+			After:         map[string]interface{}{"revoked": true},
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	cache.Invalidate(apiKeyCacheKey(key.KeyHash))
+	return nil
+}
+
+// keyResponses loads the permissions and stores of keys
+func (s *ServiceAccountService) keyResponses(keys []models.APIKey) ([]dto.APIKeyResponse, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	keyIDs := make([]uuid.UUID, len(keys))
+	for i, key := range keys {
+		keyIDs[i] = key.ID
+	}
+	var grants []models.APIKeyPermission
+	if err := database.DB.Where("api_key_id IN ?", keyIDs).Order("permission").Find(&grants).Error; err != nil {
+		return nil, fmt.Errorf("failed to query API key permissions: %w", err)
+	}
+	var stores []models.APIKeyStore
+	if err := database.DB.Where("api_key_id IN ?", keyIDs).Find(&stores).Error; err != nil {
+		return nil, fmt.Errorf("failed to query API key stores: %w", err)
+	}
+
+	permissions := make(map[uuid.UUID][]string)
+	for _, grant := range grants {
+		permissions[grant.APIKeyID] = append(permissions[grant.APIKeyID], grant.Permission)
+	}
+	storeIDs := make(map[uuid.UUID][]uuid.UUID)
+	for _, store := range stores {
+		storeIDs[store.APIKeyID] = append(storeIDs[store.APIKeyID], store.StoreID)
+	}
+	responses := make([]dto.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = apiKeyResponse(key, permissions[key.ID], storeIDs[key.ID])
+	}
+	return responses, nil
+}
+
+func serviceAccountResponse(account models.ServiceAccount, keys []dto.APIKeyResponse) dto.ServiceAccountResponse {
+	if keys == nil {
+		keys = []dto.APIKeyResponse{}
+	}
+	return dto.ServiceAccountResponse{
+		ID:          account.ID,
+		Name:        account.Name,
+		Description: account.Description,
+		Keys:        keys,
+		CreatedAt:   account.CreatedAt,
+		UpdatedAt:   account.UpdatedAt,
+	}
+}
+
+func apiKeyResponse(key models.APIKey, permissions []string, storeIDs []uuid.UUID) dto.APIKeyResponse {
+	if permissions == nil {
+		permissions = []string{}
+	}
+	if storeIDs == nil {
+		storeIDs = []uuid.UUID{}
+	}
+	return dto.APIKeyResponse{
+		ID:          key.ID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Permissions: permissions,
+		StoreIDs:    storeIDs,
+		ExpiresAt:   key.ExpiresAt,
+		LastUsedAt:  key.LastUsedAt,
+		RevokedAt:   key.RevokedAt,
+		CreatedAt:   key.CreatedAt,
+	}
+}
+
+// serviceAccountSnapshot returns the service account fields tracked in outbox diffs
+func serviceAccountSnapshot(account models.ServiceAccount) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        account.Name,
+		"description": account.Description,
+	}
+}
+
+// apiKeySnapshot returns the API key fields tracked in outbox diffs (never the key or its hash).
+// Like service account events, API key events only reach holders of service_accounts.manage
+// (see websocket.Client.canSee).
+func apiKeySnapshot(key models.APIKey, account models.ServiceAccount, permissions []string, storeIDs []uuid.UUID) map[string]interface{} {
+	return map[string]interface{}{
+		"service_account": account.Name,
+		"name":            key.Name,
+		"prefix":          key.Prefix,
+		"permissions":     permissions,
+		"store_ids":       storeIDs,
+		"expires_at":      key.ExpiresAt,
+	}
+}
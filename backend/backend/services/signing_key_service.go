@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/models"
+	"inventory-manager-server/signing"
+)
+
+// SigningKeyService loads the JWT signing keys into the signing package and rotates generated keys.
+// Keys come from JWT_KEY_FILES / JWT_KEY_DIR when configured; otherwise they are generated and
+// stored in signing_keys, so every instance signs and verifies with the same set.
+type SigningKeyService struct {
+	// Using global database instance and config
+}
+
+// NewSigningKeyService creates a new signing key service
+func NewSigningKeyService() *SigningKeyService {
+	return &SigningKeyService{}
+}
+
+// fromFiles reports whether keys are loaded from files instead of the database
+func (s *SigningKeyService) fromFiles() bool {
+	return len(config.CONFIG.JWTKeyFiles) > 0 || config.CONFIG.JWTKeyDir != ""
+}
+
+// sealKey returns the key that encrypts generated keys in the database
+func (s *SigningKeyService) sealKey() ([]byte, error) {
+	if config.CONFIG.JWTKeyEncryptionKey == "" {
+		return nil, errors.New("JWT_KEY_ENCRYPTION_KEY is required to store generated JWT signing keys " +
+			"(or load keys from JWT_KEY_FILES / JWT_KEY_DIR)")
+	}
+	key, err := signing.ParseSealKey(config.CONFIG.JWTKeyEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_KEY_ENCRYPTION_KEY: %w", err)
+	}
+	return key, nil
+}
+
+// Init loads the key set, generating the first key on a fresh database. Generated keys are
+// never stored unencrypted, so it fails without a valid JWT_KEY_ENCRYPTION_KEY.
+func (s *SigningKeyService) Init() error {
+	signing.SetReloader(s.Reload)
+
+	if !s.fromFiles() {
+		if database.DB == nil {
+			// Tokens signed by this key do not verify on other instances and die with the process
+			k, err := signing.Generate(config.CONFIG.JWTAlgorithm)
+			if err != nil {
+				return err
+			}
+			log.Printf("Warning: database not initialized, signing tokens with temporary key %s", k.ID)
+			return signing.Use([]signing.Key{k}, k.ID)
+		}
+		if _, err := s.sealKey(); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := database.DB.Model(&models.SigningKey{}).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count signing keys: %w", err)
+		}
+		if count == 0 {
+			if _, err := s.createKey(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.Reload()
+}
+
+// Reload re-reads the key set from its source
+func (s *SigningKeyService) Reload() error {
+	if s.fromFiles() {
+		return s.loadFiles()
+	}
+	if database.DB == nil {
+		return nil
+	}
+
+	keys, err := s.storedKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return signing.ErrNoSigningKey
+	}
+	return signing.Use(keys, s.activeKey(keys, time.Now()).ID)
+}
+
+// Watch reloads the key set every JWT_KEY_RELOAD_INTERVAL until ctx is cancelled, picking up
+// keys rotated by other instances and changed key files
+func (s *SigningKeyService) Watch(ctx context.Context) {
+	interval := config.CONFIG.JWTKeyReload
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(); err != nil {
+				log.Printf("Warning: failed to reload JWT signing keys: %v", err)
+			}
+		}
+	}
+}
+
+// RotateKeys generates a new key once the newest one is older than JWT_KEY_ROTATION (or uses
+// another algorithm than JWT_ALGORITHM), and removes keys no unexpired token can be signed with
+func (s *SigningKeyService) RotateKeys(ctx context.Context) error {
+	if s.fromFiles() || config.CONFIG.JWTKeyRotation <= 0 {
+		return nil
+	}
+
+	keys, err := s.storedKeys()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if len(keys) == 0 || s.rotationDue(keys[len(keys)-1], now) {
+		k, err := s.createKey()
+		if err != nil {
+			return err
+		}
+		log.Printf("Generated JWT signing key %s (%s)", k.ID, k.Algorithm)
+		keys = append(keys, k)
+	}
+
+	// A key signs until its successor becomes active on every instance (three reload
+	// intervals), and its tokens stay valid for at most one token lifetime after that (access
+	// tokens, login challenges or impersonation tokens, whichever lives longest)
+	retention := 3*config.CONFIG.JWTKeyReload +
+		max(config.CONFIG.AccessTokenTTL, config.CONFIG.MFAChallengeTTL, config.CONFIG.ImpersonationTTL)
+	var expired []string
+	for i := 0; i < len(keys)-1; i++ {
+		if now.Sub(keys[i+1].CreatedAt) > retention {
+			expired = append(expired, keys[i].ID)
+		}
+	}
+	if len(expired) > 0 {
+		if err := database.DB.WithContext(ctx).Where("id IN ?", expired).Delete(&models.SigningKey{}).Error; err != nil {
+			return fmt.Errorf("failed to delete expired signing keys: %w", err)
+		}
+		log.Printf("Removed %d expired JWT signing key(s)", len(expired))
+	}
+
+	return s.Reload()
+}
+
+func (s *SigningKeyService) rotationDue(newest signing.Key, now time.Time) bool {
+	return newest.Algorithm != config.CONFIG.JWTAlgorithm || now.Sub(newest.CreatedAt) >= config.CONFIG.JWTKeyRotation
+}
+
+// activeKey picks the newest key that is at least two reload intervals old: one for every
+// instance to load it, one for JWKS responses cached without it to expire. On a fresh database
+// all keys are new and the oldest one wins. keys must be sorted oldest first.
+func (s *SigningKeyService) activeKey(keys []signing.Key, now time.Time) signing.Key {
+	cutoff := now.Add(-2 * config.CONFIG.JWTKeyReload)
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !keys[i].CreatedAt.After(cutoff) {
+			return keys[i]
+		}
+	}
+	return keys[0]
+}
+
+// storedKeys returns the generated keys, oldest first. Keys stored unencrypted by earlier
+// versions are encrypted in place.
+func (s *SigningKeyService) storedKeys() ([]signing.Key, error) {
+	sealKey, err := s.sealKey()
+	if err != nil {
+		return nil, err
+	}
+	var rows []models.SigningKey
+	if err := database.DB.Order("created_at ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query signing keys: %w", err)
+	}
+
+	keys := make([]signing.Key, 0, len(rows))
+	for _, row := range rows {
+		encoded := []byte(row.PrivateKey)
+		if signing.Sealed(row.PrivateKey) {
+			if encoded, err = signing.Unseal(sealKey, row.ID, row.PrivateKey); err != nil {
+				log.Printf("Warning: skipping unreadable JWT signing key %s: %v", row.ID, err)
+				continue
+			}
+		}
+		k, err := signing.ParsePEM(row.ID, encoded)
+		if err != nil {
+			log.Printf("Warning: skipping unreadable JWT signing key %s: %v", row.ID, err)
+			continue
+		}
+		if !signing.Sealed(row.PrivateKey) {
+			if err := s.sealStored(sealKey, row.ID, encoded); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+		k.CreatedAt = row.CreatedAt
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// sealStored replaces an unencrypted stored key by its encrypted form
+func (s *SigningKeyService) sealStored(sealKey []byte, id string, encoded []byte) error {
+	sealed, err := signing.Seal(sealKey, id, encoded)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt signing key %s: %w", id, err)
+	}
+	if err := database.DB.Model(&models.SigningKey{}).Where("id = ? AND private_key = ?", id, string(encoded)).
+		Update("private_key", sealed).Error; err != nil {
+		return fmt.Errorf("failed to encrypt signing key %s: %w", id, err)
+	}
+	log.Printf("Encrypted stored JWT signing key %s", id)
+	return nil
+}
+
+func (s *SigningKeyService) createKey() (signing.Key, error) {
+	sealKey, err := s.sealKey()
+	if err != nil {
+		return signing.Key{}, err
+	}
+	k, err := signing.Generate(config.CONFIG.JWTAlgorithm)
+	if err != nil {
+		return signing.Key{}, err
+	}
+	encoded, err := signing.MarshalPEM(k)
+	if err != nil {
+		return signing.Key{}, err
+	}
+	sealed, err := signing.Seal(sealKey, k.ID, encoded)
+	if err != nil {
+		return signing.Key{}, fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+	row := models.SigningKey{ID: k.ID, Algorithm: k.Algorithm, PrivateKey: sealed, CreatedAt: k.CreatedAt}
+	if err := database.DB.Create(&row).Error; err != nil {
+		return signing.Key{}, fmt.Errorf("failed to store signing key: %w", err)
+	}
+	return k, nil
+}
+
+// loadFiles loads JWT_KEY_FILES and the keys in JWT_KEY_DIR; JWT_SIGNING_KEY_ID (or the last
+// key ID in sort order) signs
+func (s *SigningKeyService) loadFiles() error {
+	keys, err := signing.LoadFiles(config.CONFIG.JWTKeyFiles)
+	if err != nil {
+		return err
+	}
+	if config.CONFIG.JWTKeyDir != "" {
+		dirKeys, err := signing.LoadDir(config.CONFIG.JWTKeyDir)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, dirKeys...)
+	}
+	if len(keys) == 0 {
+		return errors.New("no JWT signing keys found in JWT_KEY_FILES or JWT_KEY_DIR")
+	}
+
+	activeID := config.CONFIG.JWTSigningKeyID
+	if activeID == "" {
+		sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+		activeID = keys[len(keys)-1].ID
+	}
+	return signing.Use(keys, activeID)
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/database"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// storeAssignmentsTTL bounds how long a user's cached store assignments may be served
+// (they are invalidated on every change, so this only matters if an invalidation is lost)
+const storeAssignmentsTTL = 10 * time.Minute
+
+// systemActor makes changes that no user requested (scheduled cleanups)
+var systemActor = Actor{Name: "system"}
+
+// storeAssignment is the cached part of a store assignment; validity is checked on every use,
+// so assignments start and end on time without invalidation
+type storeAssignment struct {
+	StoreID   uuid.UUID  `json:"store_id"`
+	Role      *string    `json:"role"`
+	ValidFrom *time.Time `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to"`
+}
+
+func (a storeAssignment) activeAt(t time.Time) bool {
+	return models.StoreUser{ValidFrom: a.ValidFrom, ValidTo: a.ValidTo}.ActiveAt(t)
+}
+
+// storeAssignmentsKey returns the cache key of a user's store assignments
+func storeAssignmentsKey(userID uuid.UUID) string {
+	return "rbac:stores:" + userID.String()
+}
+
+// StoreAssignmentService resolves the stores a user may access from their store assignments
+type StoreAssignmentService struct {
+	// Using global database and cache instances
+}
+
+// NewStoreAssignmentService creates a new store assignment service
+func NewStoreAssignmentService() *StoreAssignmentService {
+	return &StoreAssignmentService{}
+}
+
+// assignments returns every store assignment of a user, including inactive ones
+func (s *StoreAssignmentService) assignments(userID uuid.UUID) ([]storeAssignment, error) {
+	var assignments []storeAssignment
+	if cache.GetJSON(storeAssignmentsKey(userID), &assignments) {
+		return assignments, nil
+	}
+
+	var rows []models.StoreUser
+	if err := database.DB.Select("store_id", "role", "valid_from", "valid_to").
+		Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load store assignments: %w", err)
+	}
+	assignments = make([]storeAssignment, len(rows))
+	for i, row := range rows {
+		assignments[i] = storeAssignment{StoreID: row.StoreID, Role: row.Role, ValidFrom: row.ValidFrom, ValidTo: row.ValidTo}
+	}
+	cache.SetJSON(storeAssignmentsKey(userID), assignments, storeAssignmentsTTL)
+	return assignments, nil
+}
+
+// StoresWithPermission returns the stores in which the user currently holds permission: every
+// active assignment if the user's own permissions include it, otherwise those whose store role
+// grants it. An empty permission matches every active assignment.
+func (s *StoreAssignmentService) StoresWithPermission(userID uuid.UUID, permissions PermissionSet, permission string) ([]uuid.UUID, error) {
+	assignments, err := s.assignments(userID)
+	if err != nil {
+		return nil, err
+	}
+	fromRole := permission == "" || permissions.Has(permission)
+
+	now := time.Now()
+	stores := make([]uuid.UUID, 0, len(assignments))
+	for _, a := range assignments {
+		if !a.activeAt(now) {
+			continue
+		}
+		if !fromRole {
+			granted, err := s.roleGrants(a.Role, permission)
+			if err != nil {
+				return nil, err
+			}
+			if !granted {
+				continue
+			}
+		}
+		stores = append(stores, a.StoreID)
+	}
+	return stores, nil
+}
+
+// AssignmentGrants reports whether a store role of one of the user's active assignments grants permission
+func (s *StoreAssignmentService) AssignmentGrants(userID uuid.UUID, permission string) (bool, error) {
+	stores, err := s.StoresWithPermission(userID, nil, permission)
+	return len(stores) > 0, err
+}
+
+func (s *StoreAssignmentService) roleGrants(role *string, permission string) (bool, error) {
+	if role == nil {
+		return false, nil
+	}
+	permissions, err := NewRoleService().Permissions(*role)
+	if err != nil {
+		return false, err
+	}
+	return permissions.Has(permission), nil
+}
+
+// storeRolePermissions returns the permissions granted by the store roles of the user's active
+// assignments
+func (s *StoreAssignmentService) storeRolePermissions(userID uuid.UUID) (PermissionSet, error) {
+	assignments, err := s.assignments(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	set := PermissionSet{}
+	for _, a := range assignments {
+		if a.Role == nil || !a.activeAt(now) {
+			continue
+		}
+		permissions, err := NewRoleService().Permissions(*a.Role)
+		if err != nil {
+			return nil, err
+		}
+		for name := range permissions {
+			set[name] = true
+		}
+	}
+	return set, nil
+}
+
+// StoreRoles returns the store roles of the user's assignments, active or not
+func (s *StoreAssignmentService) StoreRoles(userID uuid.UUID) ([]string, error) {
+	assignments, err := s.assignments(userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]string, 0, len(assignments))
+	for _, a := range assignments {
+		if a.Role != nil {
+			roles = append(roles, *a.Role)
+		}
+	}
+	return roles, nil
+}
+
+// AssignedUser returns the user of the store assignment with the given ID
+func (s *StoreAssignmentService) AssignedUser(id uuid.UUID) (uuid.UUID, error) {
+	var storeUser models.StoreUser
+	if err := database.DB.Select("user_id").Where("id = ?", id).Take(&storeUser).Error; err != nil {
+		return uuid.Nil, fmt.Errorf("failed to load store assignment: %w", err)
+	}
+	return storeUser.UserID, nil
+}
+
+// Invalidate drops the cached store assignments of users after their assignments changed
+func (s *StoreAssignmentService) Invalidate(userIDs ...uuid.UUID) {
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = storeAssignmentsKey(id)
+	}
+	cache.Invalidate(keys...)
+}
+
+// PurgeExpiredAssignments deletes store assignments whose period has ended and refreshes the
+// affected users' cached assignments
+func (s *StoreAssignmentService) PurgeExpiredAssignments(ctx context.Context) error {
+	var expired []models.StoreUser
+	if err := database.DB.WithContext(ctx).Preload("User").Preload("Store").
+		Where("valid_to <= ?", time.Now()).Find(&expired).Error; err != nil {
+		return fmt.Errorf("failed to query expired store assignments: %w", err)
+	}
+
+	affected := make(map[uuid.UUID]bool)
+	for _, storeUser := range expired {
+		err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&storeUser).Error; err != nil {
+				return err
+			}
+			return RecordEntityChange(tx, EntityChange{
+				EntityType:    models.EntityStoreUser,
+				EntityID:      storeUser.ID,
+				OperationType: "expire",
+				Version:       storeUser.Version + 1,
+				Before:        StoreUserSnapshot(storeUser, storeUser.User.Username),
+				Actor:         systemActor,
+				StoreID:       storeUser.StoreID,
+				StoreName:     storeUser.Store.Name,
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete expired store assignment: %w", err)
+		}
+		affected[storeUser.UserID] = true
+	}
+
+	if len(affected) > 0 {
+		userIDs := make([]uuid.UUID, 0, len(affected))
+		for id := range affected {
+			userIDs = append(userIDs, id)
+		}
+		s.Invalidate(userIDs...)
+		log.Printf("Removed %d expired store assignments of %d users", len(expired), len(userIDs))
+	}
+	return nil
+}
+
+// StoreUserSnapshot returns the store assignment fields tracked in outbox diffs
+func StoreUserSnapshot(storeUser models.StoreUser, username string) map[string]interface{} {
+	return map[string]interface{}{
+		"store_id":   storeUser.StoreID.String(),
+		"user_id":    storeUser.UserID.String(),
+		"username":   username,
+		"role":       storeUser.Role,
+		"valid_from": storeUser.ValidFrom,
+		"valid_to":   storeUser.ValidTo,
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+
+	"inventory-manager-server/database"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrStoreForbidden is returned when a store is outside the caller's store scope
+var ErrStoreForbidden = errors.New("store access denied")
+
+// StoreScope limits data access to the stores a caller may access. Every query on a
+// store-scoped table (inventory) must be built through a StoreScope; unscoped queries
+// fail (see database.StoreScopeKey).
+type StoreScope struct {
+	allStores bool
+	resolve   func() ([]uuid.UUID, error) // Returns the allowed store IDs
+}
+
+// UserStoreScope limits access to the stores where the user holds permission through an active
+// store assignment (see StoreAssignmentService.StoresWithPermission); with inventory.all_stores
+// and the permission itself, the scope covers every store. An empty permission only requires
+// an active assignment.
+func UserStoreScope(userID uuid.UUID, permissions PermissionSet, permission string) StoreScope {
+	if permissions.Has(models.PermInventoryAllStores) && (permission == "" || permissions.Has(permission)) {
+		return StoreScope{allStores: true}
+	}
+	return StoreScope{resolve: func() ([]uuid.UUID, error) {
+		return NewStoreAssignmentService().StoresWithPermission(userID, permissions, permission)
+	}}
+}
+
+// APIKeyStoreScope limits access to the stores of an API key, unless allStores is set
+func APIKeyStoreScope(keyID uuid.UUID, allStores bool) StoreScope {
+	return StoreScope{allStores: allStores, resolve: func() ([]uuid.UUID, error) {
+		var ids []uuid.UUID
+		err := database.DB.Model(&models.APIKeyStore{}).Where("api_key_id = ?", keyID).Pluck("store_id", &ids).Error
+		return ids, err
+	}}
+}
+
+// SystemScope grants access to every store, for work that is not done on behalf of a user
+// (background jobs, event sinks, integrity checks)
+var SystemScope = StoreScope{allStores: true}
+
+// AllStores reports whether the scope covers every store
+func (s StoreScope) AllStores() bool {
+	return s.allStores
+}
+
+// Inventory starts a query on the inventory table limited to the scope's stores
+func (s StoreScope) Inventory(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Inventory{}).Scopes(s.Apply("inventory.store_id"))
+}
+
+// RawSQL marks a raw SQL statement on store-scoped tables. Raw SQL cannot be limited to the
+// scope's stores, so only scopes covering every store may run it; callers filter by store
+// themselves.
+func (s StoreScope) RawSQL(db *gorm.DB) *gorm.DB {
+	db = db.Set(database.StoreScopeKey, s)
+	if !s.allStores {
+		db.AddError(ErrStoreForbidden)
+	}
+	return db
+}
+
+// Apply returns a GORM scope limiting column (a store ID) to the scope's stores
+func (s StoreScope) Apply(column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Set(database.StoreScopeKey, s)
+		if s.allStores {
+			return db
+		}
+		ids, err := s.StoreIDs()
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		if len(ids) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(column+" IN ?", ids)
+	}
+}
+
+// StoreIDs returns the stores in the scope (nil if the scope covers every store)
+func (s StoreScope) StoreIDs() ([]uuid.UUID, error) {
+	if s.allStores || s.resolve == nil {
+		return nil, nil
+	}
+	return s.resolve()
+}
+
+// CheckStore returns ErrStoreForbidden if storeID is outside the scope
+func (s StoreScope) CheckStore(storeID uuid.UUID) error {
+	if s.allStores {
+		return nil
+	}
+	ids, err := s.StoreIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id == storeID {
+			return nil
+		}
+	}
+	return ErrStoreForbidden
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// dryRunPool lets dry-run statements run in transactions; nothing reaches a database
+type dryRunPool struct{}
+
+var errDryRun = errors.New("dry run")
+
+func (*dryRunPool) PrepareContext(context.Context, string) (*sql.Stmt, error) { return nil, errDryRun }
+func (*dryRunPool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errDryRun
+}
+func (*dryRunPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errDryRun
+}
+func (*dryRunPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row { return nil }
+func (p *dryRunPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+func (*dryRunPool) Commit() error   { return nil }
+func (*dryRunPool) Rollback() error { return nil }
+
+// useStatementLog points the global database at a dry run for the duration of the test and
+// returns the SQL of the statements built on the given tables
+func useStatementLog(t *testing.T, tables ...string) *[]string {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunPool{}}), &gorm.Config{
+		DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("failed to open dry-run database: %v", err)
+	}
+	logged := map[string]bool{}
+	for _, table := range tables {
+		logged[table] = true
+	}
+	var statements []string
+	record := func(db *gorm.DB) {
+		if logged[db.Statement.Table] {
+			statements = append(statements, db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...))
+		}
+	}
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Query().After("gorm:query").Register("test:log", record),
+		callbacks.Row().After("gorm:row").Register("test:log", record),
+		callbacks.Update().After("gorm:update").Register("test:log", record),
+		callbacks.Delete().After("gorm:delete").Register("test:log", record),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+	return &statements
+}
+
+// assignStaff caches a staff member assigned to storeID and returns them with their permissions
+func assignStaff(t *testing.T, storeID uuid.UUID) (uuid.UUID, PermissionSet) {
+	t.Helper()
+	userID := uuid.New()
+	cache.SetJSON(storeAssignmentsKey(userID), []storeAssignment{{StoreID: storeID}}, time.Minute)
+	t.Cleanup(func() { cache.Invalidate(storeAssignmentsKey(userID)) })
+
+	permissions := PermissionSet{}
+	for _, name := range models.BuiltInRoles[models.RoleStaff] {
+		permissions[name] = true
+	}
+	return userID, permissions
+}
+
+// checkOnlyStore fails unless every statement is limited to storeID and never names otherStoreID
+func checkOnlyStore(t *testing.T, statements []string, column string, storeID, otherStoreID uuid.UUID) {
+	t.Helper()
+	if len(statements) == 0 {
+		t.Fatal("no statements were built")
+	}
+	for _, statement := range statements {
+		if !strings.Contains(statement, column+" IN ('"+storeID.String()+"')") {
+			t.Errorf("statement is not limited to the assigned store: %s", statement)
+		}
+		if strings.Contains(statement, otherStoreID.String()) {
+			t.Errorf("statement names another store: %s", statement)
+		}
+	}
+}
+
+func TestStaffScopeExcludesOtherStores(t *testing.T) {
+	assigned, other := uuid.New(), uuid.New()
+	staff, permissions := assignStaff(t, assigned)
+
+	// Inventory routes require inventory.read; store routes only an assignment
+	for _, permission := range []string{models.PermInventoryRead, ""} {
+		scope := UserStoreScope(staff, permissions, permission)
+		if scope.AllStores() {
+			t.Fatalf("staff scope for %q covers every store", permission)
+		}
+		if err := scope.CheckStore(assigned); err != nil {
+			t.Errorf("CheckStore(assigned) for %q = %v", permission, err)
+		}
+		if err := scope.CheckStore(other); !errors.Is(err, ErrStoreForbidden) {
+			t.Errorf("CheckStore(other) for %q = %v, want %v", permission, err, ErrStoreForbidden)
+		}
+	}
+}
+
+func TestStaffInventoryReadsOnlyAssignedStores(t *testing.T) {
+	assigned, other := uuid.New(), uuid.New()
+	staff, permissions := assignStaff(t, assigned)
+	scope := UserStoreScope(staff, permissions, models.PermInventoryRead)
+	inventory := NewInventoryService()
+	statements := useStatementLog(t, "inventory")
+	params := dto.InventoryQueryParams{Page: 1, PageSize: 20}
+
+	if _, err := inventory.GetInventory(scope, params, &other, nil); !errors.Is(err, ErrStoreForbidden) {
+		t.Fatalf("GetInventory(other store) error = %v, want %v", err, ErrStoreForbidden)
+	}
+	if len(*statements) > 0 {
+		t.Fatalf("GetInventory(other store) queried inventory: %v", *statements)
+	}
+
+	if _, err := inventory.GetInventory(scope, params, nil, nil); err != nil {
+		t.Fatalf("GetInventory: %v", err)
+	}
+	// An ID of another store's inventory only matches inventory of the assigned store
+	if _, err := inventory.GetInventoryByID(scope, uuid.New()); err != nil {
+		t.Fatalf("GetInventoryByID: %v", err)
+	}
+	checkOnlyStore(t, *statements, "inventory.store_id", assigned, other)
+}
+
+func TestStaffInventoryWritesOnlyAssignedStores(t *testing.T) {
+	previous := config.CONFIG
+	config.CONFIG = &config.Config{InstanceID: "test"}
+	t.Cleanup(func() { config.CONFIG = previous })
+
+	assigned, other := uuid.New(), uuid.New()
+	staff, permissions := assignStaff(t, assigned)
+	scope := UserStoreScope(staff, permissions, models.PermInventoryAdjust)
+	inventory := NewInventoryService()
+	statements := useStatementLog(t, "inventory")
+
+	req := dto.CreateInventoryRequest{SKUID: uuid.New(), StoreID: other, Quantity: 1}
+	if _, err := inventory.CreateInventory(scope, req, staff, "staff"); !errors.Is(err, ErrStoreForbidden) {
+		t.Fatalf("CreateInventory(other store) error = %v, want %v", err, ErrStoreForbidden)
+	}
+	if len(*statements) > 0 {
+		t.Fatalf("CreateInventory(other store) touched inventory: %v", *statements)
+	}
+
+	// Writes by ID lock, change and delete only rows of the assigned store
+	id := uuid.New()
+	if _, err := inventory.UpdateInventory(scope, id, 5, staff, "staff"); err != nil {
+		t.Fatalf("UpdateInventory: %v", err)
+	}
+	if _, err := inventory.AdjustInventory(scope, id, 1, staff, "staff"); err != nil {
+		t.Fatalf("AdjustInventory: %v", err)
+	}
+	if err := inventory.DeleteInventory(scope, id, staff, "staff"); err != nil {
+		t.Fatalf("DeleteInventory: %v", err)
+	}
+	checkOnlyStore(t, *statements, "inventory.store_id", assigned, other)
+}
+
+func TestStaffSummariesCoverOnlyAssignedStores(t *testing.T) {
+	assigned, other := uuid.New(), uuid.New()
+	staff, permissions := assignStaff(t, assigned)
+	if permissions.Has(models.PermReportsRead) {
+		t.Fatal("staff may read the stock summary report of every store")
+	}
+	statements := useStatementLog(t, "stock_summary")
+
+	// Dry runs cannot return rows, which is reported once the statement was built
+	if _, err := NewStoreService().AssignedStores(staff, true); err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatalf("AssignedStores: %v", err)
+	}
+	checkOnlyStore(t, *statements, "store_id", assigned, other)
+}
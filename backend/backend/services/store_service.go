@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrStoreNotFound is returned when a store does not exist
+var ErrStoreNotFound = errors.New("store not found")
+
+// StoreService serves store information to the staff assigned to the stores
+type StoreService struct {
+	assignments *StoreAssignmentService
+	summary     *SummaryService
+}
+
+// NewStoreService creates a new store service
+func NewStoreService() *StoreService {
+	return &StoreService{
+		assignments: NewStoreAssignmentService(),
+		summary:     NewSummaryService(),
+	}
+}
+
+// AssignedStores returns the stores of the user's active assignments, sorted by name, with
+// their stock counts if withStock is set
+func (s *StoreService) AssignedStores(userID uuid.UUID, withStock bool) ([]dto.AssignedStore, error) {
+	assignments, err := s.assignments.assignments(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	active := make(map[uuid.UUID]storeAssignment, len(assignments))
+	storeIDs := make([]uuid.UUID, 0, len(assignments))
+	for _, a := range assignments {
+		if a.activeAt(now) {
+			active[a.StoreID] = a
+			storeIDs = append(storeIDs, a.StoreID)
+		}
+	}
+	result := make([]dto.AssignedStore, 0, len(storeIDs))
+	if len(storeIDs) == 0 {
+		return result, nil
+	}
+
+	var stores []models.Store
+	if err := database.DB.Where("id IN ?", storeIDs).Find(&stores).Error; err != nil {
+		return nil, fmt.Errorf("failed to query stores: %w", err)
+	}
+	var counts map[uuid.UUID]dto.StoreStockCounts
+	if withStock {
+		if counts, err = s.summary.StoreCounts(storeIDs...); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, store := range stores {
+		a := active[store.ID]
+		assigned := dto.AssignedStore{
+			StoreResponse: storeResponse(store),
+			StoreRole:     a.Role,
+			ValidFrom:     a.ValidFrom,
+			ValidTo:       a.ValidTo,
+		}
+		if withStock {
+			stock := counts[store.ID]
+			assigned.Stock = &stock
+		}
+		result = append(result, assigned)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// GetStore returns a store with its stock counts and, if userID is set, the terms of the user's
+// active assignment to it. Callers check store access first (see StoreScope.CheckStore).
+func (s *StoreService) GetStore(storeID uuid.UUID, userID uuid.UUID) (*dto.StoreDetailResponse, error) {
+	var store models.Store
+	if err := database.DB.First(&store, "id = ?", storeID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrStoreNotFound
+		}
+		return nil, err
+	}
+	counts, err := s.summary.StoreCounts(storeID)
+	if err != nil {
+		return nil, err
+	}
+	response := &dto.StoreDetailResponse{
+		StoreResponse: storeResponse(store),
+		Stock:         counts[storeID],
+	}
+
+	if userID == uuid.Nil {
+		return response, nil
+	}
+	assignments, err := s.assignments.assignments(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, a := range assignments {
+		if a.StoreID == storeID && a.activeAt(now) {
+			response.Assigned = true
+			response.StoreRole = a.Role
+			response.ValidFrom = a.ValidFrom
+			response.ValidTo = a.ValidTo
+			break
+		}
+	}
+	return response, nil
+}
+
+func storeResponse(store models.Store) dto.StoreResponse {
+	return dto.StoreResponse{
+		ID:        store.ID,
+		Name:      store.Name,
+		Address:   store.Address,
+		CreatedAt: store.CreatedAt,
+		UpdatedAt: store.UpdatedAt,
+	}
+}
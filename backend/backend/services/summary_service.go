@@ -0,0 +1,251 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"inventory-manager-server/database"
+	"inventory-manager-server/dto"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// summarySelect aggregates inventory into stock_summary rows (store × category)
+const summarySelect = `
+SELECT i.store_id, COALESCE(s.category, '') AS category,
+	COUNT(*) AS sku_count,
+	COALESCE(SUM(i.quantity), 0) AS total_quantity,
+	COUNT(*) FILTER (WHERE i.quantity = 0) AS out_of_stock_count,
+	COALESCE(SUM(i.quantity * COALESCE(s.price, 0)), 0) AS total_value,
+	NOW() AS refreshed_at
+FROM inventory i
+JOIN sku s ON s.id = i.sku_id`
+
+const summaryInsert = `INSERT INTO stock_summary (store_id, category, sku_count, total_quantity, out_of_stock_count, total_value, refreshed_at)`
+
+// summaryUpsert overwrites rows that already exist, so refreshes and rebuilds running at the
+// same time never conflict on (store_id, category)
+const summaryUpsert = `
+ON CONFLICT (store_id, category) DO UPDATE SET
+	sku_count = EXCLUDED.sku_count,
+	total_quantity = EXCLUDED.total_quantity,
+	out_of_stock_count = EXCLUDED.out_of_stock_count,
+	total_value = EXCLUDED.total_value,
+	refreshed_at = EXCLUDED.refreshed_at`
+
+// SummaryService maintains the stock summary projection
+type SummaryService struct {
+	// Using global database instance
+}
+
+// NewSummaryService creates a new summary service
+func NewSummaryService() *SummaryService {
+	return &SummaryService{}
+}
+
+// RefreshStores recomputes the summary rows of the given stores from inventory. Rows are
+// upserted, then the categories the stores no longer stock are removed: rows left with an older
+// refreshed_at than this transaction's (NOW() is fixed per transaction).
+func (s *SummaryService) RefreshStores(storeIDs ...uuid.UUID) error {
+	if len(storeIDs) == 0 {
+		return nil
+	}
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		sql := summaryInsert + summarySelect + ` WHERE i.store_id IN ? GROUP BY i.store_id, COALESCE(s.category, '')` + summaryUpsert
+		if err := SystemScope.RawSQL(tx).Exec(sql, storeIDs).Error; err != nil {
+			return fmt.Errorf("failed to refresh stock summary: %w", err)
+		}
+		if err := tx.Where("store_id IN ? AND refreshed_at < NOW()", storeIDs).Delete(&models.StockSummary{}).Error; err != nil {
+			return fmt.Errorf("failed to clear stock summary: %w", err)
+		}
+		return nil
+	})
+}
+
+// RemoveStores deletes the summary rows of the given stores (e.g. after a store was deleted)
+func (s *SummaryService) RemoveStores(storeIDs ...uuid.UUID) error {
+	if len(storeIDs) == 0 {
+		return nil
+	}
+	if err := database.DB.Where("store_id IN ?", storeIDs).Delete(&models.StockSummary{}).Error; err != nil {
+		return fmt.Errorf("failed to remove stock summary: %w", err)
+	}
+	return nil
+}
+
+// MarkApplied records that the projection reflects the event created at eventAt
+func (s *SummaryService) MarkApplied(eventAt time.Time) error {
+	if eventAt.IsZero() {
+		return nil
+	}
+	return advanceCheckpoint(database.DB, models.CheckpointProjection, eventAt)
+}
+
+// RefreshSKU recomputes the summary rows of every store stocking a SKU (e.g. after a category or price change)
+func (s *SummaryService) RefreshSKU(skuID uuid.UUID) error {
+	var storeIDs []uuid.UUID
+	if err := SystemScope.Inventory(database.DB).Where("sku_id = ?", skuID).
+		Distinct("store_id").Pluck("store_id", &storeIDs).Error; err != nil {
+		return fmt.Errorf("failed to query stores for SKU: %w", err)
+	}
+	return s.RefreshStores(storeIDs...)
+}
+
+// RebuildAll recomputes every summary row from inventory (repair job), upserting like
+// RefreshStores so it can run while the projection applies events
+func (s *SummaryService) RebuildAll() error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		sql := summaryInsert + summarySelect + ` GROUP BY i.store_id, COALESCE(s.category, '')` + summaryUpsert
+		if err := SystemScope.RawSQL(tx).Exec(sql).Error; err != nil {
+			return fmt.Errorf("failed to rebuild stock summary: %w", err)
+		}
+		if err := tx.Exec("DELETE FROM stock_summary WHERE refreshed_at < NOW()").Error; err != nil {
+			return fmt.Errorf("failed to clear stock summary: %w", err)
+		}
+		// Every event written so far is reflected now
+		newest, err := newestEvent(tx)
+		if err != nil || newest.IsZero() {
+			return err
+		}
+		return advanceCheckpoint(tx, models.CheckpointProjection, newest)
+	})
+}
+
+// newestEvent returns the creation time of the newest event, whether still in the outbox or
+// already published; zero if there has been none
+func newestEvent(tx *gorm.DB) (time.Time, error) {
+	var newest struct{ EventAt *time.Time }
+	if err := tx.Raw(`SELECT GREATEST(
+	(SELECT MAX(created_at) FROM outbox),
+	(SELECT event_at FROM event_checkpoints WHERE name = ?)) AS event_at`, models.CheckpointPublished).
+		Scan(&newest).Error; err != nil {
+		return time.Time{}, fmt.Errorf("failed to query newest event: %w", err)
+	}
+	if newest.EventAt == nil {
+		return time.Time{}, nil
+	}
+	return *newest.EventAt, nil
+}
+
+// GetSummary reads the projection, optionally limited to one store
+func (s *SummaryService) GetSummary(storeID *uuid.UUID) (*dto.StockSummaryResponse, error) {
+	query := database.DB.Model(&models.StockSummary{})
+	if storeID != nil {
+		query = query.Where("store_id = ?", *storeID)
+	}
+	var rows []models.StockSummary
+	if err := query.Order("store_id ASC, category ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query stock summary: %w", err)
+	}
+
+	// Resolve store names
+	storeNames := make(map[uuid.UUID]string)
+	storeIDs := make([]uuid.UUID, 0)
+	for _, row := range rows {
+		if _, ok := storeNames[row.StoreID]; !ok {
+			storeNames[row.StoreID] = ""
+			storeIDs = append(storeIDs, row.StoreID)
+		}
+	}
+	if len(storeIDs) > 0 {
+		var stores []models.Store
+		if err := database.DB.Where("id IN ?", storeIDs).Find(&stores).Error; err != nil {
+			return nil, fmt.Errorf("failed to query stores: %w", err)
+		}
+		for _, store := range stores {
+			storeNames[store.ID] = store.Name
+		}
+	}
+
+	response := &dto.StockSummaryResponse{
+		Stores:     []dto.StoreStockSummary{},
+		Categories: make([]dto.StockSummaryRow, len(rows)),
+	}
+	storeIndex := make(map[uuid.UUID]int)
+	for i, row := range rows {
+		response.Categories[i] = dto.StockSummaryRow{
+			StoreID:         row.StoreID,
+			StoreName:       storeNames[row.StoreID],
+			Category:        row.Category,
+			SKUCount:        row.SKUCount,
+			TotalQuantity:   row.TotalQuantity,
+			OutOfStockCount: row.OutOfStockCount,
+			TotalValue:      row.TotalValue,
+			RefreshedAt:     row.RefreshedAt,
+		}
+
+		idx, ok := storeIndex[row.StoreID]
+		if !ok {
+			idx = len(response.Stores)
+			storeIndex[row.StoreID] = idx
+			response.Stores = append(response.Stores, dto.StoreStockSummary{
+				StoreID:   row.StoreID,
+				StoreName: storeNames[row.StoreID],
+			})
+		}
+		total := &response.Stores[idx]
+		total.SKUCount += row.SKUCount
+		total.TotalQuantity += row.TotalQuantity
+		total.OutOfStockCount += row.OutOfStockCount
+		total.TotalValue += row.TotalValue
+	}
+
+	// The projection lags by the events written after the newest one it applied
+	var applied models.EventCheckpoint
+	err := database.DB.Where("name = ?", models.CheckpointProjection).Take(&applied).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to query projection checkpoint: %w", err)
+	}
+	newest, err := newestEvent(database.DB)
+	if err != nil {
+		return nil, err
+	}
+	if !applied.EventAt.IsZero() {
+		response.AsOf = &applied.EventAt
+		if newest.After(applied.EventAt) {
+			response.StaleSeconds = newest.Sub(applied.EventAt).Seconds()
+		}
+	}
+
+	return response, nil
+}
+
+// StoreCounts returns the stock counts of the given stores from the projection; stores without
+// summary rows get zero counts
+func (s *SummaryService) StoreCounts(storeIDs ...uuid.UUID) (map[uuid.UUID]dto.StoreStockCounts, error) {
+	counts := make(map[uuid.UUID]dto.StoreStockCounts, len(storeIDs))
+	for _, id := range storeIDs {
+		counts[id] = dto.StoreStockCounts{}
+	}
+	if len(storeIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		StoreID         uuid.UUID
+		SKUCount        int64 `gorm:"column:sku_count"`
+		TotalQuantity   int64
+		OutOfStockCount int64
+		AsOf            time.Time
+	}
+	if err := database.DB.Model(&models.StockSummary{}).
+		Select("store_id, SUM(sku_count) AS sku_count, SUM(total_quantity) AS total_quantity, "+
+			"SUM(out_of_stock_count) AS out_of_stock_count, MIN(refreshed_at) AS as_of").
+		Where("store_id IN ?", storeIDs).
+		Group("store_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query stock summary: %w", err)
+	}
+	for _, row := range rows {
+		asOf := row.AsOf
+		counts[row.StoreID] = dto.StoreStockCounts{
+			SKUCount:        row.SKUCount,
+			TotalQuantity:   row.TotalQuantity,
+			OutOfStockCount: row.OutOfStockCount,
+			AsOf:            &asOf,
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"inventory-manager-server/config"
+	"inventory-manager-server/database"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrUserDeactivated is returned when a deactivated user logs in or is deactivated again
+	ErrUserDeactivated = errors.New("user is deactivated")
+	// ErrUserActive is returned when reactivating a user who is not deactivated
+	ErrUserActive = errors.New("user is active")
+	// ErrUserAnonymized is returned when changing a user whose personal data was erased
+	ErrUserAnonymized = errors.New("user has been anonymized")
+)
+
+// UserService deactivates, reactivates and anonymizes users. Users are never deleted, so
+// inventory history and the audit log keep pointing at them.
+type UserService struct {
+	// Using global database instance
+}
+
+// NewUserService creates a new user service
+func NewUserService() *UserService {
+	return &UserService{}
+}
+
+// Deactivate blocks a user: every session is revoked and logins and password resets are refused
+// until the user is reactivated. Their store assignments are kept.
+func (s *UserService) Deactivate(userID uuid.UUID, reason string, actor Actor) (models.User, error) {
+	var user models.User
+	authService := NewAuthService()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.lock(tx, &user, userID); err != nil {
+			return err
+		}
+		if !user.Active() {
+			return ErrUserDeactivated
+		}
+
+		before := userStatusSnapshot(user)
+		now := time.Now()
+		user.DeactivatedAt = &now
+		user.DeactivationReason = reason
+		if err := tx.Model(&user).Select("deactivated_at", "deactivation_reason").Updates(&user).Error; err != nil {
+			return err
+		}
+		if err := authService.RevokeUserTokens(tx, user.ID); err != nil {
+			return err
+		}
+		// Outstanding reset links must not outlive the deactivation
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityUser,
+			EntityID:      user.ID,
+			OperationType: "deactivate",
+			Before:        before,
+			After:         userStatusSnapshot(user),
+			Actor:         actor,
+		})
+	})
+	if err != nil {
+		return user, err
+	}
+	// Cut off the user's access tokens and WebSocket connections
+	authService.PublishRevocation(user.ID)
+	return user, nil
+}
+
+// Reactivate lets a deactivated user log in again; sessions revoked by the deactivation stay revoked
+func (s *UserService) Reactivate(userID uuid.UUID, actor Actor) (models.User, error) {
+	var user models.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.lock(tx, &user, userID); err != nil {
+			return err
+		}
+		if user.AnonymizedAt != nil {
+			return ErrUserAnonymized
+		}
+		if user.Active() {
+			return ErrUserActive
+		}
+
+		before := userStatusSnapshot(user)
+		user.DeactivatedAt = nil
+		user.DeactivationReason = ""
+		if err := tx.Model(&user).Select("deactivated_at", "deactivation_reason").Updates(&user).Error; err != nil {
+			return err
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityUser,
+			EntityID:      user.ID,
+			OperationType: "reactivate",
+			Before:        before,
+			After:         userStatusSnapshot(user),
+			Actor:         actor,
+		})
+	})
+	return user, err
+}
+
+// AnonymizeDeactivatedUsers erases the personal data of users deactivated more than
+// USER_ANONYMIZE_AFTER ago (0 keeps it): their name, email address, credentials, linked
+// accounts and store assignments, and their name in the audit log. The user row stays, under
+// a pseudonym, so history keeps pointing at it.
+func (s *UserService) AnonymizeDeactivatedUsers(ctx context.Context) error {
+	after := config.CONFIG.UserAnonymizeAfter
+	if after <= 0 {
+		return nil
+	}
+
+	var userIDs []uuid.UUID
+	if err := database.DB.WithContext(ctx).Model(&models.User{}).
+		Where("deactivated_at < ? AND anonymized_at IS NULL", time.Now().Add(-after)).
+		Pluck("id", &userIDs).Error; err != nil {
+		return fmt.Errorf("failed to query deactivated users: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := s.anonymize(ctx, userID); err != nil {
+			return fmt.Errorf("failed to anonymize user %s: %w", userID, err)
+		}
+	}
+	if len(userIDs) > 0 {
+		log.Printf("Anonymized %d users deactivated more than %s ago", len(userIDs), after)
+	}
+	return nil
+}
+
+func (s *UserService) anonymize(ctx context.Context, userID uuid.UUID) error {
+	pseudonym := "deleted-" + userID.String()
+	hadAssignments := false
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := s.lock(tx, &user, userID); err != nil {
+			return err
+		}
+		// Reactivated or anonymized since the query
+		if user.Active() || user.AnonymizedAt != nil {
+			return nil
+		}
+
+		var assignments []models.StoreUser
+		if err := tx.Preload("Store").Where("user_id = ?", user.ID).Find(&assignments).Error; err != nil {
+			return err
+		}
+		for _, storeUser := range assignments {
+			if err := tx.Delete(&storeUser).Error; err != nil {
+				return err
+			}
+			if err := RecordEntityChange(tx, EntityChange{
+				EntityType:    models.EntityStoreUser,
+				EntityID:      storeUser.ID,
+				OperationType: "delete",
+				Version:       storeUser.Version + 1,
+				Before:        StoreUserSnapshot(storeUser, pseudonym),
+				Actor:         systemActor,
+				StoreID:       storeUser.StoreID,
+				StoreName:     storeUser.Store.Name,
+			}); err != nil {
+				return err
+			}
+		}
+		hadAssignments = len(assignments) > 0
+
+		for _, model := range []interface{}{
+			&models.UserIdentity{}, &models.UserTOTP{}, &models.RecoveryCode{},
+			&models.RefreshToken{}, &models.PasswordResetToken{}, &models.PasswordHistory{},
+		} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		// Rewrite the audit log before recording the anonymization, which names the pseudonym only
+		if err := NewAuditService().AnonymizeUser(tx, user.ID, pseudonym); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"username":                pseudonym,
+			"email":                   pseudonym + "@anonymized.invalid",
+			"password_hash":           "",
+			"password_reset_required": false,
+			"deactivation_reason":     "",
+			"anonymized_at":           now,
+		}).Error; err != nil {
+			return err
+		}
+		return RecordEntityChange(tx, EntityChange{
+			EntityType:    models.EntityUser,
+			EntityID:      user.ID,
+			OperationType: "anonymize",
+			After:         map[string]interface{}{"username": pseudonym},
+			Actor:         systemActor,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if hadAssignments {
+		NewStoreAssignmentService().Invalidate(userID)
+	}
+	return nil
+}
+
+// lock loads and locks a user within tx, returning ErrUserNotFound for unknown IDs
+func (s *UserService) lock(tx *gorm.DB, user *models.User, userID uuid.UUID) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(user, "id = ?", userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// UserSnapshot returns the user fields tracked in outbox diffs of account changes (never the
+// password hash)
+func UserSnapshot(user models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"username": user.Username,
+		"email":    user.Email,
+		"role":     user.Role,
+	}
+}
+
+// userStatusSnapshot returns the status fields tracked in outbox diffs; the username only names
+// the user in the audit log
+func userStatusSnapshot(user models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"username":            user.Username,
+		"status":              user.Status(),
+		"deactivation_reason": user.DeactivationReason,
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/database"
+	"inventory-manager-server/handlers"
+	"inventory-manager-server/kafka"
+	"inventory-manager-server/websocket"
+)
+
+// shutdown drains the instance in dependency order:
+// readiness off, HTTP server, WebSocket clients, background tasks (flushing the outbox),
+// Kafka, then Redis and the database. Steps left when ctx expires are skipped.
+func shutdown(ctx context.Context, server *http.Server, stopBackground context.CancelFunc, background *sync.WaitGroup) {
+	// Fail readiness checks so no new traffic is routed here
+	handlers.SetDraining()
+
+	// Stop accepting requests and wait for in-flight ones
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Warning: HTTP server shutdown: %v", err)
+	} else {
+		log.Println("HTTP server stopped")
+	}
+
+	// Ask WebSocket clients to reconnect to another instance
+	if websocket.Hub != nil {
+		websocket.Hub.Shutdown(ctx)
+	}
+
+	// Stop the Kafka consumer, outbox processor (which flushes its last batch) and repair job
+	stopBackground()
+	done := make(chan struct{})
+	go func() {
+		background.Wait()
+		kafka.CloseConsumer()
+		close(done)
+	}()
+	select {
+	case <-done:
+		log.Println("Background tasks stopped")
+	case <-ctx.Done():
+		log.Printf("Warning: timed out waiting for background tasks: %v", ctx.Err())
+		return
+	}
+
+	if err := kafka.CloseProducer(); err != nil {
+		log.Printf("Warning: failed to close Kafka producer: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		log.Printf("Warning: failed to close cache: %v", err)
+	}
+	if database.DB != nil {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	log.Println("Shutdown complete")
+}
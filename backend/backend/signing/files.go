@@ -0,0 +1,47 @@
+package signing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadFiles reads PEM private keys; each key's ID is its file name without the extension
+func LoadFiles(paths []string) ([]Key, error) {
+	loaded := make([]Key, 0, len(paths))
+	seen := make(map[string]string, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(path)
+		id := strings.TrimSuffix(name, filepath.Ext(name))
+		if other, ok := seen[id]; ok {
+			return nil, fmt.Errorf("key ID %q used by both %s and %s", id, other, path)
+		}
+		seen[id] = path
+
+		k, err := ParsePEM(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if info, err := os.Stat(path); err == nil {
+			k.CreatedAt = info.ModTime()
+		}
+		loaded = append(loaded, k)
+	}
+	return loaded, nil
+}
+
+// LoadDir reads every *.pem file in dir (see LoadFiles), sorted by key ID
+func LoadDir(dir string) ([]Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return LoadFiles(paths)
+}
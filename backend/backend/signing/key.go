@@ -0,0 +1,118 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Supported JWT signing algorithms
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 2048
+
+// Key is a private JWT signing key identified by its key ID ("kid" header)
+type Key struct {
+	ID        string
+	Algorithm string        // AlgRS256 or AlgEdDSA
+	Private   crypto.Signer // *rsa.PrivateKey or ed25519.PrivateKey
+	CreatedAt time.Time
+}
+
+// Public returns the key's public half, used to verify tokens
+func (k Key) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// Generate creates a key for the algorithm; its ID is derived from the public key
+func Generate(algorithm string) (Key, error) {
+	var private crypto.Signer
+	switch algorithm {
+	case AlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return Key{}, err
+		}
+		private = key
+	case AlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return Key{}, err
+		}
+		private = key
+	default:
+		return Key{}, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	id, err := keyID(private.Public())
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{ID: id, Algorithm: algorithm, Private: private, CreatedAt: time.Now()}, nil
+}
+
+// ParsePEM decodes a PKCS#8 (RSA or Ed25519) or PKCS#1 (RSA) private key; the algorithm
+// follows from the key type
+func ParsePEM(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < rsaKeyBits {
+			return Key{}, fmt.Errorf("RSA key has %d bits, at least %d are required", private.N.BitLen(), rsaKeyBits)
+		}
+		return Key{ID: id, Algorithm: AlgRS256, Private: private}, nil
+	case ed25519.PrivateKey:
+		return Key{ID: id, Algorithm: AlgEdDSA, Private: private}, nil
+	default:
+		return Key{}, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+}
+
+// MarshalPEM encodes the private key as PKCS#8
+func MarshalPEM(k Key) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// keyID is a truncated SHA-256 fingerprint of the public key
+func keyID(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
@@ -0,0 +1,146 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// minReloadInterval limits reloads triggered by tokens signed with an unknown key, so forged
+// key IDs cannot hammer the key source
+const minReloadInterval = 10 * time.Second
+
+// ErrNoSigningKey is returned when no key has been loaded yet
+var ErrNoSigningKey = errors.New("no JWT signing key loaded")
+
+var (
+	mu     sync.RWMutex
+	keys   = make(map[string]Key)
+	active Key
+
+	reloadMu   sync.Mutex
+	reloader   func() error
+	lastReload time.Time
+)
+
+// Use replaces the key set: activeID signs new tokens, every key verifies them
+func Use(set []Key, activeID string) error {
+	byID := make(map[string]Key, len(set))
+	for _, k := range set {
+		byID[k.ID] = k
+	}
+	current, ok := byID[activeID]
+	if !ok {
+		return errors.New("active signing key " + activeID + " is not in the key set")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	keys = byID
+	active = current
+	return nil
+}
+
+// SetReloader registers the function that reloads the key set when a token names an unknown key
+// (another instance may have rotated to a key this one has not loaded yet)
+func SetReloader(fn func() error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloader = fn
+}
+
+// Active returns the key that signs new tokens
+func Active() (Key, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if active.Private == nil {
+		return Key{}, ErrNoSigningKey
+	}
+	return active, nil
+}
+
+// Lookup returns the key with the given ID, reloading the key set once if it is unknown
+func Lookup(id string) (Key, bool) {
+	if k, ok := lookup(id); ok {
+		return k, true
+	}
+	if !reloadUnknown() {
+		return Key{}, false
+	}
+	return lookup(id)
+}
+
+func lookup(id string) (Key, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	k, ok := keys[id]
+	return k, ok
+}
+
+func reloadUnknown() bool {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	if reloader == nil || time.Since(lastReload) < minReloadInterval {
+		return false
+	}
+	lastReload = time.Now()
+	if err := reloader(); err != nil {
+		log.Printf("Warning: failed to reload JWT signing keys: %v", err)
+		return false
+	}
+	return true
+}
+
+// JWK is the public half of a signing key as a JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKeys returns every key that verifies tokens, active key first
+func PublicKeys() JWKSet {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(keys))}
+	if active.Private != nil {
+		set.Keys = append(set.Keys, toJWK(active))
+	}
+	for id, k := range keys {
+		if id != active.ID {
+			set.Keys = append(set.Keys, toJWK(k))
+		}
+	}
+	return set
+}
+
+func toJWK(k Key) JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch public := k.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
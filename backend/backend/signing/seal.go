@@ -0,0 +1,81 @@
+package signing
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix marks stored private keys encrypted by Seal; stored keys without it are plain PEM
+const sealedPrefix = "aes256gcm:"
+
+// ErrInvalidSealKey is returned for encryption keys that are not 32 base64-encoded bytes
+var ErrInvalidSealKey = errors.New("key encryption key must be 32 bytes, base64-encoded")
+
+// ParseSealKey decodes a base64-encoded AES-256 key encryption key
+func ParseSealKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, ErrInvalidSealKey
+	}
+	return key, nil
+}
+
+// Sealed reports whether a stored private key was encrypted by Seal
+func Sealed(stored string) bool {
+	return strings.HasPrefix(stored, sealedPrefix)
+}
+
+// Seal encrypts an encoded private key with AES-256-GCM. The key ID is authenticated with it,
+// so a sealed key cannot be passed off as another one.
+func Seal(sealKey []byte, id string, data []byte) (string, error) {
+	aead, err := newAEAD(sealKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, data, []byte(id))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Unseal decrypts a private key encrypted by Seal for the same key ID
+func Unseal(sealKey []byte, id string, stored string) ([]byte, error) {
+	if !Sealed(stored) {
+		return nil, errors.New("private key is not sealed")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, sealedPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("malformed sealed private key: %w", err)
+	}
+	aead, err := newAEAD(sealKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("malformed sealed private key")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, errors.New("failed to decrypt private key (wrong key encryption key?)")
+	}
+	return data, nil
+}
+
+func newAEAD(sealKey []byte) (cipher.AEAD, error) {
+	if len(sealKey) != 32 {
+		return nil, ErrInvalidSealKey
+	}
+	block, err := aes.NewCipher(sealKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -2,14 +2,19 @@ package utils
 
 import (
 	"errors"
-	"os"
 	"time"
 
+	"inventory-manager-server/signing"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
-var jwtSecret []byte
+// validMethods are the accepted "alg" headers; each key additionally only verifies its own algorithm
+var validMethods = []string{signing.AlgRS256, signing.AlgEdDSA}
+
+// mfaChallengeAudience marks login challenge tokens, so they cannot be used as access tokens
+const mfaChallengeAudience = "mfa_challenge"
 
 // JWTClaims represents the JWT token claims
 type JWTClaims struct {
@@ -17,55 +22,97 @@ type JWTClaims struct {
 	Username string    `json:"username"`
 	Email    string    `json:"email"`
 	Role     string    `json:"role"`
+	// Must match the user's current token version (see models.User.TokenVersion)
+	TokenVersion int `json:"tv"`
+	// Set on impersonation tokens, which carry the impersonated user's claims above
+	Impersonator *ImpersonatorClaims `json:"act,omitempty"`
+	ReadOnly     bool                `json:"ro,omitempty"` // Only safe methods (GET, HEAD) are allowed
+	jwt.RegisteredClaims
+}
+
+// ImpersonatorClaims identifies the user behind an impersonation token (the RFC 8693 "act" claim)
+type ImpersonatorClaims struct {
+	UserID   uuid.UUID `json:"userId"`
+	Username string    `json:"username"`
+	// Must match the impersonator's current token version too
+	TokenVersion int `json:"tv"`
+}
+
+// MFAChallengeClaims represents the claims of a login challenge token, issued after a correct
+// password when a second factor is still required
+type MFAChallengeClaims struct {
+	UserID       uuid.UUID `json:"userId"`
+	TokenVersion int       `json:"tv"`
+	RememberMe   bool      `json:"rememberMe"`
 	jwt.RegisteredClaims
 }
 
-// InitJWT initializes JWT secret from environment variable
-func InitJWT() {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// Default secret for development (should be changed in production)
-		secret = "your-secret-key-change-in-production"
+// sign signs the claims with the active key, naming it in the "kid" header
+func sign(claims jwt.Claims) (string, error) {
+	key, err := signing.Active()
+	if err != nil {
+		return "", err
 	}
-	jwtSecret = []byte(secret)
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
 }
 
-// GenerateToken generates a JWT token for a user
-func GenerateToken(userID uuid.UUID, username, email, role string, rememberMe bool) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Default 24 hours
-	if rememberMe {
-		expirationTime = time.Now().Add(7 * 24 * time.Hour) // 7 days if remember me
+// verificationKey returns the public key named by the token's "kid" header
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	key, ok := signing.Lookup(id)
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("invalid signing method")
 	}
+	return key.Public(), nil
+}
+
+// GenerateToken generates a short-lived JWT access token for a user
+func GenerateToken(userID uuid.UUID, username, email, role string, tokenVersion int, ttl time.Duration) (string, error) {
+	expirationTime := time.Now().Add(ttl)
 
 	claims := &JWTClaims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
-		Role:     role,
+		UserID:       userID,
+		Username:     username,
+		Email:        email,
+		Role:         role,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
-	if err != nil {
-		return "", err
-	}
+	return sign(claims)
+}
 
-	return tokenString, nil
+// GenerateImpersonationToken generates a JWT access token that authenticates as a user on behalf
+// of impersonator; no refresh token goes with it
+func GenerateImpersonationToken(userID uuid.UUID, username, email, role string, tokenVersion int, impersonator ImpersonatorClaims, readOnly bool, ttl time.Duration) (string, error) {
+	claims := &JWTClaims{
+		UserID:       userID,
+		Username:     username,
+		Email:        email,
+		Role:         role,
+		TokenVersion: tokenVersion,
+		Impersonator: &impersonator,
+		ReadOnly:     readOnly,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return sign(claims)
 }
 
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString string) (*JWTClaims, error) {
 	claims := &JWTClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return jwtSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, verificationKey, jwt.WithValidMethods(validMethods))
 
 	if err != nil {
 		return nil, err
@@ -74,6 +121,38 @@ func ValidateToken(tokenString string) (*JWTClaims, error) {
 	if !token.Valid {
 		return nil, errors.New("invalid token")
 	}
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("not an access token")
+	}
+
+	return claims, nil
+}
 
+// GenerateMFAChallenge generates a login challenge token
+func GenerateMFAChallenge(userID uuid.UUID, tokenVersion int, rememberMe bool, ttl time.Duration) (string, error) {
+	claims := &MFAChallengeClaims{
+		UserID:       userID,
+		TokenVersion: tokenVersion,
+		RememberMe:   rememberMe,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return sign(claims)
+}
+
+// ValidateMFAChallenge validates a login challenge token and returns the claims
+func ValidateMFAChallenge(tokenString string) (*MFAChallengeClaims, error) {
+	claims := &MFAChallengeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, verificationKey,
+		jwt.WithValidMethods(validMethods), jwt.WithAudience(mfaChallengeAudience))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
 	return claims, nil
 }
@@ -0,0 +1,232 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"inventory-manager-server/models"
+	"inventory-manager-server/services"
+
+	"github.com/google/uuid"
+)
+
+// accessTTL bounds how long a client's permissions and stores are reused to route events; they
+// are also resolved again after role and store assignment events that concern the client
+const accessTTL = 30 * time.Second
+
+// clientAccess is what a client may see, resolved from its user's role and store assignments
+type clientAccess struct {
+	permissions     services.PermissionSet
+	allInventory    bool               // inventory.all_stores with inventory.read
+	inventoryStores map[uuid.UUID]bool // Stores whose inventory the user may read
+	assignedStores  map[uuid.UUID]bool // Stores of the user's active assignments
+	storeRoles      map[string]bool    // Roles of the user's store assignments
+	resolvedAt      time.Time
+}
+
+// resolveAccess resolves the access of a user with the given role
+func resolveAccess(userID uuid.UUID, role string) (*clientAccess, error) {
+	permissions, err := services.NewRoleService().Permissions(role)
+	if err != nil {
+		return nil, err
+	}
+	access := &clientAccess{
+		permissions:     permissions,
+		allInventory:    permissions.Has(models.PermInventoryAllStores, models.PermInventoryRead),
+		inventoryStores: map[uuid.UUID]bool{},
+		assignedStores:  map[uuid.UUID]bool{},
+		storeRoles:      map[string]bool{},
+		resolvedAt:      time.Now(),
+	}
+
+	assignments := services.NewStoreAssignmentService()
+	assigned, err := assignments.StoresWithPermission(userID, permissions, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range assigned {
+		access.assignedStores[id] = true
+	}
+	readable, err := assignments.StoresWithPermission(userID, permissions, models.PermInventoryRead)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range readable {
+		access.inventoryStores[id] = true
+	}
+	storeRoles, err := assignments.StoreRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range storeRoles {
+		access.storeRoles[name] = true
+	}
+	return access, nil
+}
+
+// loadAccess resolves the client's access before it is registered
+func (c *Client) loadAccess() error {
+	access, err := resolveAccess(c.userID, c.Role)
+	if err != nil {
+		return err
+	}
+	c.access.Store(access)
+	return nil
+}
+
+// currentAccess returns the client's access without blocking the hub. Once the access is older
+// than accessTTL or invalidated, it is resolved again in the background; events are routed with
+// the previous access until then.
+func (c *Client) currentAccess() *clientAccess {
+	access := c.access.Load()
+	if access == nil || c.invalidated.Load() || time.Since(access.resolvedAt) >= accessTTL {
+		c.refreshAccess()
+	}
+	return access
+}
+
+// invalidateAccess makes the client's access be resolved again
+func (c *Client) invalidateAccess() {
+	c.invalidated.Store(true)
+	c.refreshAccess()
+}
+
+// refreshAccess resolves the client's access in a goroutine, unless one is already running;
+// invalidations arriving meanwhile make it resolve again
+func (c *Client) refreshAccess() {
+	if !c.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer c.refreshing.Store(false)
+		for {
+			invalidated := c.invalidated.Swap(false)
+			if current := c.access.Load(); !invalidated && current != nil && time.Since(current.resolvedAt) < accessTTL {
+				return
+			}
+			access, err := resolveAccess(c.userID, c.Role)
+			if err != nil {
+				// Keep routing with the previous access rather than dropping every event
+				log.Printf("Warning: failed to resolve WebSocket access of user %s: %v", c.UserID, err)
+				if invalidated {
+					c.invalidated.Store(true)
+				}
+				return
+			}
+			c.access.Store(access)
+		}
+	}()
+}
+
+// canSee reports whether an event may be sent to the client. Inventory goes to clients that may
+// read the store's inventory, stores to their staff and to store managers, and SKUs to catalog
+// readers. Users, roles, staff assignments, service accounts and API keys only go to holders of
+// the matching manage permission. Unknown entity types go to nobody.
+func (c *Client) canSee(record models.Outbox) bool {
+	access := c.currentAccess()
+	if access == nil {
+		return false
+	}
+
+	switch record.EntityType {
+	case models.EntityInventory, "":
+		return access.allInventory || access.inventoryStores[record.StoreID]
+	case models.EntitySKU:
+		return access.permissions.Has(models.PermSKURead)
+	case models.EntityStore:
+		return access.permissions.Has(models.PermStoresManage) || access.assignedStores[record.StoreID]
+	case models.EntityStoreUser:
+		return access.permissions.Has(models.PermStoresManage)
+	case models.EntityUser:
+		return access.permissions.Has(models.PermUsersManage)
+	case models.EntityRole:
+		return access.permissions.Has(models.PermRolesManage)
+	case models.EntityServiceAccount, models.EntityAPIKey:
+		return access.permissions.Has(models.PermServiceAccountsManage)
+	}
+	return false
+}
+
+// accessChange names the clients whose access an event may change: users with the role (as
+// their own role or a store role) or the user of the store assignment
+type accessChange struct {
+	all   bool // Unknown target; every client is affected
+	roles map[string]bool
+	users map[uuid.UUID]bool
+}
+
+// affects reports whether the change concerns a client
+func (a *accessChange) affects(c *Client) bool {
+	if a.all || a.roles[c.Role] || a.users[c.userID] {
+		return true
+	}
+	if access := c.access.Load(); access != nil {
+		for role := range a.roles {
+			if access.storeRoles[role] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// accessChangeOf returns the clients whose access an event may change, or nil for events that
+// change no access. Names missing from the diff (unchanged fields of updates) are looked up.
+func accessChangeOf(record models.Outbox) *accessChange {
+	var field string
+	switch record.EntityType {
+	case models.EntityRole:
+		field = "name"
+	case models.EntityStoreUser:
+		field = "user_id"
+	default:
+		return nil
+	}
+
+	values := diffValues(record.Diff, field)
+	if len(values) == 0 {
+		var value string
+		var err error
+		if record.EntityType == models.EntityRole {
+			value, err = services.NewRoleService().Name(record.EntityID)
+		} else {
+			var userID uuid.UUID
+			userID, err = services.NewStoreAssignmentService().AssignedUser(record.EntityID)
+			value = userID.String()
+		}
+		if err != nil {
+			log.Printf("Warning: failed to find the clients affected by %s %s: %v (resolving every client)", record.EntityType, record.EntityID, err)
+			return &accessChange{all: true}
+		}
+		values = []string{value}
+	}
+
+	change := &accessChange{roles: map[string]bool{}, users: map[uuid.UUID]bool{}}
+	for _, value := range values {
+		if record.EntityType == models.EntityRole {
+			change.roles[value] = true
+		} else if id, err := uuid.Parse(value); err == nil {
+			change.users[id] = true
+		}
+	}
+	return change
+}
+
+// diffValues returns the old and new string values of a field in an outbox diff
+func diffValues(diff models.JSON, field string) []string {
+	var changes map[string]struct {
+		Old interface{} `json:"old"`
+		New interface{} `json:"new"`
+	}
+	if len(diff) == 0 || json.Unmarshal(diff, &changes) != nil {
+		return nil
+	}
+	var values []string
+	for _, value := range []interface{}{changes[field].Old, changes[field].New} {
+		if s, ok := value.(string); ok && s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
@@ -0,0 +1,166 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"inventory-manager-server/cache"
+	"inventory-manager-server/models"
+
+	"github.com/google/uuid"
+)
+
+// newTestHub starts a hub that is stopped at the end of the test
+func newTestHub(t *testing.T) *HubType {
+	t.Helper()
+	h := &HubType{
+		clients:    make(map[*Client]bool),
+		broadcast:  make(chan event),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		disconnect: make(chan string),
+		shutdown:   make(chan chan []*Client),
+		stopped:    make(chan struct{}),
+	}
+	go h.Run()
+	t.Cleanup(func() {
+		reply := make(chan []*Client, 1)
+		h.shutdown <- reply
+		<-reply
+	})
+	return h
+}
+
+// connect registers a client of a user whose role has permissions and who is assigned to
+// storeID. The role and assignments are cached the way services.RoleService and
+// services.StoreAssignmentService cache them.
+func connect(t *testing.T, h *HubType, role string, permissions []string, storeID uuid.UUID) *Client {
+	t.Helper()
+	userID := uuid.New()
+	roleKey, storesKey := "rbac:role:"+role, "rbac:stores:"+userID.String()
+	cache.SetJSON(roleKey, permissions, time.Minute)
+	cache.SetJSON(storesKey, []map[string]interface{}{{"store_id": storeID}}, time.Minute)
+	t.Cleanup(func() { cache.Invalidate(roleKey, storesKey) })
+
+	client := &Client{Send: make(chan []byte, 16), UserID: userID.String(), Role: role, userID: userID}
+	if err := client.loadAccess(); err != nil {
+		t.Fatalf("failed to resolve access: %v", err)
+	}
+	h.Register(client)
+	return client
+}
+
+// connectStaff registers a staff client assigned to storeID
+func connectStaff(t *testing.T, h *HubType, storeID uuid.UUID) *Client {
+	t.Helper()
+	return connect(t, h, models.RoleStaff, models.BuiltInRoles[models.RoleStaff], storeID)
+}
+
+// checkReceived fails unless the client received exactly want before the end marker
+func checkReceived(t *testing.T, client *Client, want []string) {
+	t.Helper()
+	got := received(t, client)
+	if len(got) != len(want) {
+		t.Errorf("client %s (%s) received %v, want %v", client.UserID, client.Role, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("client %s (%s) received %v, want %v", client.UserID, client.Role, got, want)
+			return
+		}
+	}
+}
+
+// received returns the payloads a client received before the end marker
+func received(t *testing.T, client *Client) []string {
+	t.Helper()
+	var payloads []string
+	for {
+		select {
+		case payload := <-client.Send:
+			if string(payload) == "end" {
+				return payloads
+			}
+			payloads = append(payloads, string(payload))
+		case <-time.After(time.Second):
+			t.Fatalf("client %s did not receive the end marker", client.UserID)
+		}
+	}
+}
+
+// assignedDiff is the diff of a store assignment event of a user
+func assignedDiff(userID uuid.UUID) models.JSON {
+	return models.JSON(`{"user_id": {"old": null, "new": "` + userID.String() + `"}}`)
+}
+
+func TestStaffOnlyReceiveEventsOfTheirStores(t *testing.T) {
+	h := newTestHub(t)
+	storeA, storeB := uuid.New(), uuid.New()
+	staffA, staffB := connectStaff(t, h, storeA), connectStaff(t, h, storeB)
+
+	h.Broadcast(models.Outbox{EntityType: models.EntityInventory, StoreID: storeA}, []byte("inventory A"))
+	h.Broadcast(models.Outbox{EntityType: models.EntityInventory, StoreID: storeB}, []byte("inventory B"))
+	h.Broadcast(models.Outbox{EntityType: models.EntityStore, StoreID: storeB}, []byte("store B"))
+	h.Broadcast(models.Outbox{EntityType: models.EntityStoreUser, StoreID: storeB, Diff: assignedDiff(uuid.New())}, []byte("staff B"))
+	// SKUs are not store-scoped, so every staff member receives the marker
+	h.Broadcast(models.Outbox{EntityType: models.EntitySKU}, []byte("end"))
+
+	checkReceived(t, staffA, []string{"inventory A"})
+	checkReceived(t, staffB, []string{"inventory B", "store B"})
+}
+
+func TestServiceAccountEventsOnlyReachTheirManagers(t *testing.T) {
+	h := newTestHub(t)
+	store := uuid.New()
+	var allButServiceAccounts []string
+	for _, permission := range models.BuiltInRoles[models.RoleManager] {
+		if permission != models.PermServiceAccountsManage {
+			allButServiceAccounts = append(allButServiceAccounts, permission)
+		}
+	}
+	manager := connect(t, h, models.RoleManager, models.BuiltInRoles[models.RoleManager], store)
+	administrator := connect(t, h, "administrator", allButServiceAccounts, store)
+	staff := connectStaff(t, h, store)
+
+	h.Broadcast(models.Outbox{EntityType: models.EntityServiceAccount}, []byte("service account"))
+	h.Broadcast(models.Outbox{EntityType: models.EntityAPIKey}, []byte("api key"))
+	h.Broadcast(models.Outbox{EntityType: models.EntitySKU}, []byte("end"))
+
+	checkReceived(t, manager, []string{"service account", "api key"})
+	checkReceived(t, administrator, nil)
+	checkReceived(t, staff, nil)
+}
+
+func TestAccessChangesOnlyConcernAffectedClients(t *testing.T) {
+	h := newTestHub(t)
+	store := uuid.New()
+	staff, otherStaff := connectStaff(t, h, store), connectStaff(t, h, store)
+	manager := connect(t, h, models.RoleManager, models.BuiltInRoles[models.RoleManager], store)
+	// A manager whose store assignment has the staff role
+	manager.access.Load().storeRoles[models.RoleStaff] = true
+
+	for _, tc := range []struct {
+		name     string
+		record   models.Outbox
+		affected []*Client
+	}{
+		{"staff assignment", models.Outbox{EntityType: models.EntityStoreUser, Diff: assignedDiff(staff.userID)}, []*Client{staff}},
+		{"staff role", models.Outbox{EntityType: models.EntityRole,
+			Diff: models.JSON(`{"name": {"old": "staff", "new": null}}`)}, []*Client{staff, otherStaff, manager}},
+		{"SKU", models.Outbox{EntityType: models.EntitySKU}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			change := accessChangeOf(tc.record)
+			for _, client := range []*Client{staff, otherStaff, manager} {
+				want := false
+				for _, affected := range tc.affected {
+					want = want || affected == client
+				}
+				if got := change != nil && change.affects(client); got != want {
+					t.Errorf("affects client %s (%s) = %v, want %v", client.UserID, client.Role, got, want)
+				}
+			}
+		})
+	}
+}
@@ -1,12 +1,18 @@
 package websocket
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"inventory-manager-server/cache"
+	"inventory-manager-server/services"
 	"inventory-manager-server/utils"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -32,6 +38,12 @@ const (
 
 	// Maximum message size allowed from peer
 	maxMessageSize = 512 * 1024
+
+	// Close reason sent when the server shuts down (close code 1012 "service restart")
+	restartCloseText = "server restarting, please reconnect"
+
+	// Close reason sent when the user's tokens are revoked (close code 1008 "policy violation")
+	revokedCloseText = "session revoked"
 )
 
 // Client represents a WebSocket client connection
@@ -41,6 +53,20 @@ type Client struct {
 	Send   chan []byte
 	UserID string
 	Role   string
+
+	userID uuid.UUID
+	// Resolved permissions and stores, used by the hub to route events (nil until resolved);
+	// resolved again in the background (see Client.currentAccess)
+	access      atomic.Pointer[clientAccess]
+	invalidated atomic.Bool // Access must be resolved again
+	refreshing  atomic.Bool // A goroutine is resolving access
+
+	// Close frame sent when the hub closes Send (zero code means an empty close frame)
+	closeCode int
+	closeText string
+
+	// Closed when WritePump has exited
+	done chan struct{}
 }
 
 // NewClient creates a new client
@@ -51,9 +77,25 @@ func NewClient(conn *websocket.Conn, userID string, role string) *Client {
 		Send:   make(chan []byte, 256),
 		UserID: userID,
 		Role:   role,
+		userID: uuid.MustParse(userID),
+		done:   make(chan struct{}),
 	}
 }
 
+// markRestart makes the client's final close frame ask it to reconnect
+// Must be called by the hub before it closes Send
+func (c *Client) markRestart() {
+	c.closeCode = websocket.CloseServiceRestart
+	c.closeText = restartCloseText
+}
+
+// markRevoked makes the client's final close frame tell it not to reconnect with its token
+// Must be called by the hub before it closes Send
+func (c *Client) markRevoked() {
+	c.closeCode = websocket.ClosePolicyViolation
+	c.closeText = revokedCloseText
+}
+
 // ReadPump reads messages from WebSocket connection
 func (c *Client) ReadPump() {
 	defer func() {
@@ -68,18 +110,14 @@ func (c *Client) ReadPump() {
 		return nil
 	})
 
+	// Clients only receive events; anything they send is discarded
 	for {
-		_, message, err := c.Conn.ReadMessage()
-		if err != nil {
+		if _, _, err := c.Conn.ReadMessage(); err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
-
-		// Echo message back to all clients (broadcast)
-		// In a real application, you might want to process the message first
-		c.Hub.Broadcast(message)
 	}
 }
 
@@ -89,6 +127,7 @@ func (c *Client) WritePump() {
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
+		close(c.done)
 	}()
 
 	for {
@@ -97,7 +136,11 @@ func (c *Client) WritePump() {
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// The hub closed the channel
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				closeMessage := []byte{}
+				if c.closeCode != 0 {
+					closeMessage = websocket.FormatCloseMessage(c.closeCode, c.closeText)
+				}
+				c.Conn.WriteMessage(websocket.CloseMessage, closeMessage)
 				return
 			}
 
@@ -142,6 +185,14 @@ func ServeWS(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized: invalid or expired token", http.StatusUnauthorized)
 		return
 	}
+	if err := services.NewAuthService().ValidateClaims(claims); err != nil {
+		if errors.Is(err, services.ErrSessionRevoked) {
+			http.Error(w, "Unauthorized: session revoked", http.StatusUnauthorized)
+		} else {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
 
 	// Hub check
 	if Hub == nil {
@@ -150,18 +201,42 @@ func ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Create client with user info from claims, resolving its access before any event is routed
+	client := NewClient(nil, claims.UserID.String(), claims.Role)
+	if err := client.loadAccess(); err != nil {
+		log.Printf("Failed to resolve WebSocket access of user %s: %v", client.UserID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	// Upgrade connection
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
-
-	// Create client with user info from claims
-	client := NewClient(conn, claims.UserID.String(), claims.Role)
+	client.Conn = conn
 	Hub.Register(client)
 
 	// Start client goroutines
 	go client.WritePump()
 	go client.ReadPump()
 }
+
+// StartRevocationListener closes the connections of users whose tokens were revoked on
+// any instance until ctx is cancelled
+func StartRevocationListener(ctx context.Context) {
+	messages, err := cache.Default.Subscribe(ctx, services.RevocationChannel)
+	if err != nil {
+		log.Printf("Warning: failed to subscribe to token revocations: %v", err)
+		return
+	}
+
+	log.Println("Token revocation listener started")
+	for userID := range messages {
+		if Hub != nil {
+			Hub.DisconnectUser(userID)
+		}
+	}
+	log.Println("Token revocation listener stopped")
+}
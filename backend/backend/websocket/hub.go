@@ -1,7 +1,10 @@
 package websocket
 
 import (
+	"context"
 	"log"
+
+	"inventory-manager-server/models"
 )
 
 // Hub maintains all active client connections
@@ -12,29 +15,48 @@ type HubType struct {
 	// Registered clients
 	clients map[*Client]bool
 
-	// Inbound messages from clients
-	broadcast chan []byte
+	// Outbox events to send to the clients allowed to see them
+	broadcast chan event
 
 	// Register requests from clients
 	register chan *Client
 
 	// Unregister requests from clients
 	unregister chan *Client
+
+	// User IDs whose connections must be closed
+	disconnect chan string
+
+	// Shutdown requests, answered with the clients that were connected
+	shutdown chan chan []*Client
+
+	// Closed once the hub has stopped
+	stopped chan struct{}
 }
 
 // InitHub initializes the WebSocket Hub
 func InitHub() error {
 	Hub = &HubType{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
+		broadcast:  make(chan event),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		disconnect: make(chan string),
+		shutdown:   make(chan chan []*Client),
+		stopped:    make(chan struct{}),
 	}
 	go Hub.Run()
 	log.Println("WebSocket Hub initialized and started")
 	return nil
 }
 
+// event is an outbox record with the JSON payload sent to clients
+type event struct {
+	record  models.Outbox
+	payload []byte
+	access  *accessChange // Clients whose access the event may change; nil for none
+}
+
 // GetHub returns the global Hub instance
 func GetHub() *HubType {
 	return Hub
@@ -56,32 +78,109 @@ func (h *HubType) Run() {
 				log.Printf("Client unregistered. Total clients: %d", len(h.clients))
 			}
 
-		case message := <-h.broadcast:
-			// Broadcast message to all registered clients
+		case userID := <-h.disconnect:
+			// The user's tokens were revoked
+			closed := 0
 			for client := range h.clients {
+				if client.UserID == userID {
+					client.markRevoked()
+					close(client.Send)
+					delete(h.clients, client)
+					closed++
+				}
+			}
+			if closed > 0 {
+				log.Printf("Closed %d clients of revoked user %s. Total clients: %d", closed, userID, len(h.clients))
+			}
+
+		case msg := <-h.broadcast:
+			// Send the event to the clients allowed to see it
+			for client := range h.clients {
+				if msg.access != nil && msg.access.affects(client) {
+					client.invalidateAccess()
+				}
+				if !client.canSee(msg.record) {
+					continue
+				}
 				select {
-				case client.Send <- message:
+				case client.Send <- msg.payload:
 				default:
 					// If client's send channel is full, close connection
 					close(client.Send)
 					delete(h.clients, client)
 				}
 			}
+
+		case reply := <-h.shutdown:
+			// Ask every client to reconnect (to another instance) and stop
+			clients := make([]*Client, 0, len(h.clients))
+			for client := range h.clients {
+				client.markRestart()
+				close(client.Send)
+				delete(h.clients, client)
+				clients = append(clients, client)
+			}
+			close(h.stopped)
+			reply <- clients
+			log.Printf("WebSocket Hub stopped. Closed %d clients", len(clients))
+			return
+		}
+	}
+}
+
+// Shutdown sends every client a close frame with a reconnect hint and waits until
+// the frames are written or ctx expires. Clients registering afterwards are closed immediately.
+func (h *HubType) Shutdown(ctx context.Context) {
+	reply := make(chan []*Client, 1)
+	select {
+	case h.shutdown <- reply:
+	case <-h.stopped:
+		return
+	}
+
+	for _, client := range <-reply {
+		select {
+		case <-client.done:
+		case <-ctx.Done():
+			log.Printf("Timed out waiting for WebSocket clients to close: %v", ctx.Err())
+			return
 		}
 	}
 }
 
 // Register registers a client
 func (h *HubType) Register(client *Client) {
-	h.register <- client
+	select {
+	case h.register <- client:
+	case <-h.stopped:
+		// Shutting down: tell the client to reconnect elsewhere
+		client.markRestart()
+		close(client.Send)
+	}
 }
 
 // Unregister unregisters a client
 func (h *HubType) Unregister(client *Client) {
-	h.unregister <- client
+	select {
+	case h.unregister <- client:
+	case <-h.stopped:
+	}
 }
 
-// Broadcast broadcasts a message
-func (h *HubType) Broadcast(message []byte) {
-	h.broadcast <- message
+// DisconnectUser closes every connection of a user on this instance
+func (h *HubType) DisconnectUser(userID string) {
+	select {
+	case h.disconnect <- userID:
+	case <-h.stopped:
+	}
+}
+
+// Broadcast sends the payload of an outbox record to the clients allowed to see the record
+// (see Client.canSee). The clients whose access the record may change are found here, so the
+// hub never waits for the database.
+func (h *HubType) Broadcast(record models.Outbox, payload []byte) {
+	select {
+	case h.broadcast <- event{record: record, payload: payload, access: accessChangeOf(record)}:
+	case <-h.stopped:
+	}
 }
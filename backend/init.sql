@@ -1,8 +1,24 @@
 -- Enable UUID extension (PostgreSQL)
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
--- User role enum
-CREATE TYPE user_role AS ENUM ('manager', 'staff');
+-- Role table (the permissions of built-in roles are synced by the server at startup)
+CREATE TABLE roles (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO roles (name, built_in) VALUES ('manager', TRUE), ('staff', TRUE);
+
+-- Role permission table (e.g. 'inventory.adjust')
+CREATE TABLE role_permissions (
+    role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
 
 -- User table
 CREATE TABLE users (
@@ -10,11 +26,18 @@ CREATE TABLE users (
     username VARCHAR(50) NOT NULL UNIQUE,
     password_hash VARCHAR(255) NOT NULL,
     email VARCHAR(100) NOT NULL UNIQUE,
-    role user_role NOT NULL,
+    role VARCHAR(50) NOT NULL REFERENCES roles (name) ON UPDATE CASCADE,
+    token_version INTEGER NOT NULL DEFAULT 0,
+    password_reset_required BOOLEAN NOT NULL DEFAULT FALSE,
+    deactivated_at TIMESTAMP,
+    deactivation_reason VARCHAR(255),
+    anonymized_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE INDEX idx_users_deactivated_at ON users (deactivated_at);
+
 -- Store table
 CREATE TABLE stores (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
@@ -29,12 +52,19 @@ CREATE TABLE store_user (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
     store_id UUID NOT NULL REFERENCES stores (id) ON DELETE CASCADE,
     user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role VARCHAR(50) REFERENCES roles (name) ON UPDATE CASCADE,
+    valid_from TIMESTAMP,
+    valid_to TIMESTAMP,
+    source VARCHAR(20) NOT NULL DEFAULT '',
     version INTEGER DEFAULT 1,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE (store_id, user_id)
+    UNIQUE (store_id, user_id),
+    CHECK (valid_to > valid_from)
 );
 
+CREATE INDEX idx_store_user_valid_to ON store_user (valid_to);
+
 -- SKU table
 CREATE TABLE sku (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
@@ -64,6 +94,9 @@ CREATE TABLE outbox (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
     operation_type VARCHAR(20) NOT NULL,
     sender_instance_id VARCHAR(100) NOT NULL,
+    entity_type VARCHAR(30) NOT NULL DEFAULT 'inventory',
+    entity_id UUID,
+    diff JSONB,
     inventory_id UUID NOT NULL,
     sku_id UUID NOT NULL,
     sku_name VARCHAR(100) NOT NULL,
@@ -78,6 +111,207 @@ CREATE TABLE outbox (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Stock summary projection (store x category), maintained by the event consumer
+CREATE TABLE stock_summary (
+    store_id UUID NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    sku_count BIGINT NOT NULL DEFAULT 0,
+    total_quantity BIGINT NOT NULL DEFAULT 0,
+    out_of_stock_count BIGINT NOT NULL DEFAULT 0,
+    total_value DECIMAL(14, 2) NOT NULL DEFAULT 0,
+    refreshed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (store_id, category)
+);
+
+-- Creation time of the newest event published to Kafka and applied to the stock summary;
+-- their difference is how far the projection lags behind
+CREATE TABLE event_checkpoints (
+    name VARCHAR(50) PRIMARY KEY,
+    event_at TIMESTAMP NOT NULL
+);
+
+-- Periodic background jobs: shared schedule and lease (one pod runs each occurrence)
+CREATE TABLE scheduled_jobs (
+    name VARCHAR(100) PRIMARY KEY,
+    schedule VARCHAR(100) NOT NULL,
+    next_run_at TIMESTAMP NOT NULL,
+    owner VARCHAR(255),
+    fencing_token BIGINT NOT NULL DEFAULT 0,
+    lease_until TIMESTAMP,
+    last_started_at TIMESTAMP,
+    last_finished_at TIMESTAMP,
+    last_status VARCHAR(20),
+    last_error TEXT
+);
+
+-- Refresh tokens (only the SHA-256 hash is stored); rotated on every use
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    remember_me BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    replaced_by UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_refresh_tokens_user ON refresh_tokens (user_id);
+
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens (family_id);
+
+-- Single-use password reset tokens (only the SHA-256 hash is stored)
+CREATE TABLE password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_password_reset_tokens_user ON password_reset_tokens (user_id);
+
+-- Previous password hashes, so the password policy can refuse reusing them
+CREATE TABLE password_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_password_history_user ON password_history (user_id, created_at);
+
+-- Accounts of users at external identity providers (OIDC single sign-on)
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX idx_user_identities_user ON user_identities (user_id);
+
+-- TOTP authenticators of users (pending until the first code is confirmed)
+CREATE TABLE user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Single-use two-factor recovery codes (only the SHA-256 hash is stored)
+CREATE TABLE recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_recovery_codes_user ON recovery_codes (user_id);
+
+-- Service accounts: non-human API clients (POS terminals, scanners, ERP sync jobs)
+CREATE TABLE service_accounts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- API keys of service accounts (only the SHA-256 hash is stored)
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    service_account_id UUID NOT NULL REFERENCES service_accounts (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_api_keys_service_account ON api_keys (service_account_id);
+
+-- API key permission table (same names as role_permissions)
+CREATE TABLE api_key_permissions (
+    api_key_id UUID NOT NULL REFERENCES api_keys (id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (api_key_id, permission)
+);
+
+-- Stores an API key can access (without inventory.all_stores)
+CREATE TABLE api_key_stores (
+    api_key_id UUID NOT NULL REFERENCES api_keys (id) ON DELETE CASCADE,
+    store_id UUID NOT NULL REFERENCES stores (id) ON DELETE CASCADE,
+    PRIMARY KEY (api_key_id, store_id)
+);
+
+-- Generated JWT signing keys (not used when keys are loaded from files)
+CREATE TABLE signing_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL,
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_signing_keys_created_at ON signing_keys (created_at);
+
+-- Append-only audit log of administrative changes
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    actor_id UUID NOT NULL,
+    actor_name VARCHAR(100) NOT NULL,
+    action VARCHAR(60) NOT NULL,
+    target_type VARCHAR(30) NOT NULL,
+    target_id UUID,
+    target_name VARCHAR(255),
+    diff JSONB,
+    client_ip VARCHAR(45),
+    request_id VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_actor_id ON audit_log (actor_id);
+
+CREATE INDEX idx_audit_log_action ON audit_log (action);
+
+CREATE INDEX idx_audit_log_target ON audit_log (target_type, target_id);
+
+CREATE INDEX idx_audit_log_request_id ON audit_log (request_id);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at);
+
+-- Rows can only be deleted by the retention job, which sets audit_log.purge, and only names,
+-- client IPs and diffs can be rewritten by user anonymization, which sets audit_log.anonymize
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('audit_log.purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    IF TG_OP = 'UPDATE' AND current_setting('audit_log.anonymize', true) = 'on'
+        AND (NEW.id, NEW.actor_id, NEW.action, NEW.target_type, NEW.target_id, NEW.request_id, NEW.created_at)
+            IS NOT DISTINCT FROM (OLD.id, OLD.actor_id, OLD.action, OLD.target_type, OLD.target_id, OLD.request_id, OLD.created_at) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
 -- Indexes for better query performance
 CREATE INDEX idx_inventory_sku ON inventory (sku_id);
 
@@ -85,4 +319,6 @@ CREATE INDEX idx_inventory_store ON inventory (store_id);
 
 CREATE INDEX idx_outbox_sender ON outbox (sender_instance_id);
 
-CREATE INDEX idx_outbox_inventory ON outbox (inventory_id);
\ No newline at end of file
+CREATE INDEX idx_outbox_inventory ON outbox (inventory_id);
+
+CREATE INDEX idx_outbox_entity ON outbox (entity_type, entity_id);
\ No newline at end of file
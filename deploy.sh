@@ -35,9 +35,15 @@ echo "📝 Applying Kubernetes manifests..."
 kubectl apply -f k8s/namespace.yaml
 kubectl apply -f k8s/configmap.yaml
 kubectl apply -f k8s/secrets.yaml
+# The key that encrypts JWT signing keys in the database is generated once and never committed
+if ! kubectl get secret jwt-key-encryption -n inventory-manager > /dev/null 2>&1; then
+    kubectl create secret generic jwt-key-encryption -n inventory-manager \
+        --from-literal=JWT_KEY_ENCRYPTION_KEY="$(openssl rand -base64 32)"
+fi
 kubectl apply -f k8s/postgres-init-configmap.yaml
 kubectl apply -f k8s/postgres.yaml
 kubectl apply -f k8s/redis.yaml
+kubectl apply -f k8s/mailpit.yaml
 kubectl apply -f k8s/kafka.yaml
 
 # Wait for Kafka to be ready before initializing topics
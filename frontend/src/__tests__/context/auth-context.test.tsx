@@ -1,15 +1,21 @@
 import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
 import { renderHook, waitFor } from '@testing-library/react';
 import { AuthProvider, useAuth } from '@/context/auth-context';
+import { loginRequest, logoutRequest, verifyMfaRequest } from '@/lib/api-client';
 import { act } from 'react';
 
 // Create mock functions
 const mockGetProfile = vi.fn();
 const mockListUsers = vi.fn();
+const mockImpersonateUser = vi.fn();
 
 // Mock the api-client module
 vi.mock('@/lib/api-client', () => ({
   loginRequest: vi.fn(),
+  verifyMfaRequest: vi.fn(),
+  isMfaChallenge: vi.fn((response) => response.mfa_required === true),
+  refreshRequest: vi.fn(),
+  logoutRequest: vi.fn(() => Promise.resolve({ message: 'Logged out successfully' })),
   createApiClient: vi.fn(() => ({
     getProfile: mockGetProfile,
     listUsers: mockListUsers,
@@ -17,11 +23,15 @@ vi.mock('@/lib/api-client', () => ({
     createUser: vi.fn(),
     updateUser: vi.fn(),
     deleteUser: vi.fn(),
+    deactivateUser: vi.fn(),
+    reactivateUser: vi.fn(),
+    impersonateUser: mockImpersonateUser,
     listStores: vi.fn(),
     createStore: vi.fn(),
     deleteStore: vi.fn(),
     listStoreStaff: vi.fn(),
     addStaffToStore: vi.fn(),
+    updateStoreStaff: vi.fn(),
     deleteStaffFromStore: vi.fn(),
     listSkus: vi.fn(),
     listSkuCategories: vi.fn(),
@@ -156,6 +166,113 @@ describe('AuthContext', () => {
     expect(sessionStorage.getItem('inventory-manager-auth')).toBe(null);
   });
 
+  it('should revoke the refresh token on logout', async () => {
+    const mockAuth = {
+      token: 'test-token',
+      refreshToken: 'test-refresh-token',
+      user: { id: '1', username: 'testuser', email: 'test@example.com', role: 'staff' },
+      persist: 'local',
+    };
+
+    localStorage.setItem('inventory-manager-auth', JSON.stringify(mockAuth));
+
+    const { result } = renderHook(() => useAuth(), {
+      wrapper: AuthProvider,
+    });
+
+    await waitFor(() => {
+      expect(result.current.loading).toBe(false);
+    });
+
+    act(() => {
+      result.current.logout();
+    });
+
+    expect(logoutRequest).toHaveBeenCalledWith('test-refresh-token', false, expect.any(String));
+    expect(result.current.token).toBe(null);
+    expect(localStorage.getItem('inventory-manager-auth')).toBe(null);
+  });
+
+  it("should return to the manager's session after impersonating a user", async () => {
+    const mockAuth = {
+      token: 'manager-token',
+      refreshToken: 'manager-refresh-token',
+      user: { id: '1', username: 'admin', email: 'admin@admin.com', role: 'manager' },
+      persist: 'local',
+    };
+    localStorage.setItem('inventory-manager-auth', JSON.stringify(mockAuth));
+    mockImpersonateUser.mockResolvedValueOnce({
+      token: 'impersonation-token',
+      expires_in: 900,
+      user: {
+        id: '2',
+        username: 'employee001',
+        email: 'employee@example.com',
+        role: 'staff',
+        impersonation: { impersonator_id: '1', impersonator_username: 'admin', read_only: true, expires_at: '' },
+      },
+    });
+
+    const { result } = renderHook(() => useAuth(), {
+      wrapper: AuthProvider,
+    });
+
+    await waitFor(() => {
+      expect(result.current.loading).toBe(false);
+    });
+
+    await act(async () => {
+      await result.current.impersonate('2');
+    });
+    expect(mockImpersonateUser).toHaveBeenCalledWith('2', {});
+    expect(result.current.token).toBe('impersonation-token');
+    expect(localStorage.getItem('inventory-manager-auth')).toContain('manager-refresh-token');
+
+    act(() => {
+      result.current.stopImpersonating();
+    });
+    expect(result.current.token).toBe('manager-token');
+    expect(JSON.parse(localStorage.getItem('inventory-manager-auth') ?? '{}').impersonator).toBeUndefined();
+  });
+
+  it('should only start a session once the second factor is verified', async () => {
+    const challenge = {
+      mfa_required: true,
+      enrollment_required: false,
+      challenge_token: 'challenge-token',
+      expires_in: 300,
+    };
+    (loginRequest as ReturnType<typeof vi.fn>).mockResolvedValueOnce(challenge);
+    (verifyMfaRequest as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
+      token: 'mfa-token',
+      refresh_token: 'mfa-refresh-token',
+      expires_in: 900,
+      user: { id: '1', username: 'testuser', email: 'test@example.com', role: 'manager' },
+    });
+
+    const { result } = renderHook(() => useAuth(), {
+      wrapper: AuthProvider,
+    });
+
+    await waitFor(() => {
+      expect(result.current.loading).toBe(false);
+    });
+
+    let response;
+    await act(async () => {
+      response = await result.current.login({ username: 'testuser', password: 'password123', rememberMe: true });
+    });
+    expect(response).toEqual(challenge);
+    expect(result.current.token).toBe(null);
+
+    await act(async () => {
+      await result.current.verifyMfa(challenge, '123456', true);
+    });
+    expect(verifyMfaRequest).toHaveBeenCalledWith('challenge-token', '123456', expect.any(String));
+    expect(result.current.token).toBe('mfa-token');
+    expect(localStorage.getItem('inventory-manager-auth')).toContain('mfa-token');
+  });
+
   it('should prefer localStorage over sessionStorage', () => {
     const localAuth = {
       token: 'local-token',
@@ -1,6 +1,10 @@
 import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
 import { renderHook, waitFor, act } from '@testing-library/react';
-import { InventoryUpdatesProvider, useInventoryUpdates } from '@/context/inventory-updates-context';
+import {
+  InventoryUpdatesProvider,
+  useEntityInvalidation,
+  useInventoryUpdates,
+} from '@/context/inventory-updates-context';
 import { useAuth } from '@/context/auth-context';
 import { InventoryUpdateEvent } from '@/lib/types';
 
@@ -315,6 +319,69 @@ describe('InventoryUpdatesContext', () => {
     consoleErrorSpy.mockRestore();
   });
 
+  it('should ignore change events for non-inventory entities', async () => {
+    const mockToken = 'test-token-123';
+    (useAuth as ReturnType<typeof vi.fn>).mockReturnValue({ token: mockToken });
+
+    const { result } = renderHook(() => useInventoryUpdates(), {
+      wrapper: InventoryUpdatesProvider,
+    });
+
+    const ws = MockWebSocket.instances[0];
+
+    act(() => {
+      ws.simulateOpen();
+    });
+
+    act(() => {
+      ws.simulateMessage(JSON.stringify({
+        id: 'evt-1',
+        operation_type: 'update',
+        entity_type: 'sku',
+        entity_id: 'sku-456',
+        diff: { name: { old: 'Old Name', new: 'New Name' } },
+        sku_id: 'sku-456',
+        sku_name: 'New Name',
+      }));
+    });
+
+    await waitFor(() => {
+      expect(result.current.connected).toBe(true);
+    });
+    expect(result.current.lastEvent).toBe(null);
+  });
+
+  it('should reload subscribers when a matching entity changes', async () => {
+    const mockToken = 'test-token-123';
+    (useAuth as ReturnType<typeof vi.fn>).mockReturnValue({ token: mockToken });
+    const reloadSkus = vi.fn();
+    const reloadUsers = vi.fn();
+
+    renderHook(
+      () => {
+        useEntityInvalidation(['sku'], reloadSkus);
+        useEntityInvalidation(['user'], reloadUsers);
+      },
+      { wrapper: InventoryUpdatesProvider },
+    );
+
+    const ws = MockWebSocket.instances[0];
+
+    act(() => {
+      ws.simulateOpen();
+      ws.simulateMessage(JSON.stringify({
+        id: 'evt-1',
+        operation_type: 'update',
+        entity_type: 'sku',
+        entity_id: 'sku-456',
+        diff: { price: { old: 10, new: 12 } },
+      }));
+    });
+
+    expect(reloadSkus).toHaveBeenCalledTimes(1);
+    expect(reloadUsers).not.toHaveBeenCalled();
+  });
+
   it('should set connected to false on WebSocket error', async () => {
     const mockToken = 'test-token-123';
     (useAuth as ReturnType<typeof vi.fn>).mockReturnValue({ token: mockToken });
@@ -1,5 +1,5 @@
 import { describe, it, expect, vi, beforeEach } from 'vitest';
-import { createApiClient, loginRequest } from '@/lib/api-client';
+import { createApiClient, loginRequest, oidcCallbackRequest, refreshRequest, resetPasswordRequest } from '@/lib/api-client';
 
 describe('loginRequest', () => {
   beforeEach(() => {
@@ -45,6 +45,93 @@ describe('loginRequest', () => {
   });
 });
 
+describe('refreshRequest', () => {
+  beforeEach(() => {
+    global.fetch = vi.fn();
+  });
+
+  it('should make a POST request to /api/auth/refresh', async () => {
+    const mockResponse = {
+      token: 'new-token',
+      refresh_token: 'new-refresh-token',
+      expires_in: 900,
+      user: { id: '1', username: 'testuser', email: 'test@example.com' },
+    };
+
+    (global.fetch as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
+      ok: true,
+      status: 200,
+      json: async () => mockResponse,
+    });
+
+    const result = await refreshRequest('old-refresh-token');
+
+    expect(global.fetch).toHaveBeenCalledWith(
+      'http://localhost:8080/api/auth/refresh',
+      expect.objectContaining({
+        method: 'POST',
+        body: JSON.stringify({ refresh_token: 'old-refresh-token' }),
+      })
+    );
+    expect(result).toEqual(mockResponse);
+  });
+});
+
+describe('resetPasswordRequest', () => {
+  beforeEach(() => {
+    global.fetch = vi.fn();
+  });
+
+  it('should make a POST request to /api/auth/reset-password', async () => {
+    (global.fetch as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
+      ok: true,
+      status: 200,
+      json: async () => ({ message: 'Password reset successfully' }),
+    });
+
+    await resetPasswordRequest('reset-token', 'newpassword123');
+
+    expect(global.fetch).toHaveBeenCalledWith(
+      'http://localhost:8080/api/auth/reset-password',
+      expect.objectContaining({
+        method: 'POST',
+        body: JSON.stringify({ token: 'reset-token', new_password: 'newpassword123' }),
+      })
+    );
+  });
+});
+
+describe('oidcCallbackRequest', () => {
+  beforeEach(() => {
+    global.fetch = vi.fn();
+  });
+
+  it('should post the authorization code, state and binding to /api/auth/oidc/callback', async () => {
+    const mockResponse = {
+      token: 'test-token',
+      refresh_token: 'test-refresh',
+      expires_in: 900,
+      user: { id: '1', username: 'sso-user', email: 'sso@example.com' },
+    };
+    (global.fetch as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
+      ok: true,
+      status: 200,
+      json: async () => mockResponse,
+    });
+
+    const result = await oidcCallbackRequest('auth-code', 'login-state', 'tab-binding');
+
+    expect(global.fetch).toHaveBeenCalledWith(
+      'http://localhost:8080/api/auth/oidc/callback',
+      expect.objectContaining({
+        method: 'POST',
+        body: JSON.stringify({ code: 'auth-code', state: 'login-state', binding: 'tab-binding' }),
+      })
+    );
+    expect(result).toEqual(mockResponse);
+  });
+});
+
 describe('createApiClient', () => {
   const mockToken = 'test-token-123';
   let api: ReturnType<typeof createApiClient>;
@@ -98,6 +185,25 @@ describe('createApiClient', () => {
     );
   });
 
+  it('should deactivate a user with a reason', async () => {
+    (global.fetch as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
+      ok: true,
+      status: 200,
+      json: async () => ({ id: 'user-123', status: 'deactivated', deactivation_reason: 'Left the company' }),
+    });
+
+    const result = await api.deactivateUser('user-123', 'Left the company');
+
+    expect(global.fetch).toHaveBeenCalledWith(
+      'http://localhost:8080/api/manager/users/user-123/deactivate',
+      expect.objectContaining({
+        method: 'POST',
+        body: JSON.stringify({ reason: 'Left the company' }),
+      })
+    );
+    expect(result.status).toBe('deactivated');
+  });
+
   it('should handle 204 No Content responses', async () => {
     (global.fetch as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
       ok: true,
@@ -108,4 +214,106 @@ describe('createApiClient', () => {
 
     expect(result).toBeNull();
   });
+
+  it('should list the stores assigned to the current user', async () => {
+    (global.fetch as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
+      ok: true,
+      status: 200,
+      json: async () => ({
+        items: [
+          {
+            id: 'store-1',
+            name: 'Main Street Store',
+            store_role: null,
+            stock: { sku_count: 12, total_quantity: 340, out_of_stock_count: 1, as_of: '2025-01-20T09:00:00Z' },
+          },
+        ],
+      }),
+    });
+
+    const result = await api.listMyStores();
+
+    expect(global.fetch).toHaveBeenCalledWith('http://localhost:8080/api/profile/stores', expect.any(Object));
+    expect(result.items[0].stock?.sku_count).toBe(12);
+  });
+
+  it('should get a store outside the manager routes', async () => {
+    (global.fetch as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
+      ok: true,
+      status: 200,
+      json: async () => ({ id: 'store-1', name: 'Main Street Store', assigned: true }),
+    });
+
+    const result = await api.getStore('store-1');
+
+    expect(global.fetch).toHaveBeenCalledWith('http://localhost:8080/api/stores/store-1', expect.any(Object));
+    expect(result.assigned).toBe(true);
+  });
+
+  it('should redeem a single sign-on link for the current user', async () => {
+    (global.fetch as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
+      ok: true,
+      status: 200,
+      json: async () => ({ message: 'Single sign-on linked' }),
+    });
+
+    await api.completeSsoLink('auth-code', 'link-state', 'tab-binding');
+
+    expect(global.fetch).toHaveBeenCalledWith(
+      'http://localhost:8080/api/profile/sso/link/callback',
+      expect.objectContaining({
+        method: 'POST',
+        body: JSON.stringify({ code: 'auth-code', state: 'link-state', binding: 'tab-binding' }),
+        headers: expect.objectContaining({ Authorization: `Bearer ${mockToken}` }),
+      })
+    );
+  });
+
+  it('should export the audit log as CSV without paging', async () => {
+    const csv = new Blob(['id,created_at\n'], { type: 'text/csv' });
+    (global.fetch as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
+      ok: true,
+      status: 200,
+      blob: async () => csv,
+    });
+
+    const result = await api.exportAuditLog({ actor: 'alice', page: 3, page_size: 50 });
+
+    expect(global.fetch).toHaveBeenCalledWith(
+      'http://localhost:8080/api/manager/audit?actor=alice&format=csv',
+      expect.any(Object)
+    );
+    expect(result).toBe(csv);
+  });
+
+  it('should refresh the access token and retry once on 401', async () => {
+    const mockUser = { id: '1', username: 'testuser', email: 'test@example.com', role: 'staff' };
+    const refresher = vi.fn().mockResolvedValue('refreshed-token');
+    const refreshingApi = createApiClient(mockToken, undefined, refresher);
+
+    (global.fetch as ReturnType<typeof vi.fn>)
+      .mockResolvedValueOnce({
+        ok: false,
+        status: 401,
+        json: async () => ({ message: 'Invalid or expired token' }),
+      })
+      .mockResolvedValueOnce({
+        ok: true,
+        status: 200,
+        json: async () => mockUser,
+      });
+
+    const result = await refreshingApi.getProfile();
+
+    expect(refresher).toHaveBeenCalledTimes(1);
+    expect(global.fetch).toHaveBeenLastCalledWith(
+      'http://localhost:8080/api/profile',
+      expect.objectContaining({
+        headers: expect.objectContaining({
+          Authorization: 'Bearer refreshed-token',
+        }),
+      })
+    );
+    expect(result).toEqual(mockUser);
+  });
 });
@@ -0,0 +1,82 @@
+'use client';
+
+import { FormEvent, useState } from 'react';
+import Link from 'next/link';
+import { useServer } from '@/context/server-context';
+import { forgotPasswordRequest } from '@/lib/api-client';
+
+export default function ForgotPasswordPage() {
+  const { selectedServer } = useServer();
+  const [email, setEmail] = useState('');
+  const [error, setError] = useState<string | null>(null);
+  const [message, setMessage] = useState<string | null>(null);
+  const [submitting, setSubmitting] = useState(false);
+
+  const handleSubmit = async (event: FormEvent) => {
+    event.preventDefault();
+    setError(null);
+    setMessage(null);
+
+    if (!email.trim()) {
+      setError('Email is required');
+      return;
+    }
+
+    setSubmitting(true);
+    try {
+      const response = await forgotPasswordRequest(email.trim(), selectedServer.url);
+      setMessage(response.message);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Request failed');
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-slate-950 p-4">
+      <div className="w-full max-w-lg rounded-3xl border border-white/10 bg-slate-900/80 p-8 shadow-2xl shadow-cyan-500/10 backdrop-blur">
+        <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Inventory Manager</p>
+        <h1 className="mt-2 text-3xl font-semibold text-white">Forgot password</h1>
+        <p className="mt-1 text-sm text-slate-400">We will email you a link to choose a new password.</p>
+
+        <form onSubmit={handleSubmit} className="mt-8 space-y-6">
+          <div className="space-y-2">
+            <label className="label" htmlFor="email">
+              Email
+            </label>
+            <input
+              id="email"
+              type="email"
+              className="input"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              autoComplete="email"
+              required
+            />
+          </div>
+
+          {message && (
+            <div className="rounded-xl border border-emerald-400/40 bg-emerald-500/10 p-3 text-sm text-emerald-100">
+              {message}
+            </div>
+          )}
+          {error && (
+            <div className="rounded-xl border border-rose-400/40 bg-rose-500/10 p-3 text-sm text-rose-100">
+              {error}
+            </div>
+          )}
+
+          <button type="submit" className="btn-primary w-full py-3 text-base" disabled={submitting}>
+            {submitting ? 'Sending…' : 'Send reset link'}
+          </button>
+          <p className="text-center text-sm text-slate-400">
+            <Link href="/login" className="text-cyan-300 hover:text-cyan-200">
+              Back to sign in
+            </Link>
+          </p>
+        </form>
+      </div>
+    </div>
+  );
+}
@@ -1,26 +1,77 @@
 'use client';
 
-import { FormEvent, useEffect, useState } from 'react';
+import { FormEvent, useEffect, useRef, useState } from 'react';
+import Link from 'next/link';
 import { useRouter } from 'next/navigation';
 import { useAuth } from '@/context/auth-context';
 import { useServer } from '@/context/server-context';
+import { enrollMfaChallengeRequest, isMfaChallenge, oidcConfigRequest } from '@/lib/api-client';
+import { MfaChallengeResponse, MfaEnrollment, OidcConfig } from '@/lib/types';
 
 export default function LoginPage() {
-  const { login, token, loading } = useAuth();
+  const { login, verifyMfa, startOidcLogin, takeOidcChallenge, token, loading } = useAuth();
   const { selectedServer, setSelectedServer, servers } = useServer();
   const router = useRouter();
-  const [username, setUsername] = useState('admin');
-  const [password, setPassword] = useState('adminadmin');
+  const [username, setUsername] = useState('');
+  const [password, setPassword] = useState('');
   const [rememberMe, setRememberMe] = useState(true);
   const [error, setError] = useState<string | null>(null);
   const [submitting, setSubmitting] = useState(false);
+  const [challenge, setChallenge] = useState<MfaChallengeResponse | null>(null);
+  const [enrollment, setEnrollment] = useState<MfaEnrollment | null>(null);
+  const [code, setCode] = useState('');
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
+  // Keeps the user here until they have seen the recovery codes issued at enrolment
+  const holdRedirect = useRef(false);
+  const [oidcConfig, setOidcConfig] = useState<OidcConfig | null>(null);
 
   useEffect(() => {
-    if (!loading && token) {
+    if (!loading && token && !holdRedirect.current) {
       router.replace('/dashboard');
     }
   }, [loading, token, router]);
 
+  // Single sign-on logins that need a second factor continue here
+  useEffect(() => {
+    const pending = takeOidcChallenge();
+    if (!pending) return;
+    setChallenge(pending.challenge);
+    setRememberMe(pending.rememberMe);
+    if (pending.challenge.enrollment_required) {
+      enrollMfaChallengeRequest(pending.challenge.challenge_token, selectedServer.url)
+        .then(setEnrollment)
+        .catch((err) => setError(err instanceof Error ? err.message : 'Failed to start setup'));
+    }
+    // Only on arrival from the callback page
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, []);
+
+  useEffect(() => {
+    let cancelled = false;
+    setOidcConfig(null);
+    oidcConfigRequest(selectedServer.url)
+      .then((config) => {
+        if (!cancelled) setOidcConfig(config);
+      })
+      .catch(() => {
+        // Servers without single sign-on support only offer password login
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, [selectedServer.url]);
+
+  const handleOidcLogin = async () => {
+    setError(null);
+    setSubmitting(true);
+    try {
+      await startOidcLogin(rememberMe);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Single sign-on failed');
+      setSubmitting(false);
+    }
+  };
+
   const handleSubmit = async (event: FormEvent) => {
     event.preventDefault();
     setError(null);
@@ -45,7 +96,14 @@ export default function LoginPage() {
 
     setSubmitting(true);
     try {
-      await login({ username, password, rememberMe });
+      const response = await login({ username, password, rememberMe });
+      if (isMfaChallenge(response)) {
+        setChallenge(response);
+        if (response.enrollment_required) {
+          setEnrollment(await enrollMfaChallengeRequest(response.challenge_token, selectedServer.url));
+        }
+        return;
+      }
       router.replace('/dashboard');
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Login failed');
@@ -54,6 +112,127 @@ export default function LoginPage() {
     }
   };
 
+  const handleVerify = async (event: FormEvent) => {
+    event.preventDefault();
+    if (!challenge) return;
+    setError(null);
+
+    if (!code.trim()) {
+      setError('Code is required');
+      return;
+    }
+
+    setSubmitting(true);
+    holdRedirect.current = challenge.enrollment_required;
+    try {
+      const response = await verifyMfa(challenge, code.trim(), rememberMe);
+      if (response.recovery_codes?.length) {
+        setRecoveryCodes(response.recovery_codes);
+        return;
+      }
+      router.replace('/dashboard');
+    } catch (err) {
+      holdRedirect.current = false;
+      setError(err instanceof Error ? err.message : 'Verification failed');
+      // Expired challenges cannot be retried; start over with the password
+      if (err instanceof Error && err.message.includes('challenge')) {
+        setChallenge(null);
+        setEnrollment(null);
+      }
+    } finally {
+      setCode('');
+      setSubmitting(false);
+    }
+  };
+
+  if (recoveryCodes) {
+    return (
+      <div className="flex min-h-screen items-center justify-center bg-slate-950 p-4">
+        <div className="w-full max-w-lg rounded-3xl border border-white/10 bg-slate-900/80 p-8 shadow-2xl shadow-cyan-500/10 backdrop-blur">
+          <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Inventory Manager</p>
+          <h1 className="mt-2 text-3xl font-semibold text-white">Save your recovery codes</h1>
+          <p className="mt-1 text-sm text-slate-400">
+            Each code signs you in once if you lose your authenticator. They will not be shown again.
+          </p>
+          <ul className="mt-6 grid grid-cols-2 gap-2 rounded-xl border border-white/10 bg-slate-950/60 p-4 font-mono text-sm text-slate-100">
+            {recoveryCodes.map((recoveryCode) => (
+              <li key={recoveryCode}>{recoveryCode}</li>
+            ))}
+          </ul>
+          <button className="btn-primary mt-6 w-full py-3 text-base" onClick={() => router.replace('/dashboard')}>
+            I have saved my codes
+          </button>
+        </div>
+      </div>
+    );
+  }
+
+  if (challenge) {
+    return (
+      <div className="flex min-h-screen items-center justify-center bg-slate-950 p-4">
+        <div className="w-full max-w-lg rounded-3xl border border-white/10 bg-slate-900/80 p-8 shadow-2xl shadow-cyan-500/10 backdrop-blur">
+          <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Inventory Manager</p>
+          <h1 className="mt-2 text-3xl font-semibold text-white">Two-factor authentication</h1>
+          <p className="mt-1 text-sm text-slate-400">
+            {challenge.enrollment_required
+              ? 'Your role requires two-factor authentication. Add this account to your authenticator app, then enter the code it shows.'
+              : 'Enter the code from your authenticator app, or one of your recovery codes.'}
+          </p>
+
+          {enrollment && (
+            <div className="mt-6 space-y-2 rounded-xl border border-white/10 bg-slate-950/60 p-4 text-sm">
+              <p className="text-slate-400">Secret key</p>
+              <p className="break-all font-mono text-slate-100">{enrollment.secret}</p>
+              <a href={enrollment.provisioning_uri} className="text-cyan-300 hover:text-cyan-200">
+                Open in authenticator app
+              </a>
+            </div>
+          )}
+
+          <form onSubmit={handleVerify} className="mt-8 space-y-6">
+            <div className="space-y-2">
+              <label className="label" htmlFor="code">
+                Code
+              </label>
+              <input
+                id="code"
+                className="input font-mono"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                autoComplete="one-time-code"
+                autoFocus
+                required
+              />
+            </div>
+
+            {error && (
+              <div className="rounded-xl border border-rose-400/40 bg-rose-500/10 p-3 text-sm text-rose-100">
+                {error}
+              </div>
+            )}
+
+            <button type="submit" className="btn-primary w-full py-3 text-base" disabled={submitting}>
+              {submitting ? 'Verifying…' : 'Verify'}
+            </button>
+            <p className="text-center text-sm text-slate-400">
+              <button
+                type="button"
+                className="text-cyan-300 hover:text-cyan-200"
+                onClick={() => {
+                  setChallenge(null);
+                  setEnrollment(null);
+                  setError(null);
+                }}
+              >
+                Back to sign in
+              </button>
+            </p>
+          </form>
+        </div>
+      </div>
+    );
+  }
+
   return (
     <div className="flex min-h-screen items-center justify-center bg-slate-950 p-4">
       <div className="w-full max-w-lg rounded-3xl border border-white/10 bg-slate-900/80 p-8 shadow-2xl shadow-cyan-500/10 backdrop-blur">
@@ -120,7 +299,6 @@ export default function LoginPage() {
               />
               Remember me
             </label>
-            <p className="text-xs text-slate-500">Default admin: admin/adminadmin</p>
           </div>
 
           {error && (
@@ -132,6 +310,21 @@ export default function LoginPage() {
           <button type="submit" className="btn-primary w-full py-3 text-base" disabled={submitting}>
             {submitting ? 'Signing in…' : 'Sign in'}
           </button>
+          {oidcConfig?.enabled && (
+            <button
+              type="button"
+              className="btn-secondary w-full py-3 text-base"
+              onClick={handleOidcLogin}
+              disabled={submitting}
+            >
+              Sign in with {oidcConfig.name || 'SSO'}
+            </button>
+          )}
+          <p className="text-center text-sm text-slate-400">
+            <Link href="/forgot-password" className="text-cyan-300 hover:text-cyan-200">
+              Forgot password?
+            </Link>
+          </p>
         </form>
       </div>
     </div>
@@ -0,0 +1,69 @@
+'use client';
+
+import { Suspense, useEffect, useRef, useState } from 'react';
+import Link from 'next/link';
+import { useRouter, useSearchParams } from 'next/navigation';
+import { useAuth } from '@/context/auth-context';
+import { isMfaChallenge } from '@/lib/api-client';
+
+function OidcCallback() {
+  const { completeOidcLogin, oidcLinkPending, completeOidcLink, loading } = useAuth();
+  const router = useRouter();
+  const searchParams = useSearchParams();
+  const [error, setError] = useState<string | null>(null);
+  // The code is single-use, so it must not be redeemed twice (e.g. by a Strict Mode re-run)
+  const started = useRef(false);
+
+  useEffect(() => {
+    // Links need the restored session of the signed-in user
+    if (loading || started.current) return;
+    started.current = true;
+
+    const code = searchParams.get('code');
+    const state = searchParams.get('state');
+    if (!code || !state) {
+      setError(searchParams.get('error_description') ?? searchParams.get('error') ?? 'The sign-in link is invalid.');
+      return;
+    }
+
+    if (oidcLinkPending()) {
+      completeOidcLink(code, state)
+        .then(() => router.replace('/dashboard/profile'))
+        .catch((err) => setError(err instanceof Error ? err.message : 'Failed to link single sign-on'));
+      return;
+    }
+
+    // Users with two-factor authentication enter their code on the login page
+    completeOidcLogin(code, state)
+      .then((response) => router.replace(isMfaChallenge(response) ? '/login' : '/dashboard'))
+      .catch((err) => setError(err instanceof Error ? err.message : 'Single sign-on failed'));
+  }, [completeOidcLink, completeOidcLogin, loading, oidcLinkPending, router, searchParams]);
+
+  if (error) {
+    return (
+      <div className="mt-8 space-y-6">
+        <div className="rounded-xl border border-rose-400/40 bg-rose-500/10 p-3 text-sm text-rose-100">{error}</div>
+        <Link href="/login" className="btn-primary block w-full py-3 text-center text-base">
+          Back to sign in
+        </Link>
+      </div>
+    );
+  }
+
+  return <p className="mt-8 text-sm text-slate-400">Signing you in…</p>;
+}
+
+export default function OidcCallbackPage() {
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-slate-950 p-4">
+      <div className="w-full max-w-lg rounded-3xl border border-white/10 bg-slate-900/80 p-8 shadow-2xl shadow-cyan-500/10 backdrop-blur">
+        <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Inventory Manager</p>
+        <h1 className="mt-2 text-3xl font-semibold text-white">Single sign-on</h1>
+        {/* useSearchParams needs a Suspense boundary for static rendering */}
+        <Suspense fallback={null}>
+          <OidcCallback />
+        </Suspense>
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,108 @@
+'use client';
+
+import { FormEvent, Suspense, useState } from 'react';
+import Link from 'next/link';
+import { useRouter, useSearchParams } from 'next/navigation';
+import { useServer } from '@/context/server-context';
+import { resetPasswordRequest } from '@/lib/api-client';
+
+function ResetPasswordForm() {
+  const { selectedServer } = useServer();
+  const router = useRouter();
+  const token = useSearchParams().get('token') ?? '';
+  const [password, setPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [error, setError] = useState<string | null>(null);
+  const [submitting, setSubmitting] = useState(false);
+
+  const handleSubmit = async (event: FormEvent) => {
+    event.preventDefault();
+    setError(null);
+
+    // Validation
+    if (!token) {
+      setError('This reset link is invalid. Request a new one.');
+      return;
+    }
+    if (password.length < 8) {
+      setError('Password must be at least 8 characters');
+      return;
+    }
+    if (password !== confirmPassword) {
+      setError('Passwords do not match');
+      return;
+    }
+
+    setSubmitting(true);
+    try {
+      await resetPasswordRequest(token, password, selectedServer.url);
+      router.replace('/login');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Password reset failed');
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  return (
+    <form onSubmit={handleSubmit} className="mt-8 space-y-6">
+      <div className="space-y-2">
+        <label className="label" htmlFor="password">
+          New password
+        </label>
+        <input
+          id="password"
+          type="password"
+          className="input"
+          value={password}
+          onChange={(e) => setPassword(e.target.value)}
+          autoComplete="new-password"
+          required
+        />
+      </div>
+      <div className="space-y-2">
+        <label className="label" htmlFor="confirm-password">
+          Confirm new password
+        </label>
+        <input
+          id="confirm-password"
+          type="password"
+          className="input"
+          value={confirmPassword}
+          onChange={(e) => setConfirmPassword(e.target.value)}
+          autoComplete="new-password"
+          required
+        />
+      </div>
+
+      {error && (
+        <div className="rounded-xl border border-rose-400/40 bg-rose-500/10 p-3 text-sm text-rose-100">
+          {error}{' '}
+          <Link href="/forgot-password" className="underline">
+            Request a new link
+          </Link>
+        </div>
+      )}
+
+      <button type="submit" className="btn-primary w-full py-3 text-base" disabled={submitting}>
+        {submitting ? 'Saving…' : 'Set new password'}
+      </button>
+    </form>
+  );
+}
+
+export default function ResetPasswordPage() {
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-slate-950 p-4">
+      <div className="w-full max-w-lg rounded-3xl border border-white/10 bg-slate-900/80 p-8 shadow-2xl shadow-cyan-500/10 backdrop-blur">
+        <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Inventory Manager</p>
+        <h1 className="mt-2 text-3xl font-semibold text-white">Choose a new password</h1>
+        <p className="mt-1 text-sm text-slate-400">You will be signed out of all sessions.</p>
+        {/* useSearchParams needs a Suspense boundary for static rendering */}
+        <Suspense fallback={null}>
+          <ResetPasswordForm />
+        </Suspense>
+      </div>
+    </div>
+  );
+}
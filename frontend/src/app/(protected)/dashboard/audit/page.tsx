@@ -1,43 +1,84 @@
 'use client';
 
-import { useMemo, useState } from 'react';
+import { useCallback, useEffect, useState } from 'react';
 import { useAuth } from '@/context/auth-context';
+import { hasPermission } from '@/lib/permissions';
 import { useApiQuery } from '@/hooks/useApiQuery';
-import { InventoryRecord } from '@/lib/types';
+import { AuditFilters, AuditLogEntry } from '@/lib/types';
+
+const PAGE_SIZE = 50;
+
+const emptyFilters = {
+  actor: '',
+  action: '',
+  start: '',
+  end: '',
+};
 
 export default function AuditPage() {
   const { api, user } = useAuth();
-  const [filters, setFilters] = useState({
-    store_id: '',
-    sku_id: '',
-    start: '',
-    end: '',
-  });
-  const inventoryQuery = useApiQuery(
-    api
-      ? () =>
-          api.listInventory({
-            page: 1,
-            page_size: 100,
-            order: 'desc',
-            sort_by: 'updated_at',
-          })
-      : null,
+  const canRead = hasPermission(user, 'audit.read');
+  const [filters, setFilters] = useState(emptyFilters);
+  const [page, setPage] = useState(1);
+  const [exporting, setExporting] = useState(false);
+  const [exportError, setExportError] = useState<string | null>(null);
+
+  // Dates are whole days in the browser's time zone; the end day is inclusive
+  const query: AuditFilters = {
+    actor: filters.actor || undefined,
+    action: filters.action || undefined,
+    from: filters.start ? new Date(`${filters.start}T00:00:00`).toISOString() : undefined,
+    to: filters.end ? new Date(`${filters.end}T23:59:59.999`).toISOString() : undefined,
+  };
+
+  const auditQuery = useApiQuery(
+    api && canRead ? () => api.listAuditLog({ ...query, page, page_size: PAGE_SIZE }) : null,
+    { skipInitial: true },
   );
-  const storesQuery = useApiQuery(api && user?.role === 'manager' ? () => api.listStores() : null);
-  const skuQuery = useApiQuery(api ? () => api.listSkus({ page: 1, page_size: 100 }) : null);
+  const { reload } = auditQuery;
+
+  // The query hook only fetches once, so refetch whenever the filters or page change
+  useEffect(() => {
+    if (api && canRead) {
+      reload();
+    }
+  }, [api, canRead, filters, page, reload]);
+
+  const updateFilter = (key: keyof typeof emptyFilters, value: string) => {
+    setFilters((prev) => ({ ...prev, [key]: value }));
+    setPage(1);
+  };
 
-  const filteredEntries = useMemo(() => {
-    const records = inventoryQuery.data?.items ?? [];
-    return records.filter((record) => {
-      const matchesStore = filters.store_id ? record.store_id === filters.store_id : true;
-      const matchesSku = filters.sku_id ? record.sku_id === filters.sku_id : true;
-      const updatedAt = new Date(record.updated_at);
-      const matchesStart = filters.start ? updatedAt >= new Date(filters.start) : true;
-      const matchesEnd = filters.end ? updatedAt <= new Date(filters.end) : true;
-      return matchesStore && matchesSku && matchesStart && matchesEnd;
-    });
-  }, [filters, inventoryQuery.data]);
+  const handleExport = useCallback(async () => {
+    if (!api) return;
+    setExporting(true);
+    setExportError(null);
+    try {
+      const blob = await api.exportAuditLog(query);
+      const url = URL.createObjectURL(blob);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = `audit-log-${new Date().toISOString().slice(0, 10)}.csv`;
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch (error) {
+      setExportError(error instanceof Error ? error.message : 'Failed to export audit log');
+    } finally {
+      setExporting(false);
+    }
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [api, filters]);
+
+  if (!canRead) {
+    return (
+      <div className="card">
+        <p className="text-sm text-slate-400">You do not have permission to view the audit log.</p>
+      </div>
+    );
+  }
+
+  const entries = auditQuery.data?.items ?? [];
+  const totalPages = auditQuery.data?.total_pages ?? 1;
 
   return (
     <div className="space-y-6">
@@ -45,78 +86,114 @@ export default function AuditPage() {
         <header className="flex flex-col gap-3 md:flex-row md:items-center md:justify-between">
           <div>
             <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Audit log</p>
-            <h1 className="text-2xl font-semibold text-white">Inventory adjustment timeline</h1>
+            <h1 className="text-2xl font-semibold text-white">Administrative changes</h1>
             <p className="text-sm text-slate-400">
-              Data derived from GET /api/inventory. Filter by store, SKU, or time range.
+              Who changed users, roles, stores, SKUs and service accounts, and when. Entries cannot be edited.
             </p>
           </div>
-          <div className="flex flex-wrap gap-3">
-            <select
-              className="input w-44"
-              value={filters.store_id}
-              onChange={(e) => setFilters((prev) => ({ ...prev, store_id: e.target.value }))}
-            >
-              <option value="">All stores</option>
-              {(storesQuery.data?.items ?? []).map((store) => (
-                <option key={store.id} value={store.id}>
-                  {store.name}
-                </option>
-              ))}
-            </select>
-            <select
-              className="input w-44"
-              value={filters.sku_id}
-              onChange={(e) => setFilters((prev) => ({ ...prev, sku_id: e.target.value }))}
-            >
-              <option value="">All SKUs</option>
-              {(skuQuery.data?.items ?? []).map((sku) => (
-                <option key={sku.id} value={sku.id}>
-                  {sku.name}
-                </option>
-              ))}
-            </select>
-            <input
-              type="date"
-              className="input"
-              value={filters.start}
-              onChange={(e) => setFilters((prev) => ({ ...prev, start: e.target.value }))}
-            />
-            <input
-              type="date"
-              className="input"
-              value={filters.end}
-              onChange={(e) => setFilters((prev) => ({ ...prev, end: e.target.value }))}
-            />
-            <button className="btn-secondary text-sm" onClick={() => setFilters({ store_id: '', sku_id: '', start: '', end: '' })}>
-              Clear
-            </button>
-          </div>
+          <button className="btn-secondary text-sm" onClick={handleExport} disabled={exporting}>
+            {exporting ? 'Exporting…' : 'Export CSV'}
+          </button>
         </header>
+        <div className="flex flex-wrap gap-3">
+          <input
+            className="input w-44"
+            placeholder="Actor"
+            value={filters.actor}
+            onChange={(e) => updateFilter('actor', e.target.value)}
+          />
+          <input
+            className="input w-44"
+            placeholder="Action (e.g. user.delete)"
+            value={filters.action}
+            onChange={(e) => updateFilter('action', e.target.value)}
+          />
+          <input
+            type="date"
+            className="input"
+            value={filters.start}
+            onChange={(e) => updateFilter('start', e.target.value)}
+          />
+          <input
+            type="date"
+            className="input"
+            value={filters.end}
+            onChange={(e) => updateFilter('end', e.target.value)}
+          />
+          <button
+            className="btn-secondary text-sm"
+            onClick={() => {
+              setFilters(emptyFilters);
+              setPage(1);
+            }}
+          >
+            Clear
+          </button>
+        </div>
+        {exportError && <p className="text-sm text-rose-400">{exportError}</p>}
       </section>
 
       <section className="space-y-4">
-        {filteredEntries.map((record: InventoryRecord) => (
-          <article key={record.id} className="card flex flex-col gap-2 md:flex-row md:items-center md:justify-between">
-            <div>
-              <p className="text-sm text-slate-400">
-                {record.store?.name ?? record.store_id} · {record.sku?.name ?? record.sku_id}
-              </p>
-              <p className="text-2xl font-semibold text-white">{record.quantity} units</p>
-              <p className="text-xs text-slate-500">v{record.version} · {new Date(record.updated_at).toLocaleString()}</p>
-            </div>
-            <div className="text-right text-xs text-slate-400">
-              <p>ID: {record.id}</p>
-              <p>Created: {new Date(record.created_at).toLocaleString()}</p>
-            </div>
-          </article>
+        {auditQuery.error && (
+          <div className="card">
+            <p className="text-sm text-rose-400">{auditQuery.error}</p>
+          </div>
+        )}
+        {entries.map((entry) => (
+          <AuditEntry key={entry.id} entry={entry} />
         ))}
-        {filteredEntries.length === 0 && (
+        {!auditQuery.loading && !auditQuery.error && entries.length === 0 && (
           <div className="card">
             <p className="text-sm text-slate-400">No entries for this filter.</p>
           </div>
         )}
+        {totalPages > 1 && (
+          <div className="flex items-center justify-between text-sm text-slate-400">
+            <button className="btn-secondary text-sm" disabled={page <= 1} onClick={() => setPage((p) => p - 1)}>
+              Previous
+            </button>
+            <span>
+              Page {page} of {totalPages}
+            </span>
+            <button className="btn-secondary text-sm" disabled={page >= totalPages} onClick={() => setPage((p) => p + 1)}>
+              Next
+            </button>
+          </div>
+        )}
       </section>
     </div>
   );
 }
 
+function AuditEntry({ entry }: { entry: AuditLogEntry }) {
+  const changes = Object.entries(entry.diff ?? {});
+  return (
+    <article className="card space-y-2">
+      <div className="flex flex-col gap-1 md:flex-row md:items-center md:justify-between">
+        <p className="text-sm text-white">
+          <span className="font-semibold">{entry.actor_name || 'System'}</span>{' '}
+          <span className="text-slate-400">{entry.action}</span> {entry.target_name || entry.target_id}
+        </p>
+        <p className="text-xs text-slate-500">{new Date(entry.created_at).toLocaleString()}</p>
+      </div>
+      {changes.length > 0 && (
+        <ul className="space-y-1 text-xs text-slate-400">
+          {changes.map(([field, change]) => (
+            <li key={field}>
+              <span className="text-slate-300">{field}</span>: {formatValue(change.old)} → {formatValue(change.new)}
+            </li>
+          ))}
+        </ul>
+      )}
+      <p className="text-xs text-slate-500">
+        {entry.client_ip && <>IP {entry.client_ip} · </>}
+        {entry.request_id && <>Request {entry.request_id}</>}
+      </p>
+    </article>
+  );
+}
+
+function formatValue(value: unknown) {
+  if (value === undefined || value === null) return '—';
+  return typeof value === 'string' ? value : JSON.stringify(value);
+}
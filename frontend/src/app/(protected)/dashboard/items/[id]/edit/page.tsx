@@ -12,7 +12,7 @@ export default function EditItemPage() {
   const { api } = useAuth();
   const skuId = params?.id as string;
   const skuQuery = useApiQuery(api ? () => api.getSku(skuId) : null);
-  const categoriesQuery = useApiQuery(api ? () => api.listSkuCategories() : null);
+  const categoriesQuery = useApiQuery(api ? () => api.listSkuCategories() : null, { invalidateOn: ['sku'] });
 
   const sku = skuQuery.data as SKU | undefined;
 
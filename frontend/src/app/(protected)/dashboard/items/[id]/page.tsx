@@ -5,6 +5,7 @@ import { useParams, useRouter } from 'next/navigation';
 import Link from 'next/link';
 import { Trash2Icon } from 'lucide-react';
 import { useAuth } from '@/context/auth-context';
+import { hasPermission } from '@/lib/permissions';
 import { useApiQuery } from '@/hooks/useApiQuery';
 import { InventoryRecord, SKU, Store } from '@/lib/types';
 import { ConfirmDialog } from '@/components/ConfirmDialog';
@@ -28,7 +29,7 @@ export default function ItemDetailPage() {
     onConfirm: () => void;
   }>({ isOpen: false, title: '', message: '', onConfirm: () => {} });
 
-  const skuQuery = useApiQuery(api ? () => api.getSku(skuId) : null);
+  const skuQuery = useApiQuery(api ? () => api.getSku(skuId) : null, { invalidateOn: ['sku'] });
   const inventoryQuery = useApiQuery(
     api
       ? () =>
@@ -40,12 +41,21 @@ export default function ItemDetailPage() {
           })
       : null,
   );
-  const storesQuery = useApiQuery(api && user?.role === 'manager' ? () => api.listStores() : null);
+  const storesQuery = useApiQuery(api && hasPermission(user, 'stores.manage') ? () => api.listStores() : null, {
+    invalidateOn: ['store'],
+  });
+  // Without stores.manage, offer the stores the user is assigned to
+  const myStoresQuery = useApiQuery(api && !hasPermission(user, 'stores.manage') ? () => api.listMyStores() : null, {
+    invalidateOn: ['store', 'store_user'],
+  });
 
   const sku = skuQuery.data as SKU | undefined;
   const inventoryItems = inventoryQuery.data?.items ?? [];
 
-  const stores: Store[] = useMemo(() => (storesQuery.data?.items ?? []), [storesQuery.data]);
+  const stores: Store[] = useMemo(
+    () => (hasPermission(user, 'stores.manage') ? storesQuery.data?.items : myStoresQuery.data?.items) ?? [],
+    [user, storesQuery.data, myStoresQuery.data],
+  );
 
   const selectInventory = useCallback(
     (record: InventoryRecord) => {
@@ -160,7 +170,7 @@ export default function ItemDetailPage() {
             <Link href={`/dashboard/items/${sku.id}/edit`} className="btn-secondary">
               Edit
             </Link>
-            {user?.role === 'manager' && (
+            {hasPermission(user, 'sku.manage') && (
               <button
                 onClick={handleDeleteSku}
                 className="flex items-center gap-2 rounded-2xl border border-rose-500/40 px-4 py-2 text-sm text-rose-100"
@@ -233,7 +243,7 @@ export default function ItemDetailPage() {
                       >
                         Manage
                       </button>
-                      {user?.role === 'manager' && (
+                      {hasPermission(user, 'inventory.manage') && (
                         <button
                           className="ml-2 rounded-xl border border-rose-500/40 px-3 py-1 text-xs text-rose-100"
                           onClick={() => handleDeleteInventory(record)}
@@ -273,7 +283,7 @@ export default function ItemDetailPage() {
                 </div>
                 <p className="mt-1 text-xs text-slate-400">POST /api/inventory/{selectedInventory.id}/adjust</p>
               </div>
-              {user?.role === 'manager' && (
+              {hasPermission(user, 'inventory.update') && (
                 <>
                   <div className="rounded-2xl border border-white/5 bg-white/5 p-4">
                     <p className="text-xs uppercase text-slate-500">Set quantity</p>
@@ -312,7 +322,7 @@ export default function ItemDetailPage() {
             <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Store coverage</p>
             <h2 className="text-xl font-semibold text-white">Add or move inventory</h2>
           </header>
-          {user?.role === 'manager' ? (
+          {hasPermission(user, 'inventory.manage') ? (
             <div className="grid gap-4 md:grid-cols-2">
               <div className="rounded-2xl border border-white/5 bg-white/5 p-4 space-y-3">
                 <p className="text-sm text-slate-300">Create new inventory record</p>
@@ -14,8 +14,8 @@ export default function CreateItemPage() {
   const { api } = useAuth();
   const router = useRouter();
 
-  const categoriesQuery = useApiQuery(api ? () => api.listSkuCategories() : null);
-  const storesQuery = useApiQuery(api ? () => api.listStores() : null);
+  const categoriesQuery = useApiQuery(api ? () => api.listSkuCategories() : null, { invalidateOn: ['sku'] });
+  const storesQuery = useApiQuery(api ? () => api.listStores() : null, { invalidateOn: ['store'] });
 
   const [form, setForm] = useState({
     name: '',
@@ -4,6 +4,7 @@ import { useCallback, useEffect, useMemo, useState } from 'react';
 import Link from 'next/link';
 import { Trash2Icon, DownloadIcon } from 'lucide-react';
 import { useAuth } from '@/context/auth-context';
+import { hasPermission } from '@/lib/permissions';
 import { useInventoryUpdates } from '@/context/inventory-updates-context';
 import { useApiQuery } from '@/hooks/useApiQuery';
 import { ConfirmDialog } from '@/components/ConfirmDialog';
@@ -59,36 +60,32 @@ export default function ItemsPage() {
   }>({ isOpen: false, message: '', variant: 'info' });
 
   const skuFetcher = useCallback(() => (api ? api.listSkus(skuFilters) : Promise.resolve(null)), [api, skuFilters]);
-  const skuQuery = useApiQuery(api ? skuFetcher : null);
+  const skuQuery = useApiQuery(api ? skuFetcher : null, { invalidateOn: ['sku'] });
 
   const categoriesQuery = useApiQuery(
     api ? () => api.listSkuCategories() : null,
     {
       enabled: Boolean(api),
+      invalidateOn: ['sku'],
     },
   );
 
   const storesQuery = useApiQuery(
-    api && user?.role === 'manager' ? () => api.listStores() : null,
+    api && hasPermission(user, 'stores.manage') ? () => api.listStores() : null,
     {
-      enabled: Boolean(api && user?.role === 'manager'),
+      enabled: Boolean(api && hasPermission(user, 'stores.manage')),
+      invalidateOn: ['store'],
     },
   );
 
-  // For staff: fetch unfiltered inventory to get all accessible stores
-  const allInventoryFetcher = useCallback(
-    () =>
-      api && user?.role === 'staff'
-        ? api.listInventory({
-            page: 1,
-            page_size: 100, // Get enough to capture all unique stores
-            sort_by: 'updated_at',
-            order: 'desc',
-          })
-        : Promise.resolve(null),
-    [api, user?.role],
+  // Without stores.manage, offer the stores the user is assigned to
+  const myStoresQuery = useApiQuery(
+    api && !hasPermission(user, 'stores.manage') ? () => api.listMyStores() : null,
+    {
+      enabled: Boolean(api && !hasPermission(user, 'stores.manage')),
+      invalidateOn: ['store', 'store_user'],
+    },
   );
-  const allInventoryQuery = useApiQuery(api && user?.role === 'staff' ? allInventoryFetcher : null);
 
   const inventoryFetcher = useCallback(
     () =>
@@ -101,7 +98,8 @@ export default function ItemsPage() {
         : Promise.resolve(null),
     [api, inventoryFilters],
   );
-  const inventoryQuery = useApiQuery(api ? inventoryFetcher : null);
+  // Inventory records carry SKU and store names
+  const inventoryQuery = useApiQuery(api ? inventoryFetcher : null, { invalidateOn: ['sku', 'store'] });
 
   // Reload SKU query when filters change
   useEffect(() => {
@@ -341,22 +339,11 @@ export default function ItemsPage() {
   };
 
   const stores: Store[] = useMemo(() => {
-    if (user?.role === 'manager') {
+    if (hasPermission(user, 'stores.manage')) {
       return (storesQuery.data as StoreListResponse | null)?.items ?? [];
-    } else {
-      // For staff, extract unique stores from ALL inventory (not filtered)
-      const inventoryItems = allInventoryQuery.data?.items ?? [];
-      const uniqueStores = new Map<string, Store>();
-      
-      inventoryItems.forEach((item) => {
-        if (item.store) {
-          uniqueStores.set(item.store.id, item.store);
-        }
-      });
-      
-      return Array.from(uniqueStores.values());
     }
-  }, [user?.role, storesQuery.data, allInventoryQuery.data]);
+    return myStoresQuery.data?.items ?? [];
+  }, [user, storesQuery.data, myStoresQuery.data]);
 
   return (
     <div className="space-y-8">
@@ -410,7 +397,7 @@ export default function ItemsPage() {
               <DownloadIcon className="h-4 w-4" />
               Export CSV
             </button>
-            {user?.role === 'manager' && (
+            {hasPermission(user, 'sku.manage') && (
               <Link href="/dashboard/items/new" className="btn-secondary">
                 + New SKU
               </Link>
@@ -445,7 +432,7 @@ export default function ItemsPage() {
                       <Link href={`/dashboard/items/${sku.id}`} className="btn-secondary px-3 py-1">
                         View
                       </Link>
-                      {user?.role === 'manager' && (
+                      {hasPermission(user, 'sku.manage') && (
                         <>
                           <Link href={`/dashboard/items/${sku.id}/edit`} className="btn-secondary px-3 py-1">
                             Edit
@@ -505,7 +492,7 @@ export default function ItemsPage() {
                 <Link href={`/dashboard/items/${sku.id}`} className="btn-secondary px-3 py-1 text-sm flex-1 text-center">
                   View
                 </Link>
-                {user?.role === 'manager' && (
+                {hasPermission(user, 'sku.manage') && (
                   <>
                     <Link href={`/dashboard/items/${sku.id}/edit`} className="btn-secondary px-3 py-1 text-sm flex-1 text-center">
                       Edit
@@ -575,7 +562,7 @@ export default function ItemsPage() {
         </div>
       </section>
 
-      {user?.role === 'manager' && (
+      {hasPermission(user, 'inventory.manage') && (
         <section className="card">
           <header>
             <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Create</p>
@@ -852,7 +839,7 @@ export default function ItemsPage() {
                 </p>
               </div>
 
-              {user?.role === 'manager' && (
+              {hasPermission(user, 'inventory.update') && (
                 <>
                   <div className="rounded-2xl border border-white/5 bg-white/5 p-4">
                     <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Set quantity</p>
@@ -3,6 +3,7 @@
 import Link from 'next/link';
 import { ArrowUpRightIcon } from 'lucide-react';
 import { useAuth } from '@/context/auth-context';
+import { hasPermission } from '@/lib/permissions';
 import { useApiQuery } from '@/hooks/useApiQuery';
 import { SKU } from '@/lib/types';
 
@@ -19,6 +20,7 @@ export default function DashboardPage() {
             sort_by: 'created_at',
           })
       : null,
+    { invalidateOn: ['sku'] },
   );
 
   const inventoryQuery = useApiQuery(
@@ -31,17 +33,24 @@ export default function DashboardPage() {
             sort_by: 'updated_at',
           })
       : null,
+    { invalidateOn: ['inventory', 'sku', 'store'] },
   );
 
-  const userQuery = useApiQuery(api && user?.role === 'manager' ? () => api.listUsers({ page: 1, limit: 50 }) : null);
+  const userQuery = useApiQuery(api && hasPermission(user, 'users.manage') ? () => api.listUsers({ page: 1, limit: 50 }) : null, {
+    invalidateOn: ['user'],
+  });
+  const myStoresQuery = useApiQuery(api && !hasPermission(user, 'users.manage') ? () => api.listMyStores() : null, {
+    invalidateOn: ['store', 'store_user'],
+  });
 
   const totalSkus = skuQuery.data?.total ?? 0;
   const totalInventoryItems = inventoryQuery.data?.total ?? 0;
-  const totalUsers = user?.role === 'manager' ? userQuery.data?.total ?? 0 : undefined;
+  const totalUsers = hasPermission(user, 'users.manage') ? userQuery.data?.total ?? 0 : undefined;
   const uniqueStores = new Set((inventoryQuery.data?.items ?? []).map((item) => item.store_id)).size;
 
   const lowStockItems = (inventoryQuery.data?.items ?? []).filter((item) => item.quantity < 25);
 
+  const myStores = myStoresQuery.data?.items ?? [];
   const latestSkus = skuQuery.data?.items ?? [];
   const recentInventory = inventoryQuery.data?.items ?? [];
 
@@ -51,17 +60,46 @@ export default function DashboardPage() {
         <MetricCard title="Total SKUs" value={totalSkus} description="Managed across all stores" />
         <MetricCard title="Inventory Records" value={totalInventoryItems} description="Active store + SKU pairs" />
         <MetricCard title="Active Stores" value={uniqueStores} description="Stores with tracked inventory" />
-        {user?.role === 'manager' ? (
+        {hasPermission(user, 'users.manage') ? (
           <MetricCard title="Active Users" value={totalUsers ?? 0} description="Manager + staff accounts" />
         ) : (
           <MetricCard
             title="Assigned Stores"
-            value={uniqueStores || '—'}
+            value={myStores.length || '—'}
             description="You can access these locations"
           />
         )}
       </section>
 
+      {myStores.length > 0 && (
+        <section className="card">
+          <header className="mb-4">
+            <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Your stores</p>
+            <h2 className="text-xl font-semibold text-white">Assigned locations</h2>
+          </header>
+          <div className="grid gap-3 md:grid-cols-2 xl:grid-cols-3">
+            {myStores.map((store) => (
+              <article key={store.id} className="rounded-2xl border border-white/5 bg-white/5 p-4">
+                <p className="font-semibold text-white">{store.name}</p>
+                <p className="text-xs text-slate-400">
+                  {store.address}
+                  {store.store_role && ` · ${store.store_role}`}
+                  {store.valid_to && ` · until ${new Date(store.valid_to).toLocaleDateString()}`}
+                </p>
+                {store.stock && (
+                  <p className="mt-2 text-sm text-slate-300">
+                    {store.stock.sku_count.toLocaleString()} SKUs · {store.stock.total_quantity.toLocaleString()} units
+                    {store.stock.out_of_stock_count > 0 && (
+                      <span className="text-amber-300"> · {store.stock.out_of_stock_count} out of stock</span>
+                    )}
+                  </p>
+                )}
+              </article>
+            ))}
+          </div>
+        </section>
+      )}
+
       <section className="grid gap-6 xl:grid-cols-3">
         <div className="card xl:col-span-2">
           <header className="mb-4 flex items-center justify-between">
@@ -174,7 +212,7 @@ export default function DashboardPage() {
               title="Browse inventory"
               description="Filter across SKUs, stores, and stock levels."
             />
-            {user?.role === 'manager' && (
+            {hasPermission(user, 'users.manage') && (
               <>
                 <ActionLink
                   href="/dashboard/users"
@@ -4,15 +4,28 @@ import { FormEvent, useState } from 'react';
 import { useAuth } from '@/context/auth-context';
 import { useServer } from '@/context/server-context';
 import { useInventoryUpdates } from '@/context/inventory-updates-context';
+import { useApiQuery } from '@/hooks/useApiQuery';
+import { oidcConfigRequest } from '@/lib/api-client';
+import { MfaEnrollment } from '@/lib/types';
 
 export default function ProfilePage() {
-  const { user, api, refreshProfile } = useAuth();
+  const { user, api, refreshProfile, startOidcLink } = useAuth();
   const { selectedServer } = useServer();
   const { connected } = useInventoryUpdates();
   const [form, setForm] = useState({ old_password: '', new_password: '' });
   const [message, setMessage] = useState<string | null>(null);
   const [error, setError] = useState<string | null>(null);
   const [submitting, setSubmitting] = useState(false);
+  const mfaQuery = useApiQuery(api ? () => api.getMfaStatus() : null);
+  const [enrollment, setEnrollment] = useState<MfaEnrollment | null>(null);
+  const [mfaCode, setMfaCode] = useState('');
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
+  const [mfaMessage, setMfaMessage] = useState<string | null>(null);
+  const [mfaError, setMfaError] = useState<string | null>(null);
+  const [mfaSubmitting, setMfaSubmitting] = useState(false);
+  const oidcQuery = useApiQuery(() => oidcConfigRequest(selectedServer.url));
+  const [linkError, setLinkError] = useState<string | null>(null);
+  const [linking, setLinking] = useState(false);
 
   const handleSubmit = async (event: FormEvent) => {
     event.preventDefault();
@@ -51,6 +64,67 @@ export default function ProfilePage() {
     }
   };
 
+  // Runs a two-factor action, reporting its outcome in the two-factor section
+  const runMfaAction = async (action: () => Promise<void>, fallback: string) => {
+    setMfaError(null);
+    setMfaMessage(null);
+    setMfaSubmitting(true);
+    try {
+      await action();
+      mfaQuery.reload();
+    } catch (err) {
+      setMfaError(err instanceof Error ? err.message : fallback);
+    } finally {
+      setMfaCode('');
+      setMfaSubmitting(false);
+    }
+  };
+
+  const handleStartEnrollment = () =>
+    runMfaAction(async () => {
+      if (!api) return;
+      setRecoveryCodes(null);
+      setEnrollment(await api.enrollMfa());
+    }, 'Failed to start setup');
+
+  const handleConfirmEnrollment = (event: FormEvent) => {
+    event.preventDefault();
+    return runMfaAction(async () => {
+      if (!api) return;
+      const response = await api.confirmMfa(mfaCode.trim());
+      setEnrollment(null);
+      setRecoveryCodes(response.recovery_codes);
+      setMfaMessage('Two-factor authentication enabled.');
+    }, 'Failed to enable two-factor authentication');
+  };
+
+  const handleRegenerateCodes = () =>
+    runMfaAction(async () => {
+      if (!api) return;
+      const response = await api.regenerateRecoveryCodes(mfaCode.trim());
+      setRecoveryCodes(response.recovery_codes);
+      setMfaMessage('New recovery codes generated; the old ones no longer work.');
+    }, 'Failed to generate recovery codes');
+
+  const handleDisable = () =>
+    runMfaAction(async () => {
+      if (!api) return;
+      const response = await api.disableMfa(mfaCode.trim());
+      setRecoveryCodes(null);
+      setMfaMessage(response.message);
+    }, 'Failed to disable two-factor authentication');
+
+  const handleLinkSso = async () => {
+    setLinkError(null);
+    setLinking(true);
+    try {
+      await startOidcLink();
+    } catch (err) {
+      setLinkError(err instanceof Error ? err.message : 'Failed to link single sign-on');
+      setLinking(false);
+    }
+  };
+
   if (!user) {
     return (
       <div className="card">
@@ -130,6 +204,110 @@ export default function ProfilePage() {
           {submitting ? 'Updating…' : 'Update password'}
         </button>
       </form>
+
+      <section className="card space-y-4">
+        <div>
+          <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Security</p>
+          <h2 className="text-xl font-semibold text-white">Two-factor authentication</h2>
+          <p className="text-sm text-slate-400">
+            {mfaQuery.data?.enabled
+              ? `Enabled · ${mfaQuery.data.recovery_codes_remaining} recovery codes left`
+              : 'Not enabled'}
+            {mfaQuery.data?.required && ' · required for your role'}
+          </p>
+        </div>
+
+        {recoveryCodes && (
+          <div className="space-y-2">
+            <p className="text-sm text-slate-300">Save these recovery codes; they will not be shown again.</p>
+            <ul className="grid grid-cols-2 gap-2 rounded-xl border border-white/10 bg-slate-950/60 p-4 font-mono text-sm text-slate-100">
+              {recoveryCodes.map((recoveryCode) => (
+                <li key={recoveryCode}>{recoveryCode}</li>
+              ))}
+            </ul>
+          </div>
+        )}
+
+        {enrollment ? (
+          <form className="space-y-4" onSubmit={handleConfirmEnrollment}>
+            <div className="space-y-2 rounded-xl border border-white/10 bg-slate-950/60 p-4 text-sm">
+              <p className="text-slate-400">Add this secret key to your authenticator app</p>
+              <p className="break-all font-mono text-slate-100">{enrollment.secret}</p>
+              <a href={enrollment.provisioning_uri} className="text-cyan-300 hover:text-cyan-200">
+                Open in authenticator app
+              </a>
+            </div>
+            <div className="space-y-2">
+              <label className="label">Code from the app</label>
+              <input
+                className="input font-mono"
+                value={mfaCode}
+                onChange={(e) => setMfaCode(e.target.value)}
+                autoComplete="one-time-code"
+                required
+              />
+            </div>
+            <button type="submit" className="btn-primary" disabled={mfaSubmitting}>
+              {mfaSubmitting ? 'Verifying…' : 'Enable'}
+            </button>
+          </form>
+        ) : mfaQuery.data?.enabled ? (
+          <div className="space-y-4">
+            <div className="space-y-2">
+              <label className="label">Authenticator or recovery code</label>
+              <input
+                className="input font-mono"
+                value={mfaCode}
+                onChange={(e) => setMfaCode(e.target.value)}
+                autoComplete="one-time-code"
+              />
+            </div>
+            <div className="flex gap-2">
+              <button className="btn-secondary" disabled={mfaSubmitting || !mfaCode.trim()} onClick={handleRegenerateCodes}>
+                New recovery codes
+              </button>
+              {!mfaQuery.data.required && (
+                <button
+                  className="rounded-xl border border-rose-500/40 px-4 py-2 text-sm text-rose-100"
+                  disabled={mfaSubmitting || !mfaCode.trim()}
+                  onClick={handleDisable}
+                >
+                  Disable
+                </button>
+              )}
+            </div>
+          </div>
+        ) : (
+          <button className="btn-primary" disabled={mfaSubmitting || !mfaQuery.data} onClick={handleStartEnrollment}>
+            Set up authenticator
+          </button>
+        )}
+
+        {mfaMessage && (
+          <div className="rounded-2xl border border-emerald-400/40 bg-emerald-500/10 p-3 text-sm text-emerald-100">{mfaMessage}</div>
+        )}
+        {mfaError && (
+          <div className="rounded-2xl border border-rose-400/40 bg-rose-500/10 p-3 text-sm text-rose-100">{mfaError}</div>
+        )}
+      </section>
+
+      {oidcQuery.data?.enabled && (
+        <section className="card space-y-4">
+          <div>
+            <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Security</p>
+            <h2 className="text-xl font-semibold text-white">Single sign-on</h2>
+            <p className="text-sm text-slate-400">
+              Link your {oidcQuery.data.name ?? 'identity provider'} account to also sign in with it.
+            </p>
+          </div>
+          {linkError && (
+            <div className="rounded-2xl border border-rose-400/40 bg-rose-500/10 p-3 text-sm text-rose-100">{linkError}</div>
+          )}
+          <button className="btn-secondary" disabled={linking} onClick={handleLinkSso}>
+            {linking ? 'Redirecting…' : 'Link account'}
+          </button>
+        </section>
+      )}
     </div>
   );
 }
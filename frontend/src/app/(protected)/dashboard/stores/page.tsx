@@ -1,21 +1,55 @@
 'use client';
 
-import { useEffect, useMemo, useState } from 'react';
+import { useCallback, useEffect, useMemo, useState } from 'react';
 import { useAuth } from '@/context/auth-context';
+import { hasPermission } from '@/lib/permissions';
 import { useApiQuery } from '@/hooks/useApiQuery';
-import { Store, User } from '@/lib/types';
+import { useEntityInvalidation } from '@/context/inventory-updates-context';
+import { Store, StoreAssignmentFields, StoreStaffMember } from '@/lib/types';
 import { ConfirmDialog } from '@/components/ConfirmDialog';
 
+type AssignmentForm = { role: string; validFrom: string; validTo: string };
+
+const emptyAssignment: AssignmentForm = { role: '', validFrom: '', validTo: '' };
+
+// Dates are entered as local calendar days; an assignment ends at the start of valid_to
+function toAssignmentFields(form: AssignmentForm): StoreAssignmentFields {
+  const toISO = (day: string) => (day ? new Date(`${day}T00:00:00`).toISOString() : null);
+  return { role: form.role || null, valid_from: toISO(form.validFrom), valid_to: toISO(form.validTo) };
+}
+
+function toDay(value: string | null): string {
+  if (!value) return '';
+  const date = new Date(value);
+  const pad = (n: number) => String(n).padStart(2, '0');
+  return `${date.getFullYear()}-${pad(date.getMonth() + 1)}-${pad(date.getDate())}`;
+}
+
+function describePeriod(staff: StoreStaffMember): string {
+  if (!staff.valid_from && !staff.valid_to) return 'Permanent';
+  const from = staff.valid_from ? new Date(staff.valid_from).toLocaleDateString() : 'now';
+  const to = staff.valid_to ? new Date(staff.valid_to).toLocaleDateString() : 'removed';
+  return `${from} – ${to}`;
+}
+
 export default function StoresPage() {
   const { api, user } = useAuth();
-  const storesQuery = useApiQuery(api && user?.role === 'manager' ? () => api.listStores() : null);
-  const usersQuery = useApiQuery(api && user?.role === 'manager' ? () => api.listUsers({ limit: 100 }) : null);
+  const storesQuery = useApiQuery(api && hasPermission(user, 'stores.manage') ? () => api.listStores() : null, {
+    invalidateOn: ['store'],
+  });
+  const usersQuery = useApiQuery(api && hasPermission(user, 'users.manage') ? () => api.listUsers({ limit: 100 }) : null, {
+    invalidateOn: ['user'],
+  });
+  const rolesQuery = useApiQuery(api && hasPermission(user, 'stores.manage') ? () => api.listRoles() : null, {
+    invalidateOn: ['role'],
+  });
 
   const [selectedStoreId, setSelectedStoreId] = useState<string | null>(null);
-  const [storeStaff, setStoreStaff] = useState<User[]>([]);
-  const [staffAssociations, setStaffAssociations] = useState<Record<string, string>>({});
+  const [storeStaff, setStoreStaff] = useState<StoreStaffMember[]>([]);
   const [newStore, setNewStore] = useState({ name: '', address: '' });
   const [staffUserId, setStaffUserId] = useState('');
+  const [assignment, setAssignment] = useState<AssignmentForm>(emptyAssignment);
+  const [editingId, setEditingId] = useState<string | null>(null);
   const [message, setMessage] = useState<string | null>(null);
   const [error, setError] = useState<string | null>(null);
   const [confirmDialog, setConfirmDialog] = useState<{
@@ -33,20 +67,27 @@ export default function StoresPage() {
     return stores[0] ?? null;
   }, [selectedStoreId, stores]);
 
-  useEffect(() => {
-    async function fetchStaff() {
-      if (!api || !selectedStore) return;
-      try {
-        const response = await api.listStoreStaff(selectedStore.id);
-        setStoreStaff(response.staff);
-        // Unknown association IDs for historical rows; keep existing ones if we have them.
-      } catch (err) {
-        setError(err instanceof Error ? err.message : 'Failed to load staff.');
-      }
+  const roles = rolesQuery.data?.items ?? [];
+
+  const fetchStaff = useCallback(async () => {
+    if (!api || !selectedStore) return;
+    try {
+      const response = await api.listStoreStaff(selectedStore.id);
+      setStoreStaff(response.staff);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to load staff.');
     }
-    fetchStaff();
   }, [api, selectedStore]);
 
+  useEffect(() => {
+    fetchStaff();
+    setEditingId(null);
+    setAssignment(emptyAssignment);
+  }, [fetchStaff]);
+
+  // Staff lists embed user details and change with assignments made elsewhere
+  useEntityInvalidation(['store_user', 'user'], fetchStaff);
+
   const handleCreateStore = async () => {
     if (!api) return;
     setError(null);
@@ -113,28 +154,48 @@ export default function StoresPage() {
     setError(null);
     setMessage(null);
     try {
-      const association = await api.addStaffToStore({ store_id: selectedStore.id, user_id: staffUserId });
-      setStoreStaff((prev) => [...prev, association.user]);
-      setStaffAssociations((prev) => ({ ...prev, [association.user_id]: association.id }));
+      await api.addStaffToStore({ store_id: selectedStore.id, user_id: staffUserId, ...toAssignmentFields(assignment) });
       setStaffUserId('');
+      setAssignment(emptyAssignment);
+      await fetchStaff();
       setMessage('Staff member added to store.');
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Failed to add staff');
     }
   };
 
-  const handleRemoveStaff = async (userId: string) => {
-    if (!api) return;
-    const associationId = staffAssociations[userId];
-    if (!associationId) {
-      setError('Association ID unknown. Try removing and re-adding to manage this user.');
-      return;
+  const handleEditStaff = (staff: StoreStaffMember) => {
+    setEditingId(staff.assignment_id);
+    setAssignment({ role: staff.store_role ?? '', validFrom: toDay(staff.valid_from), validTo: toDay(staff.valid_to) });
+  };
+
+  const handleCancelEdit = () => {
+    setEditingId(null);
+    setAssignment(emptyAssignment);
+  };
+
+  const handleUpdateStaff = async () => {
+    if (!api || !editingId) return;
+    setError(null);
+    setMessage(null);
+    try {
+      await api.updateStoreStaff(editingId, toAssignmentFields(assignment));
+      handleCancelEdit();
+      await fetchStaff();
+      setMessage('Staff assignment updated.');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to update staff assignment');
     }
+  };
+
+  const handleRemoveStaff = async (staff: StoreStaffMember) => {
+    if (!api) return;
     setError(null);
     setMessage(null);
     try {
-      await api.deleteStaffFromStore(associationId);
-      setStoreStaff((prev) => prev.filter((staff) => staff.id !== userId));
+      await api.deleteStaffFromStore(staff.assignment_id);
+      setStoreStaff((prev) => prev.filter((member) => member.assignment_id !== staff.assignment_id));
+      if (editingId === staff.assignment_id) handleCancelEdit();
       setMessage('Staff removed from store.');
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Failed to remove staff');
@@ -143,14 +204,17 @@ export default function StoresPage() {
 
   const availableUsers = useMemo(
     () =>
-      (usersQuery.data?.users ?? []).filter((candidate) => !storeStaff.some((staff) => staff.id === candidate.id)),
+      (usersQuery.data?.users ?? []).filter(
+        (candidate) =>
+          candidate.status !== 'deactivated' && !storeStaff.some((staff) => staff.id === candidate.id),
+      ),
     [storeStaff, usersQuery.data],
   );
 
-  if (user?.role !== 'manager') {
+  if (!hasPermission(user, 'stores.manage')) {
     return (
       <div className="card">
-        <p className="text-sm text-slate-400">Store administration is limited to managers.</p>
+        <p className="text-sm text-slate-400">Store administration requires the stores.manage permission.</p>
       </div>
     );
   }
@@ -238,46 +302,97 @@ export default function StoresPage() {
                 Assign staff to <span className="text-white">{selectedStore.name}</span>
               </p>
               <div className="flex flex-wrap gap-2">
+                {editingId ? (
+                  <p className="input w-64 text-slate-300">
+                    Editing {storeStaff.find((staff) => staff.assignment_id === editingId)?.username}
+                  </p>
+                ) : (
+                  <select
+                    className="input w-64"
+                    value={staffUserId}
+                    onChange={(e) => setStaffUserId(e.target.value)}
+                  >
+                    <option value="">Select user</option>
+                    {availableUsers.map((candidate) => (
+                      <option key={candidate.id} value={candidate.id}>
+                        {candidate.username} · {candidate.role}
+                      </option>
+                    ))}
+                  </select>
+                )}
                 <select
-                  className="input w-64"
-                  value={staffUserId}
-                  onChange={(e) => setStaffUserId(e.target.value)}
+                  className="input w-48"
+                  value={assignment.role}
+                  onChange={(e) => setAssignment((prev) => ({ ...prev, role: e.target.value }))}
+                  aria-label="Store role"
                 >
-                  <option value="">Select user</option>
-                  {availableUsers.map((candidate) => (
-                    <option key={candidate.id} value={candidate.id}>
-                      {candidate.username} · {candidate.role}
+                  <option value="">No store role</option>
+                  {roles.map((role) => (
+                    <option key={role.name} value={role.name}>
+                      {role.name}
                     </option>
                   ))}
                 </select>
-                <button className="btn-primary" onClick={handleAddStaff}>
-                  Add staff
-                </button>
+                <input
+                  type="date"
+                  className="input"
+                  value={assignment.validFrom}
+                  onChange={(e) => setAssignment((prev) => ({ ...prev, validFrom: e.target.value }))}
+                  aria-label="Valid from"
+                />
+                <input
+                  type="date"
+                  className="input"
+                  value={assignment.validTo}
+                  onChange={(e) => setAssignment((prev) => ({ ...prev, validTo: e.target.value }))}
+                  aria-label="Valid until"
+                />
+                {editingId ? (
+                  <>
+                    <button className="btn-primary" onClick={handleUpdateStaff}>
+                      Save assignment
+                    </button>
+                    <button className="btn-secondary" onClick={handleCancelEdit}>
+                      Cancel
+                    </button>
+                  </>
+                ) : (
+                  <button className="btn-primary" onClick={handleAddStaff}>
+                    Add staff
+                  </button>
+                )}
               </div>
+              <p className="text-xs text-slate-500">
+                Leave the dates empty for permanent access; access ends at the start of the end date. A store role adds its
+                permissions in this store only.
+              </p>
               <div className="space-y-2">
                 {storeStaff.map((staff) => (
-                  <div key={staff.id} className="flex items-center justify-between rounded-2xl border border-white/10 px-4 py-2">
+                  <div
+                    key={staff.assignment_id}
+                    className="flex items-center justify-between rounded-2xl border border-white/10 px-4 py-2"
+                  >
                     <div>
-                      <p className="text-white">{staff.username}</p>
-                      <p className="text-xs text-slate-400">{staff.email}</p>
+                      <p className="text-white">
+                        {staff.username}
+                        {staff.store_role && <span className="ml-2 text-xs text-cyan-200">{staff.store_role}</span>}
+                        {!staff.active && <span className="ml-2 text-xs text-amber-200">inactive</span>}
+                      </p>
+                      <p className="text-xs text-slate-400">
+                        {staff.email} · {describePeriod(staff)}
+                      </p>
+                    </div>
+                    <div className="flex gap-3">
+                      <button className="text-xs text-cyan-200 hover:text-cyan-100" onClick={() => handleEditStaff(staff)}>
+                        Edit
+                      </button>
+                      <button className="text-xs text-rose-200 hover:text-rose-100" onClick={() => handleRemoveStaff(staff)}>
+                        Remove
+                      </button>
                     </div>
-                    <button
-                      className={`text-xs ${
-                        staffAssociations[staff.id]
-                          ? 'text-rose-200 hover:text-rose-100'
-                          : 'text-slate-500 cursor-not-allowed'
-                      }`}
-                      onClick={() => handleRemoveStaff(staff.id)}
-                      disabled={!staffAssociations[staff.id]}
-                    >
-                      Remove
-                    </button>
                   </div>
                 ))}
                 {storeStaff.length === 0 && <p className="text-sm text-slate-400">No staff assigned.</p>}
-                <p className="text-xs text-slate-500">
-                  Remove is enabled for associations created via this UI (ID sourced from POST response).
-                </p>
               </div>
             </>
           ) : (
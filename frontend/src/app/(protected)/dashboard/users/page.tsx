@@ -1,15 +1,21 @@
 'use client';
 
-import { FormEvent, useState } from 'react';
+import { FormEvent, useEffect, useState } from 'react';
+import { useRouter } from 'next/navigation';
 import { useAuth } from '@/context/auth-context';
+import { hasPermission } from '@/lib/permissions';
 import { useApiQuery } from '@/hooks/useApiQuery';
-import { User, UserRole } from '@/lib/types';
+import { User, UserRole, UserStatus } from '@/lib/types';
 import { ConfirmDialog } from '@/components/ConfirmDialog';
 
 export default function UsersPage() {
-  const { api, user } = useAuth();
+  const { api, user, impersonate } = useAuth();
+  const router = useRouter();
   const [page, setPage] = useState(1);
   const [limit, setLimit] = useState(20);
+  const [status, setStatus] = useState<UserStatus | ''>('');
+  const [deactivating, setDeactivating] = useState<User | null>(null);
+  const [deactivateReason, setDeactivateReason] = useState('');
   const [createForm, setCreateForm] = useState({
     username: '',
     email: '',
@@ -24,17 +30,33 @@ export default function UsersPage() {
     isOpen: boolean;
     title: string;
     message: string;
+    confirmText: string;
     onConfirm: () => void;
-  }>({ isOpen: false, title: '', message: '', onConfirm: () => {} });
+  }>({ isOpen: false, title: '', message: '', confirmText: '', onConfirm: () => {} });
 
+  const canManage = hasPermission(user, 'users.manage');
+  const canImpersonate = hasPermission(user, 'users.impersonate');
   const usersQuery = useApiQuery(
-    api && user?.role === 'manager' ? () => api.listUsers({ page, limit }) : null,
+    api && canManage ? () => api.listUsers({ page, limit, status: status || undefined }) : null,
+    { skipInitial: true, invalidateOn: ['user'] },
   );
+  const { reload: reloadUsers } = usersQuery;
 
-  if (user?.role !== 'manager') {
+  // The query hook only fetches once, so refetch whenever the filter or page change
+  useEffect(() => {
+    if (api && canManage) {
+      reloadUsers();
+    }
+  }, [api, canManage, page, limit, status, reloadUsers]);
+  const rolesQuery = useApiQuery(api && hasPermission(user, 'users.manage') ? () => api.listRoles() : null, {
+    invalidateOn: ['role'],
+  });
+  const roleNames = rolesQuery.data?.items.map((role) => role.name) ?? ['staff', 'manager'];
+
+  if (!canManage) {
     return (
       <div className="card">
-        <p className="text-sm text-slate-400">Only managers can manage users.</p>
+        <p className="text-sm text-slate-400">User management requires the users.manage permission.</p>
       </div>
     );
   }
@@ -138,21 +160,88 @@ export default function UsersPage() {
     }
   };
 
-  const handleDelete = (selected: User) => {
+  const handleDeactivate = async (event: FormEvent) => {
+    event.preventDefault();
+    if (!api || !deactivating) return;
+    setError(null);
+    setMessage(null);
+    try {
+      await api.deactivateUser(deactivating.id, deactivateReason.trim() || undefined);
+      setMessage(`${deactivating.username} deactivated.`);
+      setDeactivating(null);
+      usersQuery.reload();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to deactivate user');
+    }
+  };
+
+  const handleReactivate = async (selected: User) => {
+    if (!api) return;
+    setError(null);
+    setMessage(null);
+    try {
+      await api.reactivateUser(selected.id);
+      setMessage(`${selected.username} can sign in again.`);
+      usersQuery.reload();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to reactivate user');
+    }
+  };
+
+  const handleImpersonate = (selected: User) => {
+    setConfirmDialog({
+      isOpen: true,
+      title: 'View as User',
+      message: `See the application as "${selected.username}" does, with their role and store assignments? The session is read-only, expires after a few minutes, and every request is recorded in the audit log.`,
+      confirmText: 'View as user',
+      onConfirm: async () => {
+        setError(null);
+        setMessage(null);
+        try {
+          await impersonate(selected.id);
+          router.push('/dashboard');
+        } catch (err) {
+          setError(err instanceof Error ? err.message : 'Failed to view as user');
+        }
+      },
+    });
+  };
+
+  const handleForceReset = (selected: User) => {
+    if (!api) return;
+    setConfirmDialog({
+      isOpen: true,
+      title: 'Force Password Reset',
+      message: `Sign "${selected.username}" out of all sessions and email them a reset link? They cannot sign in until they choose a new password.`,
+      confirmText: 'Force reset',
+      onConfirm: async () => {
+        setError(null);
+        setMessage(null);
+        try {
+          const response = await api.forcePasswordReset(selected.id);
+          setMessage(response.message);
+        } catch (err) {
+          setError(err instanceof Error ? err.message : 'Failed to force password reset');
+        }
+      },
+    });
+  };
+
+  const handleResetMfa = (selected: User) => {
     if (!api) return;
     setConfirmDialog({
       isOpen: true,
-      title: 'Delete User',
-      message: `Delete user "${selected.username}"? This action cannot be undone.`,
+      title: 'Reset Two-Factor Authentication',
+      message: `Remove the authenticator and recovery codes of "${selected.username}"? Use this when they lost their device.`,
+      confirmText: 'Reset 2FA',
       onConfirm: async () => {
         setError(null);
         setMessage(null);
         try {
-          await api.deleteUser(selected.id);
-          setMessage('User deleted.');
-          usersQuery.reload();
+          const response = await api.resetUserMfa(selected.id);
+          setMessage(response.message);
         } catch (err) {
-          setError(err instanceof Error ? err.message : 'Failed to delete user');
+          setError(err instanceof Error ? err.message : 'Failed to reset two-factor authentication');
         }
       },
     });
@@ -178,6 +267,18 @@ export default function UsersPage() {
             <h2 className="text-xl font-semibold text-white">Existing accounts</h2>
           </div>
           <div className="flex gap-2 text-sm">
+            <select
+              className="input"
+              value={status}
+              onChange={(e) => {
+                setStatus(e.target.value as UserStatus | '');
+                setPage(1);
+              }}
+            >
+              <option value="">All users</option>
+              <option value="active">Active</option>
+              <option value="deactivated">Deactivated</option>
+            </select>
             <select className="input" value={limit} onChange={(e) => setLimit(Number(e.target.value))}>
               {[20, 50, 100].map((value) => (
                 <option key={value} value={value}>
@@ -208,6 +309,7 @@ export default function UsersPage() {
                 <th className="px-4 py-3">Username</th>
                 <th className="px-4 py-3">Email</th>
                 <th className="px-4 py-3">Role</th>
+                <th className="px-4 py-3">Status</th>
                 <th className="px-4 py-3 text-right">Actions</th>
               </tr>
             </thead>
@@ -217,24 +319,106 @@ export default function UsersPage() {
                   <td className="px-4 py-3 text-white">{account.username}</td>
                   <td className="px-4 py-3 text-slate-300">{account.email}</td>
                   <td className="px-4 py-3 capitalize text-slate-200">{account.role}</td>
+                  <td className="px-4 py-3">
+                    {account.status === 'deactivated' ? (
+                      <span
+                        className="text-amber-200"
+                        title={account.deactivation_reason || undefined}
+                      >
+                        {account.anonymized ? 'Anonymized' : 'Deactivated'}
+                        {account.deactivated_at && (
+                          <span className="block text-xs text-slate-400">
+                            {new Date(account.deactivated_at).toLocaleDateString()}
+                          </span>
+                        )}
+                      </span>
+                    ) : (
+                      <span className="text-emerald-200">Active</span>
+                    )}
+                  </td>
                   <td className="px-4 py-3 text-right">
-                    <button className="btn-secondary mr-2 px-3 py-1 text-xs" onClick={() => startEdit(account)}>
-                      Edit
-                    </button>
-                    {account.username !== 'admin' && (
+                    {!account.anonymized && (
+                      <button className="btn-secondary mr-2 px-3 py-1 text-xs" onClick={() => startEdit(account)}>
+                        Edit
+                      </button>
+                    )}
+                    {canImpersonate && account.id !== user?.id && account.status !== 'deactivated' && (
                       <button
-                        className="rounded-xl border border-rose-500/40 px-3 py-1 text-xs text-rose-100"
-                        onClick={() => handleDelete(account)}
+                        className="btn-secondary mr-2 px-3 py-1 text-xs"
+                        onClick={() => handleImpersonate(account)}
                       >
-                        Delete
+                        View as
                       </button>
                     )}
+                    {account.id !== user?.id && account.status !== 'deactivated' && (
+                      <button
+                        className="btn-secondary mr-2 px-3 py-1 text-xs"
+                        onClick={() => handleForceReset(account)}
+                      >
+                        Reset password
+                      </button>
+                    )}
+                    {account.id !== user?.id && account.status !== 'deactivated' && (
+                      <button
+                        className="btn-secondary mr-2 px-3 py-1 text-xs"
+                        onClick={() => handleResetMfa(account)}
+                      >
+                        Reset 2FA
+                      </button>
+                    )}
+                    {account.status === 'deactivated'
+                      ? !account.anonymized && (
+                          <button
+                            className="btn-secondary px-3 py-1 text-xs"
+                            onClick={() => handleReactivate(account)}
+                          >
+                            Reactivate
+                          </button>
+                        )
+                      : account.username !== 'admin' &&
+                        account.id !== user?.id && (
+                          <button
+                            className="rounded-xl border border-rose-500/40 px-3 py-1 text-xs text-rose-100"
+                            onClick={() => {
+                              setDeactivating(account);
+                              setDeactivateReason('');
+                            }}
+                          >
+                            Deactivate
+                          </button>
+                        )}
                   </td>
                 </tr>
               ))}
             </tbody>
           </table>
         </div>
+        {deactivating && (
+          <form
+            className="space-y-3 rounded-2xl border border-rose-500/40 bg-rose-500/10 p-4"
+            onSubmit={handleDeactivate}
+          >
+            <p className="text-sm text-rose-100">
+              Deactivate <span className="font-semibold">{deactivating.username}</span>? They are signed out everywhere
+              and cannot sign in until reactivated. Their personal data is erased some time after deactivation.
+            </p>
+            <input
+              className="input w-full"
+              placeholder="Reason (optional)"
+              maxLength={255}
+              value={deactivateReason}
+              onChange={(e) => setDeactivateReason(e.target.value)}
+            />
+            <div className="flex gap-2">
+              <button type="submit" className="btn-primary">
+                Deactivate
+              </button>
+              <button type="button" className="btn-secondary" onClick={() => setDeactivating(null)}>
+                Cancel
+              </button>
+            </div>
+          </form>
+        )}
       </section>
 
       <section className="grid gap-4 md:grid-cols-2">
@@ -268,8 +452,11 @@ export default function UsersPage() {
             value={createForm.role}
             onChange={(e) => setCreateForm((prev) => ({ ...prev, role: e.target.value as UserRole }))}
           >
-            <option value="staff">Staff</option>
-            <option value="manager">Manager</option>
+            {roleNames.map((name) => (
+              <option key={name} value={name}>
+                {name}
+              </option>
+            ))}
           </select>
           <button type="submit" className="btn-primary w-full">
             Create
@@ -299,8 +486,11 @@ export default function UsersPage() {
                 value={editForm.role}
                 onChange={(e) => setEditForm((prev) => ({ ...prev, role: e.target.value as UserRole }))}
               >
-                <option value="staff">Staff</option>
-                <option value="manager">Manager</option>
+                {roleNames.map((name) => (
+                  <option key={name} value={name}>
+                    {name}
+                  </option>
+                ))}
               </select>
               <div className="flex gap-2">
                 <button type="submit" className="btn-primary flex-1">
@@ -323,7 +513,7 @@ export default function UsersPage() {
         onConfirm={confirmDialog.onConfirm}
         title={confirmDialog.title}
         message={confirmDialog.message}
-        confirmText="Delete"
+        confirmText={confirmDialog.confirmText}
         cancelText="Cancel"
         variant="danger"
       />
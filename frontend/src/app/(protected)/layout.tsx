@@ -5,19 +5,21 @@ import Link from 'next/link';
 import { usePathname, useRouter } from 'next/navigation';
 import { MenuIcon, XIcon } from 'lucide-react';
 import { useAuth } from '@/context/auth-context';
+import { hasPermission, Permission } from '@/lib/permissions';
 import { useInventoryUpdates } from '@/context/inventory-updates-context';
 
-const navigation = [
-  { label: 'Dashboard', href: '/dashboard', roles: ['manager', 'staff'] },
-  { label: 'Items', href: '/dashboard/items', roles: ['manager', 'staff'] },
-  { label: 'Alerts', href: '/dashboard/alerts', roles: ['manager', 'staff'] },
-  { label: 'Stores', href: '/dashboard/stores', roles: ['manager'] },
-  { label: 'Users', href: '/dashboard/users', roles: ['manager'] },
-  { label: 'Profile', href: '/dashboard/profile', roles: ['manager', 'staff'] },
+const navigation: { label: string; href: string; permission?: Permission }[] = [
+  { label: 'Dashboard', href: '/dashboard' },
+  { label: 'Items', href: '/dashboard/items' },
+  { label: 'Alerts', href: '/dashboard/alerts' },
+  { label: 'Stores', href: '/dashboard/stores', permission: 'stores.manage' },
+  { label: 'Users', href: '/dashboard/users', permission: 'users.manage' },
+  { label: 'Audit log', href: '/dashboard/audit', permission: 'audit.read' },
+  { label: 'Profile', href: '/dashboard/profile' },
 ];
 
 export default function ProtectedLayout({ children }: { children: React.ReactNode }) {
-  const { token, loading, user, logout } = useAuth();
+  const { token, loading, user, logout, stopImpersonating } = useAuth();
   const router = useRouter();
   const { connected, lastEvent, clearLastEvent } = useInventoryUpdates();
   const pathname = usePathname();
@@ -49,8 +51,6 @@ export default function ProtectedLayout({ children }: { children: React.ReactNod
     return null;
   }
 
-  const role = user.role;
-
   return (
     <div className="flex min-h-screen">
       {/* Desktop Sidebar */}
@@ -61,7 +61,7 @@ export default function ProtectedLayout({ children }: { children: React.ReactNod
         </div>
         <nav className="space-y-1">
           {navigation
-            .filter((item) => item.roles.includes(role))
+            .filter((item) => !item.permission || hasPermission(user, item.permission))
             .map((item) => {
               const active = item.href === '/dashboard' 
                 ? pathname === '/dashboard'
@@ -124,7 +124,7 @@ export default function ProtectedLayout({ children }: { children: React.ReactNod
         </div>
         <nav className="space-y-1">
           {navigation
-            .filter((item) => item.roles.includes(role))
+            .filter((item) => !item.permission || hasPermission(user, item.permission))
             .map((item) => {
               const active = item.href === '/dashboard' 
                 ? pathname === '/dashboard'
@@ -160,6 +160,26 @@ export default function ProtectedLayout({ children }: { children: React.ReactNod
       </aside>
 
       <div className="flex flex-1 flex-col">
+        {user.impersonation && (
+          <div className="border-b border-amber-500/30 bg-amber-500/10 px-4 py-2 text-sm text-amber-100 md:px-8">
+            <div className="flex items-center justify-between gap-4">
+              <p>
+                Viewing as <span className="font-semibold text-white">{user.username}</span>
+                {user.impersonation.read_only ? ' (read-only)' : ''}, signed in as{' '}
+                {user.impersonation.impersonator_username}. Every request is recorded in the audit log.
+              </p>
+              <button
+                onClick={() => {
+                  stopImpersonating();
+                  router.push('/dashboard/users');
+                }}
+                className="text-xs uppercase tracking-wide text-amber-100 hover:text-white"
+              >
+                Stop viewing
+              </button>
+            </div>
+          </div>
+        )}
         <header className="border-b border-white/5 bg-slate-950/40 px-4 py-4 backdrop-blur md:px-8">
           <div className="flex items-center justify-between md:flex-row md:justify-start">
             {/* Mobile Menu Button */}
@@ -174,7 +194,7 @@ export default function ProtectedLayout({ children }: { children: React.ReactNod
               <div>
                 <p className="text-xs uppercase tracking-[0.4em] text-slate-500">Welcome back</p>
                 <p className="text-xl font-semibold text-white">{user.username}</p>
-                <p className="text-xs text-slate-400">{user.role === 'manager' ? 'Manager' : 'Staff'} access</p>
+                <p className="text-xs capitalize text-slate-400">{user.role} access</p>
               </div>
               <div className="flex items-center gap-3">
                 <button
@@ -1,9 +1,25 @@
 'use client';
 
-import { createContext, useCallback, useContext, useEffect, useMemo, useState } from 'react';
-import { createApiClient, loginRequest } from '@/lib/api-client';
+import { createContext, useCallback, useContext, useEffect, useMemo, useRef, useState } from 'react';
+import {
+  createApiClient,
+  isMfaChallenge,
+  loginRequest,
+  logoutRequest,
+  oidcAuthorizeRequest,
+  oidcCallbackRequest,
+  refreshRequest,
+  verifyMfaRequest,
+} from '@/lib/api-client';
 import { ApiClient } from '@/lib/api-client';
-import { LoginRequest, LoginResponse, User } from '@/lib/types';
+import {
+  ImpersonateUserRequest,
+  LoginRequest,
+  LoginResponse,
+  MfaChallengeResponse,
+  PendingMfaChallenge,
+  User,
+} from '@/lib/types';
 import { useServer } from '@/context/server-context';
 
 type PersistMode = 'local' | 'session';
@@ -13,18 +29,51 @@ interface AuthContextValue {
   user: User | null;
   loading: boolean;
   api: ApiClient | null;
-  login: (credentials: LoginRequest) => Promise<LoginResponse>;
+  // Resolves with a challenge instead of signing in when a second factor is required
+  login: (credentials: LoginRequest) => Promise<LoginResponse | MfaChallengeResponse>;
+  verifyMfa: (challenge: MfaChallengeResponse, code: string, rememberMe: boolean) => Promise<LoginResponse>;
+  // Sends the browser to the identity provider, which returns to /oidc/callback
+  startOidcLogin: (rememberMe: boolean) => Promise<void>;
+  // Resolves with a challenge, kept for takeOidcChallenge, when a second factor is required
+  completeOidcLogin: (code: string, state: string) => Promise<LoginResponse | MfaChallengeResponse>;
+  // Returns the challenge left by completeOidcLogin, once
+  takeOidcChallenge: () => PendingMfaChallenge | null;
+  // Sends a signed-in user to the identity provider to link their account there
+  startOidcLink: () => Promise<void>;
+  // Whether the identity provider sent back a link rather than a login
+  oidcLinkPending: () => boolean;
+  completeOidcLink: (code: string, state: string) => Promise<void>;
   logout: () => void;
   refreshProfile: () => Promise<void>;
+  // Views the application as another user until stopImpersonating or the token expires
+  impersonate: (userId: string, request?: ImpersonateUserRequest) => Promise<void>;
+  stopImpersonating: () => void;
+}
+
+// The manager's own session, kept while they impersonate another user
+interface SavedSession {
+  token: string;
+  refreshToken: string | null;
+  user: User | null;
 }
 
 interface StoredAuthPayload {
   token: string;
+  refreshToken?: string | null;
   user: User | null;
   persist: PersistMode;
+  impersonator?: SavedSession | null;
 }
 
 const STORAGE_KEY = 'inventory-manager-auth';
+// Remembers the "remember me" choice across the identity provider redirect
+const OIDC_REMEMBER_KEY = 'inventory-manager-oidc-remember';
+// Ties the identity provider redirect to this tab, so logins started elsewhere are rejected
+const OIDC_BINDING_KEY = 'inventory-manager-oidc-binding';
+// Marks an identity provider redirect started to link an account rather than to sign in
+const OIDC_LINK_KEY = 'inventory-manager-oidc-link';
+// SSO logins that need a second factor are finished on the login page
+const OIDC_CHALLENGE_KEY = 'inventory-manager-oidc-challenge';
 
 const AuthContext = createContext<AuthContextValue | undefined>(undefined);
 
@@ -75,22 +124,98 @@ function writeStoredAuth(payload: StoredAuthPayload | null, mode: PersistMode) {
 export function AuthProvider({ children }: { children: React.ReactNode }) {
   const { selectedServer } = useServer();
   const [token, setToken] = useState<string | null>(null);
+  const [refreshToken, setRefreshToken] = useState<string | null>(null);
   const [user, setUser] = useState<User | null>(null);
   const [persistMode, setPersistMode] = useState<PersistMode>('local');
   const [initialised, setInitialised] = useState(false);
   const [profileLoading, setProfileLoading] = useState(false);
 
+  // Concurrent 401s share one refresh, since each refresh token can only be used once
+  const refreshTokenRef = useRef<string | null>(null);
+  const refreshInFlight = useRef<Promise<string | null> | null>(null);
+  const impersonatorRef = useRef<SavedSession | null>(null);
+
   useEffect(() => {
     const stored = readStoredAuth();
     if (stored?.token) {
       setToken(stored.token);
+      setRefreshToken(stored.refreshToken ?? null);
+      refreshTokenRef.current = stored.refreshToken ?? null;
       setUser(stored.user);
       setPersistMode(stored.persist);
+      impersonatorRef.current = stored.impersonator ?? null;
     }
     setInitialised(true);
   }, []);
 
-  const api = useMemo(() => (token ? createApiClient(token, selectedServer.url) : null), [token, selectedServer.url]);
+  const clearAuth = useCallback((mode: PersistMode) => {
+    refreshTokenRef.current = null;
+    impersonatorRef.current = null;
+    setToken(null);
+    setRefreshToken(null);
+    setUser(null);
+    writeStoredAuth(null, mode);
+  }, []);
+
+  // Switches back to the manager's own session
+  const stopImpersonating = useCallback(() => {
+    const saved = impersonatorRef.current;
+    if (!saved) {
+      return;
+    }
+    impersonatorRef.current = null;
+    refreshTokenRef.current = saved.refreshToken;
+    setToken(saved.token);
+    setRefreshToken(saved.refreshToken);
+    setUser(saved.user);
+    writeStoredAuth(
+      { token: saved.token, refreshToken: saved.refreshToken, user: saved.user, persist: persistMode },
+      persistMode,
+    );
+  }, [persistMode]);
+
+  const refreshAccessToken = useCallback(() => {
+    // Impersonation tokens cannot be refreshed; an expired one ends the impersonation, and the
+    // request fails rather than being retried as the manager
+    if (impersonatorRef.current) {
+      stopImpersonating();
+      return Promise.resolve(null);
+    }
+    if (!refreshInFlight.current) {
+      refreshInFlight.current = (async () => {
+        // Another tab may already have rotated the refresh token
+        const stored = readStoredAuth();
+        const current = stored?.refreshToken ?? refreshTokenRef.current;
+        if (!current) {
+          return null;
+        }
+        try {
+          const response = await refreshRequest(current, selectedServer.url);
+          refreshTokenRef.current = response.refresh_token;
+          setToken(response.token);
+          setRefreshToken(response.refresh_token);
+          setUser(response.user);
+          writeStoredAuth(
+            { token: response.token, refreshToken: response.refresh_token, user: response.user, persist: persistMode },
+            persistMode,
+          );
+          return response.token;
+        } catch (error) {
+          console.error('Failed to refresh session', error);
+          clearAuth(persistMode);
+          return null;
+        } finally {
+          refreshInFlight.current = null;
+        }
+      })();
+    }
+    return refreshInFlight.current;
+  }, [clearAuth, persistMode, selectedServer.url, stopImpersonating]);
+
+  const api = useMemo(
+    () => (token ? createApiClient(token, selectedServer.url, refreshAccessToken) : null),
+    [token, selectedServer.url, refreshAccessToken],
+  );
 
   useEffect(() => {
     if (!token || !api || !initialised) {
@@ -99,20 +224,27 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     }
 
     let cancelled = false;
+    const impersonating = impersonatorRef.current !== null;
     setProfileLoading(true);
     api
       .getProfile()
       .then((profile) => {
         if (cancelled) return;
         setUser(profile);
-        writeStoredAuth({ token, user: profile, persist: persistMode }, persistMode);
+        writeStoredAuth(
+          { token, refreshToken, user: profile, persist: persistMode, impersonator: impersonatorRef.current },
+          persistMode,
+        );
       })
       .catch((error) => {
         if (cancelled) return;
         console.error('Failed to load profile', error);
-        setToken(null);
-        setUser(null);
-        writeStoredAuth(null, persistMode);
+        // A failed impersonation session returns to the manager's own session
+        if (impersonating) {
+          stopImpersonating();
+        } else {
+          clearAuth(persistMode);
+        }
       })
       .finally(() => {
         if (!cancelled) {
@@ -123,27 +255,114 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     return () => {
       cancelled = true;
     };
-  }, [api, token, persistMode, initialised]);
+  }, [api, token, refreshToken, persistMode, initialised, clearAuth, stopImpersonating]);
+
+  const startSession = useCallback((response: LoginResponse, remember: boolean) => {
+    const mode: PersistMode = remember ? 'local' : 'session';
+    setPersistMode(mode);
+    impersonatorRef.current = null;
+    refreshTokenRef.current = response.refresh_token;
+    setToken(response.token);
+    setRefreshToken(response.refresh_token);
+    setUser(response.user);
+    writeStoredAuth(
+      { token: response.token, refreshToken: response.refresh_token, user: response.user, persist: mode },
+      mode,
+    );
+  }, []);
 
   const login = useCallback(
     async (credentials: LoginRequest) => {
       const response = await loginRequest(credentials, selectedServer.url);
-      const remember = credentials.rememberMe ?? false;
-      const mode: PersistMode = remember ? 'local' : 'session';
-      setPersistMode(mode);
-      setToken(response.token);
-      setUser(response.user);
-      writeStoredAuth({ token: response.token, user: response.user, persist: mode }, mode);
+      if (!isMfaChallenge(response)) {
+        startSession(response, credentials.rememberMe ?? false);
+      }
+      return response;
+    },
+    [selectedServer.url, startSession],
+  );
+
+  const verifyMfa = useCallback(
+    async (challenge: MfaChallengeResponse, code: string, rememberMe: boolean) => {
+      const response = await verifyMfaRequest(challenge.challenge_token, code, selectedServer.url);
+      startSession(response, rememberMe);
       return response;
     },
+    [selectedServer.url, startSession],
+  );
+
+  const startOidcLogin = useCallback(
+    async (rememberMe: boolean) => {
+      const { authorization_url, binding } = await oidcAuthorizeRequest(rememberMe, selectedServer.url);
+      window.sessionStorage.setItem(OIDC_REMEMBER_KEY, rememberMe ? 'true' : 'false');
+      window.sessionStorage.setItem(OIDC_BINDING_KEY, binding);
+      window.sessionStorage.removeItem(OIDC_LINK_KEY);
+      window.location.assign(authorization_url);
+    },
     [selectedServer.url],
   );
 
+  const completeOidcLogin = useCallback(
+    async (code: string, state: string) => {
+      const rememberMe = window.sessionStorage.getItem(OIDC_REMEMBER_KEY) === 'true';
+      const binding = window.sessionStorage.getItem(OIDC_BINDING_KEY) ?? '';
+      window.sessionStorage.removeItem(OIDC_REMEMBER_KEY);
+      window.sessionStorage.removeItem(OIDC_BINDING_KEY);
+      const response = await oidcCallbackRequest(code, state, binding, selectedServer.url);
+      if (isMfaChallenge(response)) {
+        const pending: PendingMfaChallenge = { challenge: response, rememberMe };
+        window.sessionStorage.setItem(OIDC_CHALLENGE_KEY, JSON.stringify(pending));
+        return response;
+      }
+      startSession(response, rememberMe);
+      return response;
+    },
+    [selectedServer.url, startSession],
+  );
+
+  const takeOidcChallenge = useCallback(() => {
+    const stored = window.sessionStorage.getItem(OIDC_CHALLENGE_KEY);
+    window.sessionStorage.removeItem(OIDC_CHALLENGE_KEY);
+    if (!stored) return null;
+    try {
+      return JSON.parse(stored) as PendingMfaChallenge;
+    } catch {
+      return null;
+    }
+  }, []);
+
+  const startOidcLink = useCallback(async () => {
+    if (!api) throw new Error('You must be authenticated to call this endpoint.');
+    const { authorization_url, binding } = await api.startSsoLink();
+    window.sessionStorage.setItem(OIDC_LINK_KEY, 'true');
+    window.sessionStorage.setItem(OIDC_BINDING_KEY, binding);
+    window.location.assign(authorization_url);
+  }, [api]);
+
+  const oidcLinkPending = useCallback(() => window.sessionStorage.getItem(OIDC_LINK_KEY) === 'true', []);
+
+  const completeOidcLink = useCallback(
+    async (code: string, state: string) => {
+      const binding = window.sessionStorage.getItem(OIDC_BINDING_KEY) ?? '';
+      window.sessionStorage.removeItem(OIDC_LINK_KEY);
+      window.sessionStorage.removeItem(OIDC_BINDING_KEY);
+      if (!api) throw new Error('You must be authenticated to call this endpoint.');
+      await api.completeSsoLink(code, state, binding);
+    },
+    [api],
+  );
+
   const logout = useCallback(() => {
-    setToken(null);
-    setUser(null);
-    writeStoredAuth(null, persistMode);
-  }, [persistMode]);
+    // Revoke the session server-side (best effort; the local session ends either way); while
+    // impersonating, that is the manager's own session
+    const current = impersonatorRef.current?.refreshToken ?? refreshTokenRef.current;
+    if (current) {
+      logoutRequest(current, false, selectedServer.url).catch((error) => {
+        console.error('Failed to revoke session', error);
+      });
+    }
+    clearAuth(persistMode);
+  }, [clearAuth, persistMode, selectedServer.url]);
 
   const refreshProfile = useCallback(async () => {
     if (!api || !token) {
@@ -151,8 +370,31 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     }
     const profile = await api.getProfile();
     setUser(profile);
-    writeStoredAuth({ token, user: profile, persist: persistMode }, persistMode);
-  }, [api, persistMode, token]);
+    writeStoredAuth(
+      { token, refreshToken, user: profile, persist: persistMode, impersonator: impersonatorRef.current },
+      persistMode,
+    );
+  }, [api, persistMode, token, refreshToken]);
+
+  const impersonate = useCallback(
+    async (userId: string, request: ImpersonateUserRequest = {}) => {
+      if (!api || !token) {
+        return;
+      }
+      const response = await api.impersonateUser(userId, request);
+      const saved: SavedSession = { token, refreshToken, user };
+      impersonatorRef.current = saved;
+      refreshTokenRef.current = null;
+      setToken(response.token);
+      setRefreshToken(null);
+      setUser(response.user);
+      writeStoredAuth(
+        { token: response.token, refreshToken: null, user: response.user, persist: persistMode, impersonator: saved },
+        persistMode,
+      );
+    },
+    [api, persistMode, refreshToken, token, user],
+  );
 
   const loading = !initialised || profileLoading;
 
@@ -162,8 +404,17 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     loading,
     api,
     login,
+    verifyMfa,
+    startOidcLogin,
+    completeOidcLogin,
+    takeOidcChallenge,
+    startOidcLink,
+    oidcLinkPending,
+    completeOidcLink,
     logout,
     refreshProfile,
+    impersonate,
+    stopImpersonating,
   };
 
   return <AuthContext.Provider value={value}>{children}</AuthContext.Provider>;
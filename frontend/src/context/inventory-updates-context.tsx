@@ -1,7 +1,7 @@
 'use client';
 
-import { createContext, useContext, useEffect, useState } from 'react';
-import { InventoryUpdateEvent } from '@/lib/types';
+import { createContext, useCallback, useContext, useEffect, useRef, useState } from 'react';
+import { EntityType, InventoryUpdateEvent } from '@/lib/types';
 import { useAuth } from '@/context/auth-context';
 import { useServer } from '@/context/server-context';
 
@@ -9,6 +9,8 @@ interface InventoryUpdatesContextValue {
   connected: boolean;
   lastEvent: InventoryUpdateEvent | null;
   clearLastEvent: () => void;
+  // Calls listener with every event (of any entity type); returns the unsubscribe function
+  subscribe: (listener: (event: InventoryUpdateEvent) => void) => () => void;
 }
 
 const InventoryUpdatesContext = createContext<InventoryUpdatesContextValue | undefined>(undefined);
@@ -18,6 +20,14 @@ export function InventoryUpdatesProvider({ children }: { children: React.ReactNo
   const { selectedServer } = useServer();
   const [connected, setConnected] = useState(false);
   const [lastEvent, setLastEvent] = useState<InventoryUpdateEvent | null>(null);
+  const listenersRef = useRef(new Set<(event: InventoryUpdateEvent) => void>());
+
+  const subscribe = useCallback((listener: (event: InventoryUpdateEvent) => void) => {
+    listenersRef.current.add(listener);
+    return () => {
+      listenersRef.current.delete(listener);
+    };
+  }, []);
 
   useEffect(() => {
     if (!token) {
@@ -48,6 +58,11 @@ export function InventoryUpdatesProvider({ children }: { children: React.ReactNo
     socket.onmessage = (event) => {
       try {
         const payload = JSON.parse(event.data) as InventoryUpdateEvent;
+        listenersRef.current.forEach((listener) => listener(payload));
+        // Only inventory changes are surfaced as the last event; subscribers see every entity type
+        if (payload.entity_type && payload.entity_type !== 'inventory') {
+          return;
+        }
         setLastEvent(payload);
       } catch (error) {
         console.error('Failed to parse inventory update', error);
@@ -62,7 +77,7 @@ export function InventoryUpdatesProvider({ children }: { children: React.ReactNo
   const clearLastEvent = () => setLastEvent(null);
 
   return (
-    <InventoryUpdatesContext.Provider value={{ connected, lastEvent, clearLastEvent }}>
+    <InventoryUpdatesContext.Provider value={{ connected, lastEvent, clearLastEvent, subscribe }}>
       {children}
     </InventoryUpdatesContext.Provider>
   );
@@ -76,3 +91,24 @@ export function useInventoryUpdates() {
   return context;
 }
 
+// Calls reload whenever the server reports a change to one of the entity types, so screens showing
+// them do not go stale. Does nothing outside InventoryUpdatesProvider.
+export function useEntityInvalidation(entityTypes: EntityType[] | undefined, reload: () => void) {
+  const context = useContext(InventoryUpdatesContext);
+  const subscribe = context?.subscribe;
+  const reloadRef = useRef(reload);
+  reloadRef.current = reload;
+  const key = entityTypes?.join(',') ?? '';
+
+  useEffect(() => {
+    if (!subscribe || !key) {
+      return;
+    }
+    const types = key.split(',');
+    return subscribe((event) => {
+      if (types.includes(event.entity_type ?? 'inventory')) {
+        reloadRef.current();
+      }
+    });
+  }, [subscribe, key]);
+}
@@ -1,14 +1,18 @@
 'use client';
 
 import { useCallback, useEffect, useRef, useState } from 'react';
+import { useEntityInvalidation } from '@/context/inventory-updates-context';
+import { EntityType } from '@/lib/types';
 
 interface Options {
   enabled?: boolean;
   skipInitial?: boolean;
+  // Reloads when the server reports a change to one of these entity types
+  invalidateOn?: EntityType[];
 }
 
 export function useApiQuery<T>(fetcher: (() => Promise<T>) | null, options: Options = {}) {
-  const { enabled = true, skipInitial = false } = options;
+  const { enabled = true, skipInitial = false, invalidateOn } = options;
   const [data, setData] = useState<T | null>(null);
   const [loading, setLoading] = useState(!skipInitial);
   const [error, setError] = useState<string | null>(null);
@@ -35,6 +39,8 @@ export function useApiQuery<T>(fetcher: (() => Promise<T>) | null, options: Opti
     }
   }, [enabled]);
 
+  useEntityInvalidation(enabled ? invalidateOn : undefined, run);
+
   useEffect(() => {
     if (!skipInitial) {
       run();
@@ -1,26 +1,41 @@
 import {
   AdjustInventoryRequest,
+  AssignedStoreListResponse,
+  AuditFilters,
+  AuditListResponse,
   CreateInventoryRequest,
   CreateUserRequest,
+  ImpersonateUserRequest,
+  ImpersonationResponse,
   InventoryFilters,
   InventoryListResponse,
   InventoryRecord,
   InventoryUpdateEvent,
   LoginRequest,
   LoginResponse,
+  MfaChallengeResponse,
+  MfaEnrollment,
+  MfaStatus,
+  OidcAuthorizeResponse,
+  OidcConfig,
   PaginatedUsersResponse,
   PasswordChangeRequest,
+  RecoveryCodesResponse,
+  RoleListResponse,
   SKU,
   SKUListFilters,
   SKUListResponse,
   SKURequestBody,
   Store,
+  StoreDetail,
   StoreListResponse,
+  StoreAssignmentFields,
   StoreStaffAssociation,
   StoreStaffListResponse,
   UpdateInventoryRequest,
   UpdateUserRequest,
   User,
+  UserStatus,
 } from '@/lib/types';
 import { API_BASE_URL } from '@/lib/config';
 
@@ -60,7 +75,10 @@ function buildQuery(params: Record<string, string | number | boolean | undefined
   return qs ? `?${qs}` : '';
 }
 
-export async function loginRequest(body: LoginRequest, baseUrl = API_BASE_URL): Promise<LoginResponse> {
+export async function loginRequest(
+  body: LoginRequest,
+  baseUrl = API_BASE_URL,
+): Promise<LoginResponse | MfaChallengeResponse> {
   const response = await fetch(`${baseUrl}/api/auth/login`, {
     method: 'POST',
     headers: jsonHeaders,
@@ -68,28 +86,157 @@ export async function loginRequest(body: LoginRequest, baseUrl = API_BASE_URL):
     cache: 'no-store',
   });
 
+  return handleResponse<LoginResponse | MfaChallengeResponse>(response);
+}
+
+export function isMfaChallenge(response: LoginResponse | MfaChallengeResponse): response is MfaChallengeResponse {
+  return 'mfa_required' in response && response.mfa_required === true;
+}
+
+export async function verifyMfaRequest(
+  challengeToken: string,
+  code: string,
+  baseUrl = API_BASE_URL,
+): Promise<LoginResponse> {
+  const response = await fetch(`${baseUrl}/api/auth/2fa/verify`, {
+    method: 'POST',
+    headers: jsonHeaders,
+    body: JSON.stringify({ challenge_token: challengeToken, code }),
+    cache: 'no-store',
+  });
+
+  return handleResponse<LoginResponse>(response);
+}
+
+export async function enrollMfaChallengeRequest(challengeToken: string, baseUrl = API_BASE_URL): Promise<MfaEnrollment> {
+  const response = await fetch(`${baseUrl}/api/auth/2fa/enroll`, {
+    method: 'POST',
+    headers: jsonHeaders,
+    body: JSON.stringify({ challenge_token: challengeToken }),
+    cache: 'no-store',
+  });
+
+  return handleResponse<MfaEnrollment>(response);
+}
+
+export async function oidcConfigRequest(baseUrl = API_BASE_URL): Promise<OidcConfig> {
+  const response = await fetch(`${baseUrl}/api/auth/oidc/config`, { cache: 'no-store' });
+
+  return handleResponse<OidcConfig>(response);
+}
+
+export async function oidcAuthorizeRequest(rememberMe: boolean, baseUrl = API_BASE_URL): Promise<OidcAuthorizeResponse> {
+  const response = await fetch(`${baseUrl}/api/auth/oidc/authorize`, {
+    method: 'POST',
+    headers: jsonHeaders,
+    body: JSON.stringify({ rememberMe }),
+    cache: 'no-store',
+  });
+
+  return handleResponse<OidcAuthorizeResponse>(response);
+}
+
+export async function oidcCallbackRequest(
+  code: string,
+  state: string,
+  binding: string,
+  baseUrl = API_BASE_URL,
+): Promise<LoginResponse | MfaChallengeResponse> {
+  const response = await fetch(`${baseUrl}/api/auth/oidc/callback`, {
+    method: 'POST',
+    headers: jsonHeaders,
+    body: JSON.stringify({ code, state, binding }),
+    cache: 'no-store',
+  });
+
+  return handleResponse<LoginResponse | MfaChallengeResponse>(response);
+}
+
+export async function refreshRequest(refreshToken: string, baseUrl = API_BASE_URL): Promise<LoginResponse> {
+  const response = await fetch(`${baseUrl}/api/auth/refresh`, {
+    method: 'POST',
+    headers: jsonHeaders,
+    body: JSON.stringify({ refresh_token: refreshToken }),
+    cache: 'no-store',
+  });
+
   return handleResponse<LoginResponse>(response);
 }
 
+export async function logoutRequest(refreshToken: string, all = false, baseUrl = API_BASE_URL): Promise<{ message: string }> {
+  const response = await fetch(`${baseUrl}/api/auth/logout`, {
+    method: 'POST',
+    headers: jsonHeaders,
+    body: JSON.stringify({ refresh_token: refreshToken, all }),
+    cache: 'no-store',
+  });
+
+  return handleResponse<{ message: string }>(response);
+}
+
+export async function forgotPasswordRequest(email: string, baseUrl = API_BASE_URL): Promise<{ message: string }> {
+  const response = await fetch(`${baseUrl}/api/auth/forgot-password`, {
+    method: 'POST',
+    headers: jsonHeaders,
+    body: JSON.stringify({ email }),
+    cache: 'no-store',
+  });
+
+  return handleResponse<{ message: string }>(response);
+}
+
+export async function resetPasswordRequest(
+  token: string,
+  newPassword: string,
+  baseUrl = API_BASE_URL,
+): Promise<{ message: string }> {
+  const response = await fetch(`${baseUrl}/api/auth/reset-password`, {
+    method: 'POST',
+    headers: jsonHeaders,
+    body: JSON.stringify({ token, new_password: newPassword }),
+    cache: 'no-store',
+  });
+
+  return handleResponse<{ message: string }>(response);
+}
+
 export type ApiClient = ReturnType<typeof createApiClient>;
 
-export function createApiClient(token: string | null, baseUrl = API_BASE_URL) {
-  async function authedFetch<T>(path: string, init: RequestInit = {}) {
+// Returns a new access token after the current one was rejected, or null if the session has ended
+export type TokenRefresher = () => Promise<string | null>;
+
+export function createApiClient(token: string | null, baseUrl = API_BASE_URL, refreshToken?: TokenRefresher) {
+  async function authedRequest(path: string, init: RequestInit = {}) {
     if (!token) {
       throw new Error('You must be authenticated to call this endpoint.');
     }
 
-    const response = await fetch(`${baseUrl}${path}`, {
-      ...init,
-      headers: {
-        ...jsonHeaders,
-        ...(init.headers || {}),
-        Authorization: `Bearer ${token}`,
-      },
-      cache: 'no-store',
-    });
-
-    return handleResponse<T>(response);
+    const send = (accessToken: string) =>
+      fetch(`${baseUrl}${path}`, {
+        ...init,
+        headers: {
+          ...jsonHeaders,
+          ...(init.headers || {}),
+          Authorization: `Bearer ${accessToken}`,
+        },
+        cache: 'no-store',
+      });
+
+    let response = await send(token);
+
+    // Access tokens are short-lived: refresh once and retry
+    if (response.status === 401 && refreshToken) {
+      const newToken = await refreshToken();
+      if (newToken) {
+        response = await send(newToken);
+      }
+    }
+
+    return response;
+  }
+
+  async function authedFetch<T>(path: string, init: RequestInit = {}) {
+    return handleResponse<T>(await authedRequest(path, init));
   }
 
   return {
@@ -100,9 +247,38 @@ export function createApiClient(token: string | null, baseUrl = API_BASE_URL) {
         method: 'PUT',
         body: JSON.stringify(body),
       }),
+    getMfaStatus: () => authedFetch<MfaStatus>('/api/profile/2fa'),
+    enrollMfa: () =>
+      authedFetch<MfaEnrollment>('/api/profile/2fa/enroll', {
+        method: 'POST',
+      }),
+    confirmMfa: (code: string) =>
+      authedFetch<RecoveryCodesResponse>('/api/profile/2fa/confirm', {
+        method: 'POST',
+        body: JSON.stringify({ code }),
+      }),
+    regenerateRecoveryCodes: (code: string) =>
+      authedFetch<RecoveryCodesResponse>('/api/profile/2fa/recovery-codes', {
+        method: 'POST',
+        body: JSON.stringify({ code }),
+      }),
+    disableMfa: (code: string) =>
+      authedFetch<{ message: string }>('/api/profile/2fa/disable', {
+        method: 'POST',
+        body: JSON.stringify({ code }),
+      }),
+    startSsoLink: () =>
+      authedFetch<OidcAuthorizeResponse>('/api/profile/sso/link', {
+        method: 'POST',
+      }),
+    completeSsoLink: (code: string, state: string, binding: string) =>
+      authedFetch<{ message: string }>('/api/profile/sso/link/callback', {
+        method: 'POST',
+        body: JSON.stringify({ code, state, binding }),
+      }),
 
     // Users
-    listUsers: (params: { page?: number; limit?: number } = {}) =>
+    listUsers: (params: { page?: number; limit?: number; status?: UserStatus } = {}) =>
       authedFetch<PaginatedUsersResponse>(`/api/manager/users${buildQuery(params as Record<string, string | number | boolean | undefined | null>)}`),
     createUser: (body: CreateUserRequest) =>
       authedFetch<User>('/api/manager/users', {
@@ -115,12 +291,39 @@ export function createApiClient(token: string | null, baseUrl = API_BASE_URL) {
         body: JSON.stringify(body),
       }),
     deleteUser: (id: string) =>
-      authedFetch<{ message: string }>(`/api/manager/users/${id}`, {
+      authedFetch<User>(`/api/manager/users/${id}`, {
+        method: 'DELETE',
+      }),
+    deactivateUser: (id: string, reason?: string) =>
+      authedFetch<User>(`/api/manager/users/${id}/deactivate`, {
+        method: 'POST',
+        body: JSON.stringify({ reason }),
+      }),
+    reactivateUser: (id: string) =>
+      authedFetch<User>(`/api/manager/users/${id}/reactivate`, {
+        method: 'POST',
+      }),
+    impersonateUser: (id: string, request: ImpersonateUserRequest = {}) =>
+      authedFetch<ImpersonationResponse>(`/api/manager/users/${id}/impersonate`, {
+        method: 'POST',
+        body: JSON.stringify(request),
+      }),
+    forcePasswordReset: (id: string) =>
+      authedFetch<{ message: string }>(`/api/manager/users/${id}/reset-password`, {
+        method: 'POST',
+      }),
+    resetUserMfa: (id: string) =>
+      authedFetch<{ message: string }>(`/api/manager/users/${id}/2fa`, {
         method: 'DELETE',
       }),
 
+    // Roles
+    listRoles: () => authedFetch<RoleListResponse>('/api/manager/roles'),
+
     // Stores
     listStores: () => authedFetch<StoreListResponse>('/api/manager/stores'),
+    listMyStores: () => authedFetch<AssignedStoreListResponse>('/api/profile/stores'),
+    getStore: (id: string) => authedFetch<StoreDetail>(`/api/stores/${id}`),
     createStore: (body: { name: string; address: string }) =>
       authedFetch<Store>('/api/manager/stores', {
         method: 'POST',
@@ -134,11 +337,16 @@ export function createApiClient(token: string | null, baseUrl = API_BASE_URL) {
     // Store Staff
     listStoreStaff: (storeId: string) =>
       authedFetch<StoreStaffListResponse>(`/api/manager/stores/staff${buildQuery({ store_id: storeId } as Record<string, string | number | boolean | undefined | null>)}`),
-    addStaffToStore: (body: { store_id: string; user_id: string }) =>
+    addStaffToStore: (body: { store_id: string; user_id: string } & StoreAssignmentFields) =>
       authedFetch<StoreStaffAssociation>('/api/manager/stores/staff', {
         method: 'POST',
         body: JSON.stringify(body),
       }),
+    updateStoreStaff: (id: string, body: StoreAssignmentFields) =>
+      authedFetch<StoreStaffAssociation>(`/api/manager/stores/staff/${id}`, {
+        method: 'PUT',
+        body: JSON.stringify(body),
+      }),
     deleteStaffFromStore: (id: string) =>
       authedFetch<{ message: string }>(`/api/manager/stores/staff/${id}`, {
         method: 'DELETE',
@@ -187,6 +395,19 @@ export function createApiClient(token: string | null, baseUrl = API_BASE_URL) {
         method: 'POST',
         body: JSON.stringify(body),
       }),
+
+    // Audit log
+    listAuditLog: (filters: AuditFilters = {}) =>
+      authedFetch<AuditListResponse>(`/api/manager/audit${buildQuery(filters as Record<string, string | number | boolean | undefined | null>)}`),
+    // Every matching entry (not just one page) as CSV
+    exportAuditLog: async (filters: AuditFilters = {}) => {
+      const query = buildQuery({ ...filters, page: undefined, page_size: undefined, format: 'csv' });
+      const response = await authedRequest(`/api/manager/audit${query}`);
+      if (!response.ok) {
+        return handleResponse<Blob>(response);
+      }
+      return response.blob();
+    },
   };
 }
 
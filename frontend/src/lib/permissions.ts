@@ -0,0 +1,31 @@
+import { User } from '@/lib/types';
+
+// Permission names granted to roles by the backend (see GET /api/manager/roles/permissions)
+export type Permission =
+  | 'inventory.read'
+  | 'inventory.all_stores'
+  | 'inventory.adjust'
+  | 'inventory.update'
+  | 'inventory.manage'
+  | 'sku.read'
+  | 'sku.manage'
+  | 'stores.manage'
+  | 'users.manage'
+  | 'users.impersonate'
+  | 'roles.manage'
+  | 'service_accounts.manage'
+  | 'reports.read'
+  | 'reports.rebuild'
+  | 'audit.read'
+  | 'system.monitor';
+
+export function hasPermission(user: User | null | undefined, permission: Permission): boolean {
+  if (!user) {
+    return false;
+  }
+  // Sessions stored before permissions were returned only know the role
+  if (!user.permissions) {
+    return user.role === 'manager';
+  }
+  return user.permissions.includes(permission);
+}
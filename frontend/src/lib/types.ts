@@ -1,4 +1,5 @@
-export type UserRole = 'manager' | 'staff';
+// Built-in roles are 'manager' and 'staff'; managers can define custom roles
+export type UserRole = string;
 
 export interface User {
   id: string;
@@ -7,6 +8,51 @@ export interface User {
   role: UserRole;
   created_at: string;
   updated_at: string;
+  // Permissions of the user's role (login and profile responses only)
+  permissions?: string[];
+  // Status and deactivation details (user management responses only)
+  status?: UserStatus;
+  deactivated_at?: string;
+  deactivation_reason?: string;
+  anonymized?: boolean;
+  // Set in profile responses of impersonation sessions
+  impersonation?: ImpersonationInfo;
+}
+
+export type UserStatus = 'active' | 'deactivated';
+
+// Marks a session in which a manager views the application as another user
+export interface ImpersonationInfo {
+  impersonator_id: string;
+  impersonator_username: string;
+  read_only: boolean;
+  expires_at: string;
+}
+
+export interface ImpersonateUserRequest {
+  allow_writes?: boolean;
+  reason?: string;
+}
+
+// Impersonation tokens cannot be refreshed, so there is no refresh token
+export interface ImpersonationResponse {
+  token: string;
+  expires_in: number;
+  user: User;
+}
+
+export interface Role {
+  id: string;
+  name: string;
+  description: string;
+  built_in: boolean;
+  permissions: string[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface RoleListResponse {
+  items: Role[];
 }
 
 export interface LoginRequest {
@@ -17,7 +63,54 @@ export interface LoginRequest {
 
 export interface LoginResponse {
   token: string;
+  refresh_token: string;
+  expires_in: number;
   user: User;
+  // The user's active store assignments (without stock counts)
+  stores: AssignedStore[];
+  // Only set when the login completed two-factor enrolment; shown once
+  recovery_codes?: string[];
+}
+
+// Returned by login instead of tokens when a second factor is required
+export interface MfaChallengeResponse {
+  mfa_required: true;
+  enrollment_required: boolean;
+  challenge_token: string;
+  expires_in: number;
+}
+
+// A second-factor challenge of a single sign-on login, finished on the login page
+export interface PendingMfaChallenge {
+  challenge: MfaChallengeResponse;
+  rememberMe: boolean;
+}
+
+export interface MfaStatus {
+  enabled: boolean;
+  required: boolean;
+  recovery_codes_remaining: number;
+}
+
+export interface MfaEnrollment {
+  secret: string;
+  provisioning_uri: string;
+}
+
+export interface RecoveryCodesResponse {
+  recovery_codes: string[];
+}
+
+export interface OidcConfig {
+  enabled: boolean;
+  // Label of the single sign-on button
+  name?: string;
+}
+
+export interface OidcAuthorizeResponse {
+  authorization_url: string;
+  // Kept in this tab and sent back with the callback
+  binding: string;
 }
 
 export interface PasswordChangeRequest {
@@ -59,15 +152,63 @@ export interface StoreListResponse {
   items: Store[];
 }
 
+// Stock counts of a store from the stock summary projection; as_of is null if it has no inventory
+export interface StoreStockCounts {
+  sku_count: number;
+  total_quantity: number;
+  out_of_stock_count: number;
+  as_of: string | null;
+}
+
+// A store the current user is assigned to, with the terms of the assignment
+export interface AssignedStore extends Store {
+  store_role: string | null;
+  valid_from: string | null;
+  valid_to: string | null;
+  stock?: StoreStockCounts;
+}
+
+export interface AssignedStoreListResponse {
+  items: AssignedStore[];
+}
+
+// The assignment fields describe the current user's own assignment, if any
+export interface StoreDetail extends Store {
+  assigned: boolean;
+  store_role: string | null;
+  valid_from: string | null;
+  valid_to: string | null;
+  stock: StoreStockCounts;
+}
+
+// Terms of a store assignment: a store role applies in that store only, valid_to is exclusive
+export interface StoreAssignmentFields {
+  role?: string | null;
+  valid_from?: string | null;
+  valid_to?: string | null;
+}
+
+export interface StoreStaffMember extends User {
+  assignment_id: string;
+  store_role: string | null;
+  valid_from: string | null;
+  valid_to: string | null;
+  active: boolean;
+}
+
 export interface StoreStaffListResponse {
   store_id: string;
-  staff: User[];
+  staff: StoreStaffMember[];
 }
 
 export interface StoreStaffAssociation {
   id: string;
   store_id: string;
   user_id: string;
+  role: string | null;
+  valid_from: string | null;
+  valid_to: string | null;
+  active: boolean;
   created_at: string;
   updated_at: string;
   user: User;
@@ -134,10 +275,16 @@ export interface AdjustInventoryRequest {
   delta_quantity: number;
 }
 
+// Entity types of outbox events; the server only sends each client the types it may see
+export type EntityType = 'inventory' | 'sku' | 'store' | 'user' | 'store_user' | 'role' | 'service_account' | 'api_key';
+
 export interface InventoryUpdateEvent {
   id: string;
   operation_type: 'create' | 'update' | 'adjust' | 'delete';
   sender_instance_id: string;
+  entity_type?: EntityType;
+  entity_id?: string;
+  diff?: Record<string, { old: unknown; new: unknown }> | null;
   inventory_id: string;
   sku_id: string;
   sku_name: string;
@@ -170,3 +317,38 @@ export interface SKUListFilters {
   order?: 'asc' | 'desc';
 }
 
+
+export interface AuditLogEntry {
+  id: string;
+  actor_id: string;
+  actor_name: string;
+  action: string;
+  target_type: string;
+  target_id: string;
+  target_name: string;
+  // Changed fields only: {"field": {"old": ..., "new": ...}}
+  diff: Record<string, { old?: unknown; new?: unknown }> | null;
+  client_ip: string;
+  request_id: string;
+  created_at: string;
+}
+
+export interface AuditListResponse {
+  items: AuditLogEntry[];
+  total: number;
+  page: number;
+  page_size: number;
+  total_pages: number;
+}
+
+export interface AuditFilters {
+  actor?: string;
+  action?: string;
+  target_type?: string;
+  request_id?: string;
+  // RFC 3339 timestamps
+  from?: string;
+  to?: string;
+  page?: number;
+  page_size?: number;
+}